@@ -8,6 +8,8 @@ import (
 	"strconv"
 	"strings"
 
+	mk "follow.markets/internal/cmd/market"
+	db "follow.markets/internal/pkg/database"
 	"follow.markets/internal/pkg/strategy"
 	"github.com/gorilla/mux"
 )
@@ -61,6 +63,160 @@ func addSignal(w http.ResponseWriter, req *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// listTemplates returns the built-in signal templates and their parameters.
+func listTemplates(w http.ResponseWriter, req *http.Request) {
+	bts, err := json.Marshal(strategy.Templates())
+	if err != nil {
+		logger.Error.Println(err)
+		InternalError(w)
+		return
+	}
+	header := w.Header()
+	header.Set("Content-Length", strconv.Itoa(len(bts)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(bts)
+}
+
+// signalSchema returns the JSON Schema of the signals.
+func signalSchema(w http.ResponseWriter, req *http.Request) {
+	bts, err := json.Marshal(strategy.SignalSchema())
+	if err != nil {
+		logger.Error.Println(err)
+		InternalError(w)
+		return
+	}
+	header := w.Header()
+	header.Set("Content-Length", strconv.Itoa(len(bts)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(bts)
+}
+
+// instantiateTemplate expands the template with the name and the parameters in the request body
+// to a signal, and adds it for the tickers of the patterns.
+func instantiateTemplate(w http.ResponseWriter, req *http.Request) {
+	template, ok := strategy.GetTemplate(mux.Vars(req)["template"])
+	if !ok {
+		BadRequest("unknown template", w)
+		return
+	}
+	str, ok := mux.Vars(req)["patterns"]
+	if !ok {
+		BadRequest("missing patterns", w)
+		return
+	}
+	patterns, err := url.PathUnescape(str)
+	if err != nil {
+		logger.Error.Println(err)
+		InternalError(w)
+		return
+	}
+	bts, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		logger.Error.Println(err)
+		InternalError(w)
+		return
+	}
+	var instance strategy.TemplateInstance
+	if err := json.Unmarshal(bts, &instance); err != nil {
+		BadRequest(err.Error(), w)
+		return
+	}
+	signal, err := template.Instantiate(&instance)
+	if err != nil {
+		BadRequest(err.Error(), w)
+		return
+	}
+	if err := market.AddSignal(strings.Split(patterns, ","), signal); err != nil {
+		logger.Error.Println(err)
+		BadRequest(err.Error(), w)
+		return
+	}
+	bts, err = json.Marshal(signal)
+	if err != nil {
+		logger.Error.Println(err)
+		InternalError(w)
+		return
+	}
+	header := w.Header()
+	header.Set("Content-Length", strconv.Itoa(len(bts)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(bts)
+}
+
+// updateSignal applies the patch in the request body on the signal, the patch might change the
+// signal, its patterns or its active flag. The signal is saved as its next version.
+func updateSignal(w http.ResponseWriter, req *http.Request) {
+	name, ok := mux.Vars(req)["name"]
+	if !ok {
+		BadRequest("missing signal name", w)
+		return
+	}
+	bts, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		logger.Error.Println(err)
+		InternalError(w)
+		return
+	}
+	var patch mk.SignalPatch
+	if err := json.Unmarshal(bts, &patch); err != nil {
+		BadRequest(err.Error(), w)
+		return
+	}
+	if err := market.UpdateSignal(name, &patch); err != nil {
+		logger.Error.Println(err)
+		BadRequest(err.Error(), w)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func rollbackSignal(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	name, ok := vars["name"]
+	if !ok {
+		BadRequest("missing signal name", w)
+		return
+	}
+	version, err := strconv.ParseInt(vars["version"], 10, 64)
+	if err != nil {
+		BadRequest("invalid signal version", w)
+		return
+	}
+	if err := market.RollbackSignal(name, version); err != nil {
+		logger.Error.Println(err)
+		BadRequest(err.Error(), w)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func signalVersions(w http.ResponseWriter, req *http.Request) {
+	name, ok := mux.Vars(req)["name"]
+	if !ok {
+		BadRequest("missing signal name", w)
+		return
+	}
+	records, err := market.SignalVersions(name)
+	if err != nil {
+		logger.Error.Println(err)
+		InternalError(w)
+		return
+	}
+	type versions struct {
+		Versions []*db.SignalRecord `json:"versions"`
+	}
+	bts, err := json.Marshal(versions{Versions: records})
+	if err != nil {
+		logger.Error.Println(err)
+		InternalError(w)
+		return
+	}
+	header := w.Header()
+	header.Set("Content-Length", strconv.Itoa(len(bts)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(bts)
+}
+
 func listSignals(w http.ResponseWriter, req *http.Request) {
 	opts := req.URL.Query()
 	var signals strategy.Signals
@@ -80,3 +236,155 @@ func listSignals(w http.ResponseWriter, req *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write(bts)
 }
+
+func explainSignals(w http.ResponseWriter, req *http.Request) {
+	tickers, ok := parseVars(mux.Vars(req), "ticker")
+	if !ok {
+		BadRequest("missing ticker", w)
+		return
+	}
+	mk, ok := parseOptions(req.URL.Query(), "market")
+	if !ok {
+		mk = []string{"CASH"}
+	}
+	if !market.IsWatchingOn(tickers[0], mk[0]) {
+		NotFound(tickers[0]+" is not on the watchlist", w)
+		return
+	}
+	names, _ := parseOptions(req.URL.Query(), "names")
+	explanations, err := market.Explain(names, tickers[0], mk[0])
+	if err != nil {
+		logger.Error.Println(err)
+		BadRequest(err.Error(), w)
+		return
+	}
+	type explain struct {
+		Explanations []*strategy.SignalExplanation `json:"explanations"`
+	}
+	bts, err := json.Marshal(explain{Explanations: explanations})
+	if err != nil {
+		logger.Error.Println(err)
+		InternalError(w)
+		return
+	}
+	header := w.Header()
+	header.Set("Content-Length", strconv.Itoa(len(bts)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(bts)
+}
+
+// dryRun evaluates the signal in the request body, which isn't added to the evaluator, on the
+// watched tickers matching the optional patterns and reports on which ones it would trigger.
+func dryRun(w http.ResponseWriter, req *http.Request) {
+	bts, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		logger.Error.Println(err)
+		InternalError(w)
+		return
+	}
+	signal, err := strategy.NewSignalFromBytes(bts)
+	if err != nil {
+		logger.Error.Println(err)
+		BadRequest(err.Error(), w)
+		return
+	}
+	patterns, _ := parseOptions(req.URL.Query(), "patterns")
+	explanations, err := market.DryRun(patterns, signal)
+	if err != nil {
+		logger.Error.Println(err)
+		BadRequest(err.Error(), w)
+		return
+	}
+	type run struct {
+		Triggered    []string                      `json:"triggered"`
+		Explanations []*strategy.SignalExplanation `json:"explanations"`
+	}
+	out := run{Triggered: []string{}, Explanations: explanations}
+	for _, e := range explanations {
+		if e.Result {
+			out.Triggered = append(out.Triggered, e.Ticker)
+		}
+	}
+	bts, err = json.Marshal(out)
+	if err != nil {
+		logger.Error.Println(err)
+		InternalError(w)
+		return
+	}
+	header := w.Header()
+	header.Set("Content-Length", strconv.Itoa(len(bts)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(bts)
+}
+
+// addShadow runs the signal in the request body in shadow on the tickers of the patterns.
+func addShadow(w http.ResponseWriter, req *http.Request) {
+	bts, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		logger.Error.Println(err)
+		InternalError(w)
+		return
+	}
+	signal, err := strategy.NewSignalFromBytes(bts)
+	if err != nil {
+		BadRequest(err.Error(), w)
+		return
+	}
+	str, ok := mux.Vars(req)["patterns"]
+	if !ok {
+		BadRequest("missing patterns", w)
+		return
+	}
+	patterns, err := url.PathUnescape(str)
+	if err != nil {
+		logger.Error.Println(err)
+		InternalError(w)
+		return
+	}
+	if err := market.AddShadow(strings.Split(patterns, ","), signal); err != nil {
+		BadRequest(err.Error(), w)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func dropShadows(w http.ResponseWriter, req *http.Request) {
+	str, ok := mux.Vars(req)["names"]
+	if !ok {
+		BadRequest("missing signal names", w)
+		return
+	}
+	for _, s := range strings.Split(str, ",") {
+		market.DropShadow(s)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func promoteShadow(w http.ResponseWriter, req *http.Request) {
+	name, ok := mux.Vars(req)["name"]
+	if !ok {
+		BadRequest("missing signal name", w)
+		return
+	}
+	if err := market.PromoteShadow(name); err != nil {
+		BadRequest(err.Error(), w)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// listShadows returns the shadow signals side by side with their live versions, and what each
+// of them did since the shadow was added.
+func listShadows(w http.ResponseWriter, req *http.Request) {
+	names, _ := parseOptions(req.URL.Query(), "names")
+	bts, err := json.Marshal(market.CompareShadows(names))
+	if err != nil {
+		logger.Error.Println(err)
+		InternalError(w)
+		return
+	}
+	header := w.Header()
+	header.Set("Content-Length", strconv.Itoa(len(bts)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(bts)
+}
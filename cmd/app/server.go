@@ -108,6 +108,8 @@ func Mux(middleware Func) *mux.Router {
 		middleware(http.HandlerFunc(watchlist))).Methods("GET")
 	router.Handle("/watcher/last/{ticker}",
 		middleware(http.HandlerFunc(last))).Methods("GET")
+	router.Handle("/watcher/basis/{ticker}",
+		middleware(http.HandlerFunc(basis))).Methods("GET")
 	router.Handle("/watcher/is_synced/{ticker}/{frame}",
 		middleware(http.HandlerFunc(synced))).Methods("GET")
 	router.Handle("/watcher/watch/{ticker}",
@@ -122,6 +124,30 @@ func Mux(middleware Func) *mux.Router {
 		middleware(http.HandlerFunc(addSignal))).Methods("POST")
 	router.Handle("/evaluator/drop/{names}",
 		middleware(http.HandlerFunc(dropSignals))).Methods("POST")
+	router.Handle("/evaluator/update/{name}",
+		middleware(http.HandlerFunc(updateSignal))).Methods("POST", "PATCH")
+	router.Handle("/evaluator/rollback/{name}/{version}",
+		middleware(http.HandlerFunc(rollbackSignal))).Methods("POST")
+	router.Handle("/evaluator/versions/{name}",
+		middleware(http.HandlerFunc(signalVersions))).Methods("GET")
+	router.Handle("/evaluator/explain/{ticker}",
+		middleware(http.HandlerFunc(explainSignals))).Methods("GET")
+	router.Handle("/evaluator/dry_run",
+		middleware(http.HandlerFunc(dryRun))).Methods("POST")
+	router.Handle("/evaluator/schema",
+		middleware(http.HandlerFunc(signalSchema))).Methods("GET")
+	router.Handle("/evaluator/templates",
+		middleware(http.HandlerFunc(listTemplates))).Methods("GET")
+	router.Handle("/evaluator/instantiate/{template}/{patterns}",
+		middleware(http.HandlerFunc(instantiateTemplate))).Methods("POST")
+	router.Handle("/evaluator/shadow/list",
+		middleware(http.HandlerFunc(listShadows))).Methods("GET")
+	router.Handle("/evaluator/shadow/add/{patterns}",
+		middleware(http.HandlerFunc(addShadow))).Methods("POST")
+	router.Handle("/evaluator/shadow/drop/{names}",
+		middleware(http.HandlerFunc(dropShadows))).Methods("POST")
+	router.Handle("/evaluator/shadow/promote/{name}",
+		middleware(http.HandlerFunc(promoteShadow))).Methods("POST")
 
 	// notifier enpoints
 	router.Handle("/notifier/add_chat_ids/{chat_ids}",
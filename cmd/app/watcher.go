@@ -106,6 +106,31 @@ func last(w http.ResponseWriter, req *http.Request) {
 	w.Write(bts)
 }
 
+func basis(w http.ResponseWriter, req *http.Request) {
+	tickers, ok := parseVars(mux.Vars(req), "ticker")
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if !market.IsWatchingOn(tickers[0], "CASH") || !market.IsWatchingOn(tickers[0], "FUTURES") {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	type bases struct {
+		Bases tax.BasesJSON `json:"basis"`
+	}
+	bts, err := json.Marshal(bases{Bases: market.Basis(tickers[0])})
+	if err != nil {
+		logger.Error.Println(err)
+		InternalError(w)
+		return
+	}
+	header := w.Header()
+	header.Set("Content-Length", strconv.Itoa(len(bts)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(bts)
+}
+
 func synced(w http.ResponseWriter, req *http.Request) {
 	strs, ok := parseVars(mux.Vars(req), "ticker")
 	if !ok {
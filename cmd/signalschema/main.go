@@ -0,0 +1,22 @@
+// signalschema prints the JSON Schema of the signals, generated from the strategy types. The
+// published schema is regenerated with
+//
+//	go run ./cmd/signalschema > docs/signal.schema.json
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"follow.markets/internal/pkg/strategy"
+)
+
+func main() {
+	bts, err := json.MarshalIndent(strategy.SignalSchema(), "", "  ")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Println(string(bts))
+}
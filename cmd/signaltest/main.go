@@ -0,0 +1,92 @@
+// signaltest runs the test cases of signal files, it exits with a non zero code if any of them
+// fails. The arguments are signal files or directories of signal files, e.g.
+//
+//	go run ./cmd/signaltest ./configs/signals
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"follow.markets/internal/pkg/strategy"
+)
+
+func main() {
+	verbose := flag.Bool("v", false, "print the passed tests too")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: signaltest [-v] <signal file or directory>...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	files, err := signalFiles(flag.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	failed, total := 0, 0
+	for _, f := range files {
+		s, results, err := strategy.RunTestFile(f)
+		if err != nil {
+			fmt.Printf("FAIL %s: %s\n", f, err.Error())
+			failed++
+			continue
+		}
+		for _, res := range results {
+			total++
+			if res.Passed() {
+				if *verbose {
+					fmt.Printf("ok   %s/%s: triggered %v\n", s.Name, res.Name, res.Triggered)
+				}
+				continue
+			}
+			failed++
+			fmt.Printf("FAIL %s/%s: %s\n", s.Name, res.Name, describe(res))
+		}
+	}
+	fmt.Printf("%d of %d signal tests passed\n", total-failed, total)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// signalFiles returns the given files and the json files of the given directories.
+func signalFiles(args []string) ([]string, error) {
+	var out []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			out = append(out, arg)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(arg, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, matches...)
+	}
+	return out, nil
+}
+
+func describe(res *strategy.SignalTestResult) string {
+	if len(res.Error) > 0 {
+		return res.Error
+	}
+	var out []string
+	if len(res.Missing) > 0 {
+		out = append(out, fmt.Sprintf("missing %v", res.Missing))
+	}
+	if len(res.Unexpected) > 0 {
+		out = append(out, fmt.Sprintf("unexpected %v", res.Unexpected))
+	}
+	return fmt.Sprintf("%s, triggered %v, expected %v", strings.Join(out, ", "), res.Triggered, res.Expected)
+}
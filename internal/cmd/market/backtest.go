@@ -18,6 +18,7 @@ type backtest struct {
 	balance       big.Decimal
 	lossTolerance big.Decimal
 	profitMargin  big.Decimal
+	sizing        *strategy.Sizing
 
 	bt  *db.Backtest
 	r   *runner.Runner
@@ -25,9 +26,10 @@ type backtest struct {
 	rcs *ta.TradingRecord
 }
 
-func newBacktest(bt *db.Backtest) *backtest {
+func newBacktest(bt *db.Backtest, sizing *strategy.Sizing) *backtest {
 	out := &backtest{
 		bt:            bt,
+		sizing:        sizing,
 		balance:       big.NewDecimal(float64(bt.Balance)),
 		lossTolerance: big.NewDecimal(bt.LossTolerance),
 		profitMargin:  big.NewDecimal(bt.ProfitMargin),
@@ -37,8 +39,16 @@ func newBacktest(bt *db.Backtest) *backtest {
 		EntryRule:      strategy.NewRule(*bt.Signal),
 		RiskRewardRule: strategy.NewRiskRewardRule(-bt.LossTolerance, bt.ProfitMargin, true),
 	}
+	// the exits of the signal replace the loss tolerance and the profit margin of the backtest.
+	if bt.Signal.HasExit() {
+		out.s.ExitRule = strategy.NewExitRule(*bt.Signal)
+		out.s.RiskRewardRule = nil
+	}
+	// the frames of the runner include the one of the ATR of the sizing of the backtest.
+	sized := *bt.Signal
+	sized.Sizing = bt.Signal.GetSizing(sizing)
 	out.r = runner.NewRunner(bt.Ticker, &runner.RunnerConfigs{
-		LFrames:  bt.Signal.GetPeriods(),
+		LFrames:  sized.GetPeriods(),
 		IConfigs: tax.NewDefaultIndicatorConfigs(),
 	})
 	out.s.SetRunner(out.r)
@@ -47,6 +57,21 @@ func newBacktest(bt *db.Backtest) *backtest {
 	return out
 }
 
+// equity returns the balance of the backtest with the profits of its closed trades.
+func (bt backtest) equity() big.Decimal {
+	return bt.balance.Add(big.NewDecimal(ta.TotalProfitAnalysis{}.Analyze(bt.rcs)))
+}
+
+// quantity returns the quantity of an order opening a position at the price, sized by the
+// signal's or the tester's sizing model, or the whole balance without any.
+func (bt backtest) quantity(price big.Decimal) (big.Decimal, bool) {
+	sz := bt.bt.Signal.GetSizing(bt.sizing)
+	if sz == nil {
+		return bt.balance.Div(price), price.GT(big.ZERO)
+	}
+	return sz.Quantity(bt.r, *bt.bt.Signal, bt.equity(), price, big.ONE)
+}
+
 func (bt backtest) name() string {
 	return bt.r.GetName() + "-" + bt.bt.Signal.Name + "-" + time.Now().Format(simpleLayout)
 }
@@ -9,6 +9,7 @@ import (
 	"follow.markets/internal/pkg/runner"
 	"follow.markets/internal/pkg/strategy"
 	"follow.markets/pkg/config"
+	"github.com/sdcoffey/big"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -37,9 +38,31 @@ func Test_Backtest_Summary(t *testing.T) {
 		End:           time.Now(),
 		Status:        db.BacktestStatusUnknown,
 	}
-	bt := newBacktest(&btdb)
+	bt := newBacktest(&btdb, nil)
 
 	sm := bt.summary("")
 	assert.EqualValues(t, 0, sm["Profit"])
 	assert.EqualValues(t, 0, sm["PctGain"])
 }
+
+func Test_Backtest_Quantity(t *testing.T) {
+	signal, err := strategy.NewSignalFromBytes([]byte(`{"name": "sized", "expression": "CLOSE > 1"}`))
+	assert.EqualValues(t, nil, err)
+	btdb := db.Backtest{ID: 1, Name: "sized", Ticker: "BTCUSDT", Balance: 10, Market: runner.Cash, Signal: signal}
+
+	// the whole balance is traded without a sizing model.
+	bt := newBacktest(&btdb, nil)
+	qty, ok := bt.quantity(big.NewDecimal(2))
+	assert.EqualValues(t, true, ok)
+	assert.EqualValues(t, "5", qty.String())
+
+	bt = newBacktest(&btdb, &strategy.Sizing{Model: strategy.PercentEquitySizing, Percent: 50})
+	qty, ok = bt.quantity(big.NewDecimal(2))
+	assert.EqualValues(t, true, ok)
+	assert.EqualValues(t, "2.5", qty.String())
+
+	// the runner has the frame of the ATR of the volatility sizing.
+	bt = newBacktest(&btdb, &strategy.Sizing{Model: strategy.VolatilitySizing, Percent: 1, ATRPeriod: 300})
+	_, ok = bt.r.GetLines(5 * time.Minute)
+	assert.EqualValues(t, true, ok)
+}
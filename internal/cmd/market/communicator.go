@@ -20,6 +20,7 @@ type communicator struct {
 	evaluator2Notifier chan *message
 	evaluator2Streamer chan *message
 	evaluator2Trader   chan *message
+	evaluator2Watcher  chan *message
 	trader2Streamer    chan *message
 	trader2Notifier    chan *message
 	notifier2Trader    chan *message
@@ -35,6 +36,7 @@ func newCommunicator() *communicator {
 		evaluator2Notifier: make(chan *message),
 		evaluator2Streamer: make(chan *message),
 		evaluator2Trader:   make(chan *message, 10),
+		evaluator2Watcher:  make(chan *message),
 		trader2Streamer:    make(chan *message),
 		trader2Notifier:    make(chan *message),
 		notifier2Trader:    make(chan *message),
@@ -124,9 +126,14 @@ func (pl *payload) addResponseID() *payload {
 
 // strreaming channels of a payload data if agents request for streaming data.
 type streamingChannels struct {
-	bar   chan *ta.Candle
-	trade chan *tax.Trade
-	depth chan interface{}
+	bar     chan *ta.Candle
+	partial chan *ta.Candle
+	trade   chan *tax.Trade
+	depth   chan interface{}
+	book    chan *tax.OrderBook
+
+	// futures market data, mark price, open interest and liquidation.
+	derivative chan interface{}
 }
 
 // This method is to close all the streaming channels when an agent is done with streaming data.
@@ -137,10 +144,19 @@ func (scs *streamingChannels) close() {
 	if scs.bar != nil {
 		close(scs.bar)
 	}
+	if scs.partial != nil {
+		close(scs.partial)
+	}
 	if scs.trade != nil {
 		close(scs.trade)
 	}
 	if scs.depth != nil {
 		close(scs.depth)
 	}
+	if scs.book != nil {
+		close(scs.book)
+	}
+	if scs.derivative != nil {
+		close(scs.derivative)
+	}
 }
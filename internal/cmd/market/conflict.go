@@ -0,0 +1,163 @@
+package market
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/dlclark/regexp2"
+
+	"follow.markets/internal/pkg/runner"
+	"follow.markets/internal/pkg/strategy"
+	"follow.markets/pkg/config"
+)
+
+// conflictPolicy holds the policies resolving the signals firing on a runner at once, and
+// whether they are notified as a single notification.
+type conflictPolicy struct {
+	policy    string
+	tickers   []*tickerPolicy
+	aggregate bool
+}
+
+type tickerPolicy struct {
+	regex  *regexp2.Regexp
+	policy string
+}
+
+// firing is a signal fired on a runner, with the second leg of a pairs signal and the start
+// of the bar it fired on.
+type firing struct {
+	signal *strategy.Signal
+	leg    interface{}
+	bar    time.Time
+}
+
+// updateConfigs updates the conflict policies of the evaluator, all the signals go through
+// without them.
+func (e *evaluator) updateConfigs(configs *config.Configs) error {
+	cp := &conflictPolicy{policy: strategy.AllConflict}
+	if c := configs.Market.Evaluator.Conflict; c != nil {
+		if !strategy.IsConflictPolicy(c.Policy) {
+			return errors.New("unknown conflict policy " + c.Policy)
+		}
+		if c.Policy != "" {
+			cp.policy = strings.ToUpper(c.Policy)
+		}
+		cp.aggregate = c.Aggregate
+		for _, tc := range c.Tickers {
+			if tc == nil || !strategy.IsConflictPolicy(tc.Policy) {
+				return errors.New("invalid ticker conflict policy")
+			}
+			re, err := regexp2.Compile(tc.Pattern, 0)
+			if err != nil {
+				return err
+			}
+			cp.tickers = append(cp.tickers, &tickerPolicy{regex: re, policy: strings.ToUpper(tc.Policy)})
+		}
+	}
+	e.conflictMutex.Lock()
+	defer e.conflictMutex.Unlock()
+	e.conflict = cp
+	return nil
+}
+
+// conflictPolicy returns the conflict policy of the runner, and whether the signals firing on it
+// at once are aggregated.
+func (e *evaluator) conflictPolicy(r *runner.Runner) (string, bool) {
+	e.conflictMutex.Lock()
+	defer e.conflictMutex.Unlock()
+	if e.conflict == nil {
+		return strategy.AllConflict, false
+	}
+	for _, tp := range e.conflict.tickers {
+		if ok, err := tp.regex.MatchString(r.GetName()); err == nil && ok {
+			return tp.policy, e.conflict.aggregate
+		}
+	}
+	return e.conflict.policy, e.conflict.aggregate
+}
+
+// claim returns true if no signal has gone through on the current bar of the runner yet, the
+// bar is then claimed. It's for the FIRST policy, under which the first signal going through on
+// a bar wins over the signals firing later on the same bar, e.g. the intrabar ones.
+func (e *evaluator) claim(r *runner.Runner) bool {
+	c := r.LastCandle(r.SmallestFrame())
+	if c == nil {
+		return true
+	}
+	e.conflictMutex.Lock()
+	defer e.conflictMutex.Unlock()
+	if last, ok := e.claims[r.GetUniqueName()]; ok && !c.Period.Start.After(last) {
+		return false
+	}
+	e.claims[r.GetUniqueName()] = c.Period.Start
+	return true
+}
+
+// dispatch resolves the signals fired on the runner at once under its conflict policy, and sends
+// the ones going through to the notifier and the trader, highest priority first. The aggregated
+// signals are notified as a single notification per owner, except the pairs signals. Only the
+// signals going through are recorded as fired, for the signals referring to them.
+func (e *evaluator) dispatch(r *runner.Runner, fired []*firing) {
+	if len(fired) == 0 {
+		return
+	}
+	policy, aggregate := e.conflictPolicy(r)
+	signals, firings := strategy.Signals{}, map[*strategy.Signal]*firing{}
+	for _, f := range fired {
+		signals = append(signals, f.signal)
+		firings[f.signal] = f
+	}
+	resolved := strategy.ResolveConflicts(policy, signals)
+	if policy == strategy.FirstConflict && len(resolved) > 0 && !e.claim(r) {
+		resolved = strategy.Signals{}
+	}
+	if len(resolved) < len(signals) {
+		e.logger.Info.Println(e.newLog(r.GetUniqueName(), "dropped signals under the "+policy+" conflict policy: "+
+			strings.Join(signalNames(signals), ", ")+" -> "+strings.Join(signalNames(resolved), ", ")))
+	}
+	groups, owners := map[int64]strategy.Signals{}, []int64{}
+	for _, s := range resolved {
+		msg := e.communicator.newMessage(r, s, nil, firings[s].leg, nil)
+		e.communicator.evaluator2Trader <- msg
+		if !aggregate || s.IsPair() {
+			e.communicator.evaluator2Notifier <- msg
+			continue
+		}
+		var owner int64
+		if s.OwnerID != nil {
+			owner = *s.OwnerID
+		}
+		if _, ok := groups[owner]; !ok {
+			owners = append(owners, owner)
+		}
+		groups[owner] = append(groups[owner], s)
+	}
+	// an aggregated notification is the first signal with the others along.
+	for _, owner := range owners {
+		var others interface{}
+		if group := groups[owner]; len(group) > 1 {
+			others = group[1:]
+		}
+		e.communicator.evaluator2Notifier <- e.communicator.newMessage(r, groups[owner][0], nil, others, nil)
+	}
+	for _, s := range resolved {
+		if !firings[s].bar.IsZero() {
+			e.fired.Store(s.Name+"-"+r.GetUniqueName(), firings[s].bar)
+		}
+		if s.IsOnetime() {
+			if err := e.expire(s.Name); err != nil {
+				e.logger.Error.Println(e.newLog(s.Name, "failed to drop the onetime signal with err: "+err.Error()))
+			}
+		}
+	}
+}
+
+func signalNames(ss strategy.Signals) []string {
+	out := make([]string, 0, len(ss))
+	for _, s := range ss {
+		out = append(out, s.Name)
+	}
+	return out
+}
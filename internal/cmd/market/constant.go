@@ -20,6 +20,10 @@ const (
 
 var (
 	simpleLayout = fmt.Sprint(SimpleDateFormatV2, "T", SimpleTimeFormat)
+
+	// the stablecoins, fiat currencies and leveraged tokens, the signals defined on files
+	// aren't evaluated on them by default.
+	excludedBaseSuffixes = []string{"SUSD", "BUSD", "BVND", "PAX", "DAI", "TUSD", "USDC", "VAI", "BRL", "AUD", "BIRD", "EUR", "GBP", "BIDR", "DOWN", "UP", "BEAR", "BULL"}
 )
 
 const (
@@ -3,11 +3,16 @@ package market
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/dlclark/regexp2"
 
+	"follow.markets/internal/pkg/runner"
 	"follow.markets/internal/pkg/strategy"
+	tax "follow.markets/internal/pkg/techanex"
+	"follow.markets/pkg/config"
 	"follow.markets/pkg/log"
 	"follow.markets/pkg/util"
 )
@@ -16,6 +21,44 @@ type evaluator struct {
 	sync.Mutex
 	connected bool
 	signals   *sync.Map
+	streams   *sync.Map
+
+	// the number of signals added so far, the signals are evaluated in the order they're added.
+	added int64
+
+	// the locks of the streams of runners, the round trips to the streamer take seconds so
+	// they're made under the lock of their runner instead of the evaluator's.
+	streamLocks *sync.Map
+
+	// the last intrabar evaluation time of signals on runners.
+	intrabarMutex *sync.Mutex
+	intrabars     map[string]time.Time
+
+	// the last time signals fired on runners, for the signals referring to them.
+	fired *sync.Map
+
+	// the stages of the staged signals by signal name then runner, the mutex guards the maps
+	// while each stage is advanced under its own lock.
+	stageMutex *sync.Mutex
+	stages     map[string]map[string]*stage
+
+	// the shadow signals, evaluated without being notified or traded, and their records.
+	shadows      *sync.Map
+	shadowMutex  *sync.Mutex
+	shadowStates map[string]*shadowState
+
+	// the conflict policies of the signals firing on a runner at once, and the last bars claimed
+	// on runners under the FIRST policy.
+	conflictMutex *sync.Mutex
+	conflict      *conflictPolicy
+	claims        map[string]time.Time
+
+	// serializes the changes of the signals saved on the registry, so that their versions
+	// are in order.
+	registryMutex *sync.Mutex
+
+	// the timezone of the signal schedules without one.
+	location *time.Location
 
 	// shared properties with other market participants
 	logger       *log.Logger
@@ -29,20 +72,65 @@ type emember struct {
 	channels *streamingChannels
 	signals  strategy.Signals
 	patterns []string
+
+	// the universe of the signal and the unique names of the runners it's resolved to.
+	universe *strategy.Universe
+	members  []string
+
+	// the order the signal was added in, it's kept when the signal is replaced.
+	order int64
+}
+
+// stage is the stage of a staged signal on a runner.
+type stage struct {
+	sync.Mutex
+	state *strategy.StageState
+}
+
+// estream holds the streaming channels of the order book and trade data for a runner
+// that has signals with depth or trade conditions.
+type estream struct {
+	runner   *runner.Runner
+	channels *streamingChannels
 }
 
-func newEvaluator(participants *sharedParticipants) (*evaluator, error) {
-	if participants == nil || participants.communicator == nil || participants.logger == nil {
-		return nil, errors.New("missing shared participants")
+func newEvaluator(participants *sharedParticipants, configs *config.Configs) (*evaluator, error) {
+	if configs == nil || participants == nil || participants.communicator == nil || participants.logger == nil {
+		return nil, errors.New("missing shared participants or configs")
+	}
+	location, err := time.LoadLocation(configs.Market.Base.LocalTime)
+	if err != nil {
+		participants.logger.Warning.Println(fmt.Sprintf("invalid local timezone %s, using UTC", configs.Market.Base.LocalTime))
+		location = time.UTC
 	}
 	e := &evaluator{
 		connected: false,
 		signals:   &sync.Map{},
+		streams:   &sync.Map{},
+
+		streamLocks: &sync.Map{},
+
+		intrabarMutex: &sync.Mutex{},
+		intrabars:     make(map[string]time.Time),
+		fired:         &sync.Map{},
+		stageMutex:    &sync.Mutex{},
+		stages:        make(map[string]map[string]*stage),
+		shadows:       &sync.Map{},
+		shadowMutex:   &sync.Mutex{},
+		shadowStates:  make(map[string]*shadowState),
+		conflictMutex: &sync.Mutex{},
+		claims:        make(map[string]time.Time),
+		registryMutex: &sync.Mutex{},
+
+		location: location,
 
 		logger:       participants.logger,
 		provider:     participants.provider,
 		communicator: participants.communicator,
 	}
+	if err := e.updateConfigs(configs); err != nil {
+		return nil, err
+	}
 	return e, nil
 }
 
@@ -71,22 +159,25 @@ func (e *evaluator) add(patterns []string, s *strategy.Signal) error {
 	e.Lock()
 	defer e.Unlock()
 
+	if err := s.CheckReferences(e); err != nil {
+		return err
+	}
+	s.Bind(e)
 	var mem emember
 	val, ok := e.signals.Load(s.Name)
 	if !ok {
-		reges := make([]*regexp2.Regexp, 0)
-		for _, t := range patterns {
-			reg, err := regexp2.Compile(t, 0)
-			if err != nil {
-				return err
-			}
-			reges = append(reges, reg)
+		reges, err := compilePatterns(patterns)
+		if err != nil {
+			return err
 		}
+		e.added++
 		mem = emember{
 			name:     s.Name,
 			regex:    reges,
 			signals:  strategy.Signals{s},
 			patterns: patterns,
+			universe: s.Universe,
+			order:    e.added,
 		}
 		e.signals.Store(s.Name, mem)
 	} else {
@@ -94,33 +185,131 @@ func (e *evaluator) add(patterns []string, s *strategy.Signal) error {
 		mem.signals = append(mem.signals, s)
 		e.signals.Store(s.Name, mem)
 	}
-	//if s.IsOnTrade() {
-	//	go e.await(mem, s)
-	//}
 	return nil
 }
 
+// replace replaces the signal of the same name, and its patterns, or adds it if it's
+// not on the evaluator yet.
+func (e *evaluator) replace(patterns []string, s *strategy.Signal) error {
+	reges, err := compilePatterns(patterns)
+	if err != nil {
+		return err
+	}
+	e.Lock()
+	defer e.Unlock()
+	if err := s.CheckReferences(e); err != nil {
+		return err
+	}
+	s.Bind(e)
+	e.resetStages(s.Name)
+	var order int64
+	if val, ok := e.signals.Load(s.Name); ok {
+		order = val.(emember).order
+	} else {
+		e.added++
+		order = e.added
+	}
+	e.signals.Store(s.Name, emember{
+		name:     s.Name,
+		regex:    reges,
+		signals:  strategy.Signals{s},
+		patterns: patterns,
+		universe: s.Universe,
+		order:    order,
+	})
+	return nil
+}
+
+// sortByOrder sorts the signals in the order they were added, the order of the FIRST conflict
+// policy.
+func (e *evaluator) sortByOrder(signals strategy.Signals) {
+	order := func(s *strategy.Signal) int64 {
+		if val, ok := e.signals.Load(s.Name); ok {
+			return val.(emember).order
+		}
+		return 0
+	}
+	sort.SliceStable(signals, func(i, j int) bool { return order(signals[i]) < order(signals[j]) })
+}
+
+// resolve resolves the universes of the signals on the given runners, it's called again
+// whenever the watchlist changes.
+func (e *evaluator) resolve(runners []*runner.Runner) {
+	e.Lock()
+	defer e.Unlock()
+	for _, store := range []*sync.Map{e.signals, e.shadows} {
+		store.Range(func(k, v interface{}) bool {
+			m := v.(emember)
+			if m.universe != nil {
+				m.members = m.universe.Resolve(runners)
+				store.Store(k, m)
+			}
+			return true
+		})
+	}
+}
+
+// GetSignal returns a copy of the signal of the given name, it resolves the references of
+// the signals to other signals.
+func (e *evaluator) GetSignal(name string) (*strategy.Signal, bool) {
+	val, ok := e.signals.Load(name)
+	if !ok || len(val.(emember).signals) == 0 {
+		return nil, false
+	}
+	return val.(emember).signals[:1].Copy()[0], true
+}
+
+// LastFired returns the start of the last bar the signal of the given name fired on the runner.
+func (e *evaluator) LastFired(name string, r *runner.Runner) (time.Time, bool) {
+	val, ok := e.fired.Load(name + "-" + r.GetUniqueName())
+	if !ok {
+		return time.Time{}, false
+	}
+	return val.(time.Time), true
+}
+
+// getPatterns returns the patterns of the signal of the given name.
+func (e *evaluator) getPatterns(name string) ([]string, bool) {
+	val, ok := e.signals.Load(name)
+	if !ok {
+		return nil, false
+	}
+	return val.(emember).patterns, true
+}
+
 // drop removes the given signal from the evaluator. After the removal, the singal won't be
 // evaluated any longer.
 func (e *evaluator) drop(name string) error {
 	e.Lock()
-	defer e.Unlock()
-
 	if _, ok := e.signals.Load(name); !ok {
+		e.Unlock()
 		return nil
 	}
 	e.signals.Delete(name)
+	e.resetStages(name)
+	e.Unlock()
+	e.pruneStreams()
 	return nil
 }
 
-// getByTicker returns a slice of signals that are applicable to the given ticker.
-func (e *evaluator) getByTicker(ticker string) strategy.Signals {
+// getByRunner returns a slice of signals that are applicable to the given runner, either
+// matching one of their patterns or in their universe.
+func (e *evaluator) getByRunner(r *runner.Runner) strategy.Signals {
+	return getByRunner(e.signals, r)
+}
+
+func getByRunner(store *sync.Map, r *runner.Runner) strategy.Signals {
 	out := strategy.Signals{}
-	e.signals.Range(func(k, v interface{}) bool {
+	store.Range(func(k, v interface{}) bool {
 		m := v.(emember)
+		if util.StringSliceContains(m.members, r.GetUniqueName()) {
+			out = append(out, m.signals.Copy()...)
+			return true
+		}
 		for _, re := range m.regex {
-			if isMatched, err := re.MatchString(ticker); err == nil && isMatched {
+			if isMatched, err := re.MatchString(r.GetName()); err == nil && isMatched {
 				out = append(out, m.signals.Copy()...)
+				break
 			}
 		}
 		return true
@@ -130,8 +319,12 @@ func (e *evaluator) getByTicker(ticker string) strategy.Signals {
 
 // getByName return a slice of signals with the given name.
 func (e *evaluator) getByNames(names []string) strategy.Signals {
+	return getByNames(e.signals, names)
+}
+
+func getByNames(store *sync.Map, names []string) strategy.Signals {
 	out := strategy.Signals{}
-	e.signals.Range(func(k, v interface{}) bool {
+	store.Range(func(k, v interface{}) bool {
 		if len(names) == 0 || util.StringSliceContains(names, k.(string)) {
 			out = append(out, v.(emember).signals.Copy()...)
 		}
@@ -140,44 +333,318 @@ func (e *evaluator) getByNames(names []string) strategy.Signals {
 	return out
 }
 
-//func (e *evaluator) await(mem emember, s *strategy.Signal) {
-//	for !e.registerStreamingChannel(mem) {
-//		e.logger.Error.Println(e.newLog(mem.name, "failed to register streaming data"))
-//	}
-//	go func() {
-//		for msg := range mem.tChann {
-//			if s.Evaluate(nil, msg) {
-//				e.communicator.evaluator2Notifier <- e.communicator.newMessage(s, nil)
-//			}
-//		}
-//	}()
-//}
-
-func (e *evaluator) registerStreamingChannel(mem emember) bool {
+// dryRun explains the given signal, which isn't added to the evaluator, on the runners whose
+// names match any of the patterns, on all runners if there is no pattern.
+func (e *evaluator) dryRun(runners []*runner.Runner, patterns []string, s *strategy.Signal) ([]*strategy.SignalExplanation, error) {
+	reges, err := compilePatterns(patterns)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.CheckReferences(e); err != nil {
+		return nil, err
+	}
+	s.Bind(e)
+	for _, r := range runners {
+		if s.IsPair() && r.GetUniqueName() == s.Pair.Leg {
+			s.SetLeg(r)
+		}
+	}
+	out := []*strategy.SignalExplanation{}
+	for _, r := range runners {
+		isMatched := len(reges) == 0
+		for _, re := range reges {
+			if ok, err := re.MatchString(r.GetName()); err == nil && ok {
+				isMatched = true
+				break
+			}
+		}
+		if isMatched {
+			out = append(out, s.Explain(r, nil))
+		}
+	}
+	return out, nil
+}
+
+// subscribeStreams subscribes the order book and trade streams for the runner if any
+// of the given signals needs them. The streamed data is synced to the runner, so the
+// signals are evaluated on the latest order book and recent trades.
+func (e *evaluator) subscribeStreams(r *runner.Runner, signals strategy.Signals) {
+	needsDepth, needsTrades := false, false
+	for _, s := range signals {
+		needsDepth = needsDepth || s.NeedsDepth()
+		needsTrades = needsTrades || s.NeedsTrades()
+	}
+	name := r.GetUniqueName(string(EVALUATOR))
+	if !e.shouldResubscribe(name, needsDepth, needsTrades) {
+		return
+	}
+	lock := e.streamLock(name)
+	lock.Lock()
+	defer lock.Unlock()
+	// checks again since the streams might have been updated while waiting for the lock.
+	if !e.shouldResubscribe(name, needsDepth, needsTrades) {
+		return
+	}
+	e.unsubscribeStreams(name)
+	if !needsDepth && !needsTrades {
+		return
+	}
+	cs := &streamingChannels{}
+	if needsDepth {
+		cs.book = make(chan *tax.OrderBook, 10)
+	}
+	if needsTrades {
+		cs.trade = make(chan *tax.Trade, 100)
+	}
+	if !e.registerStreamingChannel(r, cs) {
+		e.logger.Error.Println(e.newLog(r.GetName(), "failed to register streaming data"))
+		return
+	}
+	e.streams.Store(name, estream{runner: r, channels: cs})
+	if cs.book != nil {
+		go func() {
+			for book := range cs.book {
+				r.SetOrderBook(book)
+			}
+		}()
+	}
+	if cs.trade != nil {
+		go func() {
+			for trade := range cs.trade {
+				r.SyncTrade(trade)
+			}
+		}()
+	}
+}
+
+// streamLock returns the lock of the streams of the given name.
+func (e *evaluator) streamLock(name string) *sync.Mutex {
+	val, _ := e.streamLocks.LoadOrStore(name, &sync.Mutex{})
+	return val.(*sync.Mutex)
+}
+
+// shouldResubscribe returns true if the current streams of the given name don't match
+// the needed streams.
+func (e *evaluator) shouldResubscribe(name string, needsDepth, needsTrades bool) bool {
+	val, ok := e.streams.Load(name)
+	if !ok {
+		return needsDepth || needsTrades
+	}
+	cs := val.(estream).channels
+	return (cs.book != nil) != needsDepth || (cs.trade != nil) != needsTrades
+}
+
+// unsubscribeStreams unsubscribes the order book and trade streams of the given name,
+// the streamer closes the streaming channels. It's called under the lock of the streams.
+func (e *evaluator) unsubscribeStreams(name string) {
+	val, ok := e.streams.Load(name)
+	if !ok {
+		return
+	}
+	st := val.(estream)
+	if !e.registerStreamingChannel(st.runner, st.channels) {
+		e.logger.Error.Println(e.newLog(st.runner.GetName(), "failed to unregister streaming data"))
+	}
+	st.runner.SetOrderBook(nil)
+	e.streams.Delete(name)
+}
+
+// pruneStreams unsubscribes the streams of the runners that no longer have any signal
+// with depth or trade conditions.
+func (e *evaluator) pruneStreams() {
+	e.streams.Range(func(k, v interface{}) bool {
+		r := v.(estream).runner
+		needsDepth, needsTrades := false, false
+		for _, s := range append(e.getByRunner(r), e.getShadowsByRunner(r)...) {
+			needsDepth = needsDepth || s.NeedsDepth()
+			needsTrades = needsTrades || s.NeedsTrades()
+		}
+		if needsDepth || needsTrades {
+			return true
+		}
+		lock := e.streamLock(k.(string))
+		lock.Lock()
+		defer lock.Unlock()
+		// checks again since the streams might have been updated while waiting for the lock.
+		if e.shouldResubscribe(k.(string), false, false) {
+			e.unsubscribeStreams(k.(string))
+		}
+		return true
+	})
+}
+
+// registerStreamingChannel registers, or unregisters if it's already registered, the
+// streaming channels of the runner with the streamer.
+func (e *evaluator) registerStreamingChannel(r *runner.Runner, cs *streamingChannels) bool {
 	doneStreamingRegister := false
 	var maxTries int
 	for !doneStreamingRegister && maxTries <= 3 {
 		resC := make(chan *payload)
-		e.communicator.evaluator2Streamer <- e.communicator.newMessage(nil, nil, nil, nil, resC)
+		e.communicator.evaluator2Streamer <- e.communicator.newMessage(r, nil, cs, nil, resC)
 		doneStreamingRegister = (<-resC).what.dynamic.(bool)
 		maxTries++
 	}
 	return doneStreamingRegister
 }
 
+// shouldEvaluateIntrabar returns true if the signal is evaluated intrabar and the minimum
+// interval since its last intrabar evaluation, of the given key, has passed.
+func (e *evaluator) shouldEvaluateIntrabar(key string, s *strategy.Signal) bool {
+	if !s.IsIntrabar() {
+		return false
+	}
+	e.intrabarMutex.Lock()
+	defer e.intrabarMutex.Unlock()
+	if last, ok := e.intrabars[key]; ok && time.Now().Sub(last) < s.GetMinInterval() {
+		return false
+	}
+	e.intrabars[key] = time.Now()
+	return true
+}
+
+// processWatcherRequest evaluates the signals applicable to the runner, in the order they were
+// added, and their shadows. All signals are evaluated on candle close, only intrabar signals are
+// evaluated on partial candle updates, on the view of the runner with the partial candle sent
+// along. The signals fired at once are dispatched together with the runner.
 func (e *evaluator) processWatcherRequest(msg *message) {
 	r := msg.request.what.runner
-	signals := e.getByTicker(r.GetName())
+	view, isIntrabar := msg.request.what.dynamic.(*runner.Runner)
+	signals, shadows := e.getByRunner(r), e.getShadowsByRunner(r)
+	if !isIntrabar {
+		view = r
+		e.subscribeStreams(r, append(signals, shadows...))
+		r.SetIntrabar(hasIntrabar(append(signals, shadows...)))
+	}
+	e.sortByOrder(signals)
+	fired := []*firing{}
 	for _, s := range signals {
-		if s.Evaluate(r, nil) {
-			msg := e.communicator.newMessage(r, s, nil, nil, nil)
-			e.communicator.evaluator2Notifier <- msg
-			e.communicator.evaluator2Trader <- msg
-			if s.IsOnetime() {
-				_ = e.drop(s.Name)
-			}
+		if leg, ok := e.evaluate(view, s, isIntrabar, false); ok {
+			bar, _ := s.LastBar(view)
+			fired = append(fired, &firing{signal: s, leg: leg, bar: bar})
 		}
 	}
+	e.dispatch(r, fired)
+	for _, s := range shadows {
+		e.evaluate(view, s, isIntrabar, true)
+	}
+}
+
+// hasIntrabar returns true if one of the signals is evaluated intrabar.
+func hasIntrabar(signals strategy.Signals) bool {
+	for _, s := range signals {
+		if s.IsIntrabar() {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluate evaluates the signal on the runner, it returns true, with the second leg of a pairs
+// signal, if the signal fires. A fired shadow signal is only recorded.
+func (e *evaluator) evaluate(r *runner.Runner, s *strategy.Signal, isIntrabar, shadow bool) (interface{}, bool) {
+	if !s.IsActive(r, time.Now(), e.location) {
+		return nil, false
+	}
+	key := s.Name + "-" + r.GetUniqueName()
+	if shadow {
+		key = shadowKey(s, r)
+	}
+	if isIntrabar && !e.shouldEvaluateIntrabar(key, s) {
+		return nil, false
+	}
+	// a pairs signal is evaluated with its second leg, which is sent along when it fires.
+	var leg interface{}
+	if s.IsPair() {
+		lr := e.getLeg(r, s)
+		if lr == nil {
+			return nil, false
+		}
+		s.SetLeg(lr)
+		leg = lr
+	}
+	fired := false
+	if s.IsStaged() {
+		name := s.Name
+		if shadow {
+			name = shadowStageName(s.Name)
+		}
+		fired = e.advance(name, r, s, !shadow)
+	} else {
+		fired = s.Evaluate(r, nil)
+	}
+	if !fired {
+		return nil, false
+	}
+	e.record(r, s, shadow)
+	if shadow {
+		if s.IsOnetime() {
+			e.dropShadow(s.Name)
+		}
+		return nil, false
+	}
+	return leg, true
+}
+
+// advance advances the staged signal from its stage of the given name on the runner, it notifies
+// the transitions set to be notified, if notify is set, and returns true if the signal fires.
+func (e *evaluator) advance(name string, r *runner.Runner, s *strategy.Signal, notify bool) bool {
+	e.stageMutex.Lock()
+	stages, ok := e.stages[name]
+	if !ok {
+		stages = make(map[string]*stage)
+		e.stages[name] = stages
+	}
+	st, ok := stages[r.GetUniqueName()]
+	if !ok {
+		st = &stage{}
+		stages[r.GetUniqueName()] = st
+	}
+	e.stageMutex.Unlock()
+
+	st.Lock()
+	state, tr, fired := s.Advance(r, st.state)
+	st.state = state
+	st.Unlock()
+	if notify && tr != nil && tr.Notify && !fired {
+		e.communicator.evaluator2Notifier <- e.communicator.newMessage(r, s, nil, tr, nil)
+	}
+	return fired
+}
+
+// getStage returns a copy of the stage of the given name on the runner, nil if it's at the
+// initial stage.
+func (e *evaluator) getStage(name, runner string) *strategy.StageState {
+	e.stageMutex.Lock()
+	st, ok := e.stages[name][runner]
+	e.stageMutex.Unlock()
+	if !ok {
+		return nil
+	}
+	st.Lock()
+	defer st.Unlock()
+	if st.state == nil {
+		return nil
+	}
+	state := *st.state
+	return &state
+}
+
+// resetStages resets the stages of the signal of the given name on all runners.
+func (e *evaluator) resetStages(name string) {
+	e.stageMutex.Lock()
+	defer e.stageMutex.Unlock()
+	delete(e.stages, name)
+}
+
+// getLeg returns the runner of the second leg of the pairs signal from the watcher, nil if
+// it isn't watched or is the given runner.
+func (e *evaluator) getLeg(r *runner.Runner, s *strategy.Signal) *runner.Runner {
+	if s.Pair.Leg == r.GetUniqueName() {
+		return nil
+	}
+	resC := make(chan *payload)
+	e.communicator.evaluator2Watcher <- e.communicator.newMessage(nil, nil, nil, s.Pair.Leg, resC)
+	return (<-resC).what.runner
 }
 
 func (e *evaluator) processStreamerRequest(msg *message) {
@@ -187,6 +654,18 @@ func (e *evaluator) processStreamerRequest(msg *message) {
 	//	}
 }
 
+func compilePatterns(patterns []string) ([]*regexp2.Regexp, error) {
+	reges := make([]*regexp2.Regexp, 0)
+	for _, t := range patterns {
+		reg, err := regexp2.Compile(t, 0)
+		if err != nil {
+			return nil, err
+		}
+		reges = append(reges, reg)
+	}
+	return reges, nil
+}
+
 func (e *evaluator) newLog(ticker, message string) string {
 	return fmt.Sprintf("[evaluator] %s: %s", ticker, message)
 }
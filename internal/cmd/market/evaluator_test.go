@@ -1,6 +1,208 @@
 package market
 
-import "testing"
+import (
+	"sync"
+	"testing"
+	"time"
+
+	bn "github.com/adshao/go-binance/v2"
+	"github.com/stretchr/testify/assert"
+
+	"follow.markets/internal/pkg/runner"
+	"follow.markets/internal/pkg/strategy"
+	tax "follow.markets/internal/pkg/techanex"
+	"follow.markets/pkg/config"
+	"follow.markets/pkg/log"
+)
 
 func Test_Evaluator(t *testing.T) {
 }
+
+func newTestEvaluator() *evaluator {
+	return &evaluator{
+		signals:       &sync.Map{},
+		streams:       &sync.Map{},
+		streamLocks:   &sync.Map{},
+		intrabarMutex: &sync.Mutex{},
+		intrabars:     make(map[string]time.Time),
+		fired:         &sync.Map{},
+		stageMutex:    &sync.Mutex{},
+		stages:        make(map[string]map[string]*stage),
+		shadows:       &sync.Map{},
+		shadowMutex:   &sync.Mutex{},
+		shadowStates:  make(map[string]*shadowState),
+		conflictMutex: &sync.Mutex{},
+		claims:        make(map[string]time.Time),
+		registryMutex: &sync.Mutex{},
+		location:      time.UTC,
+		logger:        log.NewLogger(),
+		communicator:  newCommunicator(),
+	}
+}
+
+func Test_Evaluator_Shadow(t *testing.T) {
+	e := newTestEvaluator()
+	s, err := strategy.NewSignalFromBytes([]byte(`{"name": "shadowed", "expression": "CLOSE > 5"}`))
+	assert.EqualValues(t, nil, err)
+	assert.EqualValues(t, nil, e.addShadow([]string{"BTC"}, s))
+
+	r := runner.NewRunner("BTCUSDT", nil)
+	kline := &bn.Kline{OpenTime: 1499040000000, Open: "1", High: "6", Low: "0", Close: "6", Volume: "1", TradeNum: 1}
+	assert.EqualValues(t, true, r.SyncCandle(tax.ConvertBinanceKline(kline, nil)))
+
+	// the shadow is neither on the live signals nor notified, its firing is recorded.
+	assert.EqualValues(t, 0, len(e.getByRunner(r)))
+	shadows := e.getShadowsByRunner(r)
+	assert.EqualValues(t, 1, len(shadows))
+	e.evaluate(r, shadows[0], false, true)
+	e.record(r, shadows[0], false)
+	e.record(r, &strategy.Signal{Name: "unknown"}, true)
+
+	cs := e.compareShadows(nil)
+	assert.EqualValues(t, 1, len(cs))
+	assert.EqualValues(t, "shadowed", cs[0].Name)
+	assert.EqualValues(t, []string{"BTC"}, cs[0].ShadowPatterns)
+	assert.True(t, cs[0].Live == nil)
+	assert.EqualValues(t, 1, len(cs[0].ShadowRecords))
+	assert.EqualValues(t, "BTCUSDT", cs[0].ShadowRecords[0].Runner)
+	assert.EqualValues(t, 6, cs[0].ShadowRecords[0].Price)
+	assert.EqualValues(t, 1, len(cs[0].LiveRecords))
+	_, ok := e.LastFired("shadowed", r)
+	assert.EqualValues(t, false, ok)
+
+	e.dropShadow("shadowed")
+	assert.EqualValues(t, 0, len(e.compareShadows(nil)))
+	assert.EqualValues(t, 0, len(e.shadowStates))
+}
+
+func Test_Evaluator_Conflict(t *testing.T) {
+	e := newTestEvaluator()
+	configs := &config.Configs{}
+	configs.Market.Evaluator.Conflict = &config.Conflict{Policy: "cancel", Aggregate: true,
+		Tickers: []*config.TickerConflict{{Pattern: "ETH", Policy: strategy.FirstConflict}}}
+	assert.EqualValues(t, nil, e.updateConfigs(configs))
+	configs.Market.Evaluator.Conflict.Policy = "LAST"
+	assert.EqualValues(t, "unknown conflict policy LAST", e.updateConfigs(configs).Error())
+
+	traded, notified := make(chan *message, 10), make(chan *message, 10)
+	go func() {
+		for msg := range e.communicator.evaluator2Trader {
+			traded <- msg
+		}
+	}()
+	go func() {
+		for msg := range e.communicator.evaluator2Notifier {
+			notified <- msg
+		}
+	}()
+	bull := &strategy.Signal{Name: "bull", SignalType: strategy.BullishSignal}
+	bear := &strategy.Signal{Name: "bear", SignalType: strategy.BearishSignal}
+	other := &strategy.Signal{Name: "other", Priority: 1}
+	more := &strategy.Signal{Name: "more"}
+
+	// the opposing signals cancel each other, the others are aggregated.
+	r := runner.NewRunner("BTCUSDT", nil)
+	e.dispatch(r, []*firing{{signal: bull}, {signal: bear}, {signal: other}, {signal: more}})
+	assert.EqualValues(t, "other", (<-traded).request.what.signal.Name)
+	assert.EqualValues(t, "more", (<-traded).request.what.signal.Name)
+	msg := <-notified
+	assert.EqualValues(t, "other", msg.request.what.signal.Name)
+	assert.EqualValues(t, strategy.Signals{more}, msg.request.what.dynamic)
+
+	// the first signal claims the bar.
+	r = runner.NewRunner("ETHUSDT", nil)
+	kline := &bn.Kline{OpenTime: 1499040000000, Open: "1", High: "6", Low: "0", Close: "6", Volume: "1", TradeNum: 1}
+	assert.EqualValues(t, true, r.SyncCandle(tax.ConvertBinanceKline(kline, nil)))
+	bar := time.UnixMilli(kline.OpenTime)
+	e.dispatch(r, []*firing{{signal: bull, bar: bar}, {signal: bear, bar: bar}})
+	assert.EqualValues(t, "bull", (<-traded).request.what.signal.Name)
+	assert.EqualValues(t, "bull", (<-notified).request.what.signal.Name)
+	e.dispatch(r, []*firing{{signal: bear, bar: bar}})
+	assert.EqualValues(t, 0, len(traded))
+
+	// only the signals going through are recorded as fired.
+	fired, ok := e.LastFired("bull", r)
+	assert.EqualValues(t, true, ok)
+	assert.EqualValues(t, bar, fired)
+	_, ok = e.LastFired("bear", r)
+	assert.EqualValues(t, false, ok)
+}
+
+func Test_Evaluator_Order(t *testing.T) {
+	e := newTestEvaluator()
+	signals := strategy.Signals{}
+	for _, name := range []string{"zeta", "alpha", "mid"} {
+		s, err := strategy.NewSignalFromBytes([]byte(`{"name": "` + name + `", "expression": "CLOSE > 1"}`))
+		assert.EqualValues(t, nil, err)
+		assert.EqualValues(t, nil, e.add([]string{"BTC"}, s))
+		signals = append(signals, s)
+	}
+
+	// the signals are in the order they were added, a replaced signal keeps its place.
+	s, err := strategy.NewSignalFromBytes([]byte(`{"name": "zeta", "expression": "CLOSE > 2"}`))
+	assert.EqualValues(t, nil, err)
+	assert.EqualValues(t, nil, e.replace([]string{"BTC"}, s))
+	signals = strategy.Signals{signals[2], signals[1], s}
+	e.sortByOrder(signals)
+	assert.EqualValues(t, []string{"zeta", "alpha", "mid"}, []string{signals[0].Name, signals[1].Name, signals[2].Name})
+}
+
+func Test_Evaluator_Stages(t *testing.T) {
+	e := newTestEvaluator()
+	s, err := strategy.NewSignalFromBytes([]byte(`{"name": "staged", "stages": {"transitions": [
+		{"to": "ARMED", "expression": "CLOSE > 5", "notify": true},
+		{"from": "ARMED", "to": "TRIGGER", "expression": "CLOSE < 5"}]}}`))
+	assert.EqualValues(t, nil, err)
+	r := runner.NewRunner("BTCUSDT", nil)
+	kline := &bn.Kline{OpenTime: 1499040000000, Open: "1", High: "6", Low: "0", Close: "6", Volume: "1", TradeNum: 1}
+	assert.EqualValues(t, true, r.SyncCandle(tax.ConvertBinanceKline(kline, nil)))
+
+	// the notification of a transition is sent after the stage is advanced, it doesn't block
+	// the other stages. The signals are evaluated on copies like the evaluator does.
+	done := make(chan bool)
+	go func() { done <- e.advance("staged", r, strategy.Signals{s}.Copy()[0], true) }()
+	assert.EqualValues(t, false, e.advance("staged-v2", r, strategy.Signals{s}.Copy()[0], false))
+	assert.Eventually(t, func() bool {
+		st := e.getStage("staged", r.GetUniqueName())
+		return st != nil && st.Name == "ARMED"
+	}, time.Second, 10*time.Millisecond)
+	msg := <-e.communicator.evaluator2Notifier
+	assert.EqualValues(t, "ARMED", msg.request.what.dynamic.(*strategy.Transition).To)
+	assert.EqualValues(t, false, <-done)
+	assert.EqualValues(t, "ARMED", e.getStage("staged-v2", r.GetUniqueName()).Name)
+
+	// the stages of the other signals sharing the prefix of the name are kept.
+	e.resetStages("staged")
+	assert.True(t, e.getStage("staged", r.GetUniqueName()) == nil)
+	assert.EqualValues(t, "ARMED", e.getStage("staged-v2", r.GetUniqueName()).Name)
+}
+
+func Test_Evaluator_SubscribeStreams(t *testing.T) {
+	e := newTestEvaluator()
+	s, err := strategy.NewSignalFromBytes([]byte(`{"name": "depth", "expression": "depth.IMBALANCE(percent=2) > 0.5"}`))
+	assert.EqualValues(t, nil, err)
+	assert.EqualValues(t, true, s.NeedsDepth())
+	r := runner.NewRunner("BTCUSDT", nil)
+
+	// the round trip to the streamer doesn't hold the evaluator's lock.
+	done := make(chan struct{})
+	go func() {
+		e.subscribeStreams(r, strategy.Signals{s})
+		close(done)
+	}()
+	msg := <-e.communicator.evaluator2Streamer
+	assert.EqualValues(t, nil, e.add([]string{"BTC"}, s))
+	msg.response <- e.communicator.newPayload(nil, nil, nil, true)
+	<-done
+	_, ok := e.streams.Load(r.GetUniqueName(string(EVALUATOR)))
+	assert.EqualValues(t, true, ok)
+
+	// the streams are pruned once no signal needs them.
+	go func() {
+		msg := <-e.communicator.evaluator2Streamer
+		msg.response <- e.communicator.newPayload(nil, nil, nil, true)
+	}()
+	assert.EqualValues(t, nil, e.drop("depth"))
+	_, ok = e.streams.Load(r.GetUniqueName(string(EVALUATOR)))
+	assert.EqualValues(t, false, ok)
+}
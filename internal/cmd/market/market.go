@@ -12,6 +12,7 @@ import (
 	"github.com/dlclark/regexp2"
 	"github.com/sdcoffey/big"
 
+	db "follow.markets/internal/pkg/database"
 	"follow.markets/internal/pkg/runner"
 	"follow.markets/internal/pkg/strategy"
 	tax "follow.markets/internal/pkg/techanex"
@@ -68,7 +69,7 @@ func NewMarket(configFilePath *string) (*MarketStruct, error) {
 	if err != nil {
 		return nil, err
 	}
-	evaluator, err := newEvaluator(common)
+	evaluator, err := newEvaluator(common, configs)
 	if err != nil {
 		return nil, err
 	}
@@ -98,6 +99,13 @@ func NewMarket(configFilePath *string) (*MarketStruct, error) {
 		if err := Market.initSignals(); err != nil {
 			common.logger.Error.Println("failed to init signals with err: ", err)
 		}
+		go func() {
+			// the universes of the signals depend on the watchlist and the volumes of the runners.
+			for {
+				time.Sleep(time.Minute)
+				Market.evaluator.resolve(Market.watcher.list())
+			}
+		}()
 		go func() {
 			for {
 				if err := Market.initWatchlist(); err != nil {
@@ -155,7 +163,7 @@ func (m *MarketStruct) parseRunnerConfigs(market runner.MarketType) *runner.Runn
 }
 
 // watch initializes the watching process from watcher on watchlist specified
-// in the config file.
+// in the config file, the universes of the signals are resolved as the runners are added.
 func (m *MarketStruct) initWatchlist() error {
 	stats, err := m.watcher.provider.binSpot.NewListPriceChangeStatsService().Do(context.Background())
 	if err != nil {
@@ -190,6 +198,8 @@ func (m *MarketStruct) initWatchlist() error {
 				}
 				if err := m.watcher.watch(s.Symbol, m.parseRunnerConfigs(runner.Cash), fd); err != nil {
 					m.watcher.logger.Error.Println(m.watcher.newLog(s.Symbol+"-"+string(runner.Cash), err.Error()))
+				} else {
+					m.evaluator.resolve(m.watcher.list())
 				}
 				time.Sleep(time.Second * 15)
 			}
@@ -209,6 +219,8 @@ func (m *MarketStruct) initWatchlist() error {
 				}
 				if err := m.watcher.watch(s.Symbol, m.parseRunnerConfigs(runner.Futures), fd); err != nil {
 					m.watcher.logger.Error.Println(m.watcher.newLog(s.Symbol+"-"+string(runner.Futures), err.Error()))
+				} else {
+					m.evaluator.resolve(m.watcher.list())
 				}
 				time.Sleep(time.Second * 15)
 			}
@@ -217,8 +229,25 @@ func (m *MarketStruct) initWatchlist() error {
 	return nil
 }
 
-// initSignals adds all the singals defined as json files in the configs/signals dir.
+// initSignals adds all the singals defined as json files in the configs/signals dir and the
+// active signals saved on the database.
 func (m *MarketStruct) initSignals() error {
+	if err := m.initFileSignals(); err != nil {
+		return err
+	}
+	// the signals on the database override the ones of the same names on the files.
+	if m.evaluator.provider.dbClient.IsInitialized() {
+		if err := m.evaluator.load(); err != nil {
+			return err
+		}
+	}
+	// the watchlist is still empty at start, the universes are resolved again as it's filled.
+	m.evaluator.resolve(m.watcher.list())
+	return nil
+}
+
+// initFileSignals adds all the signals defined as json files in the configs/signals dir.
+func (m *MarketStruct) initFileSignals() error {
 	if len(m.configs.Market.Evaluator.SourcePath) == 0 {
 		return nil
 	}
@@ -235,8 +264,14 @@ func (m *MarketStruct) initSignals() error {
 		if err != nil {
 			return err
 		}
-		tickers := strings.Replace("(?=(?<!(SUSD|BUSD|BVND|PAX|DAI|TUSD|USDC|VAI|BRL|AUD|BIRD|EUR|GBP|BIDR|DOWN|UP|BEAR|BULL))USDT)(?={base_market}$)", "{base_market}", m.configs.Market.Base.Crypto.QuoteCurrency, 1)
-		m.evaluator.add([]string{tickers}, signal)
+		// the signals without a universe are evaluated on all the tickers of the quote currency.
+		if signal.Universe == nil {
+			signal.Universe = &strategy.Universe{
+				QuoteAssets:         []string{m.configs.Market.Base.Crypto.QuoteCurrency},
+				ExcludeBaseSuffixes: excludedBaseSuffixes,
+			}
+		}
+		m.evaluator.add([]string{}, signal)
 	}
 	return nil
 }
@@ -247,7 +282,11 @@ func (m *MarketStruct) Watch(ticker, market string) error {
 	if !ok {
 		return errors.New("unsupported market")
 	}
-	return m.watcher.watch(ticker, m.parseRunnerConfigs(mk), nil)
+	if err := m.watcher.watch(ticker, m.parseRunnerConfigs(mk), nil); err != nil {
+		return err
+	}
+	m.evaluator.resolve(m.watcher.list())
+	return nil
 }
 
 func (m *MarketStruct) Drop(ticker, market string) error {
@@ -255,7 +294,11 @@ func (m *MarketStruct) Drop(ticker, market string) error {
 	if !ok {
 		return errors.New("unsupported market")
 	}
-	return m.watcher.drop(ticker, m.parseRunnerConfigs(mk))
+	if err := m.watcher.drop(ticker, m.parseRunnerConfigs(mk)); err != nil {
+		return err
+	}
+	m.evaluator.resolve(m.watcher.list())
+	return nil
 }
 
 func (m *MarketStruct) Watchlist() []string {
@@ -298,23 +341,142 @@ func (m *MarketStruct) LastIndicators(ticker string) tax.IndicatorsJSON {
 	return out
 }
 
+// Basis returns the last basis from all frames of a symbol watched on both the spot and the
+// perpetual market.
+func (m *MarketStruct) Basis(ticker string) tax.BasesJSON {
+	var out tax.BasesJSON
+	for _, b := range m.watcher.lastBases(ticker) {
+		out = append(out, *tax.Basis2JSON(b))
+	}
+	return out
+}
+
 func (m *MarketStruct) IsSynced(ticker string, duration time.Duration) bool {
 	return m.watcher.isSynced(ticker, duration)
 }
 
 // evaluator endpoints
+// AddSignal adds a new signal to the evaluator and saves it as its next version, a signal of
+// the same name must be updated instead.
 func (m *MarketStruct) AddSignal(patterns []string, s *strategy.Signal) error {
-	return m.evaluator.add(patterns, s)
+	if _, ok := m.evaluator.getPatterns(s.Name); ok {
+		return errors.New("signal " + s.Name + " already exists")
+	}
+	if err := m.evaluator.apply(s, patterns, true); err != nil {
+		return err
+	}
+	m.evaluator.resolve(m.watcher.list())
+	return nil
 }
 
+// DropSignal removes the signal from the evaluator, it's saved as an inactive version.
 func (m *MarketStruct) DropSignal(name string) error {
-	return m.evaluator.drop(name)
+	if _, ok := m.evaluator.getPatterns(name); !ok {
+		return nil
+	}
+	active := false
+	return m.evaluator.update(name, &SignalPatch{Active: &active})
+}
+
+// UpdateSignal applies the patch on the signal and saves it as its next version.
+func (m *MarketStruct) UpdateSignal(name string, patch *SignalPatch) error {
+	if patch == nil {
+		return errors.New("missing signal patch")
+	}
+	if err := m.evaluator.update(name, patch); err != nil {
+		return err
+	}
+	m.evaluator.resolve(m.watcher.list())
+	return nil
+}
+
+// RollbackSignal restores the given version of the signal as its next version.
+func (m *MarketStruct) RollbackSignal(name string, version int64) error {
+	if err := m.evaluator.rollback(name, version); err != nil {
+		return err
+	}
+	m.evaluator.resolve(m.watcher.list())
+	return nil
+}
+
+// SignalVersions returns all the saved versions of the signal.
+func (m *MarketStruct) SignalVersions(name string) ([]*db.SignalRecord, error) {
+	return m.evaluator.records(name)
 }
 
 func (m *MarketStruct) GetSingals(names []string) strategy.Signals {
 	return m.evaluator.getByNames(names)
 }
 
+// Explain explains the signals of the given names on a watched ticker, all the signals
+// applicable to the ticker if there is no name.
+func (m *MarketStruct) Explain(names []string, ticker, market string) ([]*strategy.SignalExplanation, error) {
+	mk, ok := runner.ValidateMarket(market)
+	if !ok {
+		return nil, errors.New("unsupported market")
+	}
+	rc := runner.NewRunnerDefaultConfigs()
+	rc.Market = mk
+	r := m.watcher.get(runner.NewRunner(ticker, rc).GetUniqueName())
+	if r == nil {
+		return nil, errors.New("ticker is not on the watchlist")
+	}
+	signals := m.evaluator.getByNames(names)
+	if len(names) == 0 {
+		signals = m.evaluator.getByRunner(r)
+	}
+	out := []*strategy.SignalExplanation{}
+	for _, s := range signals {
+		if s.IsPair() {
+			s.SetLeg(m.watcher.get(s.Pair.Leg))
+		}
+		// a staged signal is explained from its current stage on the runner.
+		if s.IsStaged() {
+			out = append(out, s.ExplainStage(r, m.evaluator.getStage(s.Name, r.GetUniqueName())))
+			continue
+		}
+		out = append(out, s.Explain(r, nil))
+	}
+	return out, nil
+}
+
+// DryRun explains a signal which isn't added to the evaluator on all the watched tickers
+// matching the patterns, it reports on which tickers the signal would trigger.
+func (m *MarketStruct) DryRun(patterns []string, s *strategy.Signal) ([]*strategy.SignalExplanation, error) {
+	return m.evaluator.dryRun(m.watcher.list(), patterns, s)
+}
+
+// AddShadow runs the signal in shadow on the tickers matching the patterns, replacing its
+// previous shadow if any. It's evaluated on the live stream but it's neither notified nor
+// traded, what it would have done is recorded along with what its live version does.
+func (m *MarketStruct) AddShadow(patterns []string, s *strategy.Signal) error {
+	if err := m.evaluator.addShadow(patterns, s); err != nil {
+		return err
+	}
+	m.evaluator.resolve(m.watcher.list())
+	return nil
+}
+
+// DropShadow stops the shadow of the signal, its records are dropped.
+func (m *MarketStruct) DropShadow(name string) {
+	m.evaluator.dropShadow(name)
+}
+
+// PromoteShadow replaces the live signal with its shadow, saved as its next version.
+func (m *MarketStruct) PromoteShadow(name string) error {
+	if err := m.evaluator.promoteShadow(name); err != nil {
+		return err
+	}
+	m.evaluator.resolve(m.watcher.list())
+	return nil
+}
+
+// CompareShadows returns the shadow signals of the given names side by side with their live
+// versions and what each of them did, all the shadow signals if there is no name.
+func (m *MarketStruct) CompareShadows(names []string) []*ShadowComparison {
+	return m.evaluator.compareShadows(names)
+}
+
 // notifier endpoints
 func (m *MarketStruct) AddChatIDs(cids []int64) {
 	m.notifier.addChatIDs(cids)
@@ -353,6 +515,9 @@ func (m *MarketStruct) Balances(market string) (map[string]string, error) {
 }
 
 func (m *MarketStruct) UpdateConfigs(c *config.Configs) error {
+	if err := m.evaluator.updateConfigs(c); err != nil {
+		return err
+	}
 	m.configs = c
 	return m.trader.updateConfigs(c)
 }
@@ -12,6 +12,7 @@ import (
 
 	db "follow.markets/internal/pkg/database"
 	"follow.markets/internal/pkg/runner"
+	"follow.markets/internal/pkg/strategy"
 	"follow.markets/pkg/config"
 	"follow.markets/pkg/log"
 	"follow.markets/pkg/util"
@@ -183,50 +184,106 @@ func (n *notifier) processEvaluatorRequest(msg *message) {
 	}
 	r, s := msg.request.what.runner, msg.request.what.signal
 	id, mess := r.GetUniqueName()+"-"+s.Name, r.GetUniqueName()+"-"+s.Name
+	// a staged signal notifies its transitions to the stages other than the final ones.
+	if tr, isStage := msg.request.what.dynamic.(*strategy.Transition); isStage {
+		mess += "\nstage: " + tr.From + " -> " + tr.To
+		n.notify(mess, s.OwnerID)
+		return
+	}
+	// an aggregated notification lists all the signals fired on the runner at once.
+	if others, isAggregated := msg.request.what.dynamic.(strategy.Signals); isAggregated {
+		n.notifyAggregated(r, append(strategy.Signals{s}, others...))
+		return
+	}
+	// a pairs signal alerts on both legs, the first one on the signal's side.
+	leg, isPair := msg.request.what.dynamic.(*runner.Runner)
+	if isPair {
+		id = r.GetUniqueName() + "-" + leg.GetUniqueName() + "-" + s.Name
+		mess = r.GetUniqueName() + "/" + leg.GetUniqueName() + "-" + s.Name
+		mess += "\n" + s.OpenTradingSide() + " " + r.GetUniqueName() + ", " + s.CloseTradingSide() + " " + leg.GetUniqueName()
+	}
 	if n.showDesscription {
 		mess += "\n" + s.Description()
 	}
-	name := r.GetName()
-	if r.GetMarketType() == runner.Futures {
-		name = r.GetUniqueName()
-	}
-	url := strings.Replace(tradingViewURL, "{ex}", r.GetExchange(), 1)
-	url = strings.Replace(url, "{sb}", name, 1)
-	url = strings.Replace(url, "{intv}", strconv.Itoa(int(s.TimePeriod/time.Minute)), 1)
+	url := tradingViewLink(r, s.TimePeriod)
 	mess += "\n" + url
-	notis := []*db.Notification{
-		&db.Notification{
-			Ticker:    r.GetName(),
-			Market:    string(r.GetMarketType()),
-			Broker:    "Binance",
-			Signal:    s.Name,
-			CreatedAt: time.Now(),
-			URL:       url,
-		},
+	notis := []*db.Notification{newNotification(r, s, url)}
+	if isPair {
+		notis = append(notis, newNotification(leg, s, url))
 	}
-	if s.IsOnetime() {
+	if n.shouldNotify(id, s) {
 		n.notify(mess, s.OwnerID)
 		go n.provider.dbClient.InsertNotifications(notis)
-		return
 	}
-	if val, ok := n.notis.Load(id); !ok {
-		n.notify(mess, s.OwnerID)
-		go n.provider.dbClient.InsertNotifications(notis)
-		n.notis.Store(id,
-			notification{
-				id:       id,
-				lastSent: time.Now().Add(-time.Minute),
-			})
-	} else {
-		if s.ShouldSend(val.(notification).lastSent) {
-			n.notify(mess, s.OwnerID)
-			go n.provider.dbClient.InsertNotifications(notis)
-			n.notis.Store(id,
-				notification{
-					id:       id,
-					lastSent: time.Now().Add(-time.Minute),
-				})
+}
+
+// notifyAggregated sends a single notification of the signals of an owner fired on the runner at
+// once, each signal is still subject to its own notify type.
+func (n *notifier) notifyAggregated(r *runner.Runner, ss strategy.Signals) {
+	mess, notis := r.GetUniqueName()+": "+strconv.Itoa(len(ss))+" signals", []*db.Notification{}
+	period := time.Duration(0)
+	for _, s := range ss {
+		if period == 0 || (s.TimePeriod > 0 && s.TimePeriod < period) {
+			period = s.TimePeriod
+		}
+	}
+	url := tradingViewLink(r, period)
+	for _, s := range ss {
+		if !n.shouldNotify(r.GetUniqueName()+"-"+s.Name, s) {
+			continue
 		}
+		mess += "\n- " + s.Name
+		if len(s.SignalType) > 0 {
+			mess += " (" + s.SignalType + ")"
+		}
+		if n.showDesscription {
+			mess += ": " + s.Description()
+		}
+		notis = append(notis, newNotification(r, s, url))
+	}
+	if len(notis) == 0 {
+		return
+	}
+	n.notify(mess+"\n"+url, ss[0].OwnerID)
+	go n.provider.dbClient.InsertNotifications(notis)
+}
+
+// shouldNotify returns true if the signal is to be notified under the id, given when it was
+// last notified, which is then updated.
+func (n *notifier) shouldNotify(id string, s *strategy.Signal) bool {
+	if s.IsOnetime() {
+		return true
+	}
+	if val, ok := n.notis.Load(id); ok && !s.ShouldSend(val.(notification).lastSent) {
+		return false
+	}
+	n.notis.Store(id,
+		notification{
+			id:       id,
+			lastSent: time.Now().Add(-time.Minute),
+		})
+	return true
+}
+
+// tradingViewLink returns the chart of the runner on the period.
+func tradingViewLink(r *runner.Runner, period time.Duration) string {
+	name := r.GetName()
+	if r.GetMarketType() == runner.Futures {
+		name = r.GetUniqueName()
+	}
+	url := strings.Replace(tradingViewURL, "{ex}", r.GetExchange(), 1)
+	url = strings.Replace(url, "{sb}", name, 1)
+	return strings.Replace(url, "{intv}", strconv.Itoa(int(period/time.Minute)), 1)
+}
+
+func newNotification(r *runner.Runner, s *strategy.Signal, url string) *db.Notification {
+	return &db.Notification{
+		Ticker:    r.GetName(),
+		Market:    string(r.GetMarketType()),
+		Broker:    "Binance",
+		Signal:    s.Name,
+		CreatedAt: time.Now(),
+		URL:       url,
 	}
 }
 
@@ -24,6 +24,7 @@ import (
 const (
 	timeFramePattern = `\d+(s|m|h)`
 	klineMaxSize     = 500
+	orderBookMaxSize = 1000
 )
 
 type provider struct {
@@ -122,6 +123,81 @@ func (p *provider) fetchBinanceFuturesKlinesV3(ticker string, d time.Duration, o
 	return candles, nil
 }
 
+// fetchBinanceSpotOrderBook fetches the depth snapshot and syncs it to the given local order book.
+func (p *provider) fetchBinanceSpotOrderBook(book *tax.OrderBook) error {
+	depth, err := p.binSpot.NewDepthService().Symbol(book.Symbol()).Limit(orderBookMaxSize).Do(context.Background())
+	if err != nil {
+		return err
+	}
+	return book.Snapshot(depth.LastUpdateID, depth.Bids, depth.Asks)
+}
+
+// fetchBinanceFuturesOrderBook fetches the depth snapshot and syncs it to the given local order book.
+func (p *provider) fetchBinanceFuturesOrderBook(book *tax.OrderBook) error {
+	depth, err := p.binFutu.NewDepthService().Symbol(book.Symbol()).Limit(orderBookMaxSize).Do(context.Background())
+	if err != nil {
+		return err
+	}
+	return book.Snapshot(depth.LastUpdateID, depth.Bids, depth.Asks)
+}
+
+// fetchBinanceFuturesFundingRates fetches the realized funding rate history of the given ticker.
+func (p *provider) fetchBinanceFuturesFundingRates(ticker string, limit int) ([]*tax.FundingRate, error) {
+	rates, err := p.binFutu.NewFundingRateService().Symbol(ticker).Limit(limit).Do(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	var out []*tax.FundingRate
+	for _, r := range rates {
+		out = append(out, tax.ConvertBinanceFuturesFundingRate(r))
+	}
+	return out, nil
+}
+
+// fetchBinanceFuturesOpenInterest fetches the current open interest of the given ticker.
+func (p *provider) fetchBinanceFuturesOpenInterest(ticker string) (*tax.OpenInterest, error) {
+	oi, err := p.binFutu.NewGetOpenInterestService().Symbol(ticker).Do(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return tax.ConvertBinanceFuturesOpenInterest(oi), nil
+}
+
+// binanceOpenInterestPeriod returns the largest open interest history period, available on Binance,
+// not longer than the given duration. Binance only keeps the history from the 5-minute period.
+func binanceOpenInterestPeriod(d time.Duration) string {
+	switch {
+	case d < 15*time.Minute:
+		return "5m"
+	case d < 30*time.Minute:
+		return "15m"
+	case d < time.Hour:
+		return "30m"
+	case d < 2*time.Hour:
+		return "1h"
+	case d < 4*time.Hour:
+		return "2h"
+	case d < 24*time.Hour:
+		return "4h"
+	default:
+		return "1d"
+	}
+}
+
+// fetchBinanceFuturesOpenInterestHistory fetches the open interest history of the given ticker on
+// the given period.
+func (p *provider) fetchBinanceFuturesOpenInterestHistory(ticker string, period string, limit int) ([]*tax.OpenInterest, error) {
+	stats, err := p.binFutu.NewOpenInterestStatisticsService().Symbol(ticker).Period(period).Limit(limit).Do(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	var out []*tax.OpenInterest
+	for _, s := range stats {
+		out = append(out, tax.ConvertBinanceFuturesOpenInterestStatistic(s))
+	}
+	return out, nil
+}
+
 func (p *provider) fetchCoinFundamentals(base string, limit int) (map[string]runner.Fundamental, error) {
 	out := make(map[string]runner.Fundamental)
 	listings, err := p.coinCap.Cryptocurrency.LatestListings(&cc.ListingOptions{Limit: limit})
@@ -129,10 +205,12 @@ func (p *provider) fetchCoinFundamentals(base string, limit int) (map[string]run
 		return out, err
 	}
 	for _, l := range listings {
+		added, _ := time.Parse(time.RFC3339, l.DateAdded)
 		out[l.Symbol+base] = runner.Fundamental{
 			MaxSupply:         l.MaxSupply,
 			TotalSupply:       l.TotalSupply,
 			CirculatingSupply: l.CirculatingSupply,
+			DateAdded:         added,
 		}
 	}
 	return out, nil
@@ -0,0 +1,172 @@
+package market
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	db "follow.markets/internal/pkg/database"
+	"follow.markets/internal/pkg/strategy"
+)
+
+// SignalPatch holds the changes of a signal on the registry, the missing fields are kept.
+type SignalPatch struct {
+	Patterns []string        `json:"patterns"`
+	Active   *bool           `json:"active"`
+	Signal   json.RawMessage `json:"signal"`
+}
+
+// records returns all the versions of the signal of the given name from the database, sorted by version.
+func (e *evaluator) records(name string) ([]*db.SignalRecord, error) {
+	if e.provider == nil || e.provider.dbClient == nil {
+		return nil, errors.New("missing database client")
+	}
+	return e.provider.dbClient.GetSignalRecords(&name)
+}
+
+// latest returns the latest version of the signal of the given name, nil if it has never been saved.
+func (e *evaluator) latest(name string) (*db.SignalRecord, error) {
+	srs, err := e.records(name)
+	if err != nil {
+		return nil, err
+	}
+	if latest := db.LatestSignalRecords(srs); len(latest) > 0 {
+		return latest[0], nil
+	}
+	return nil, nil
+}
+
+// save saves the signal as its next version, it's called under the registry mutex.
+func (e *evaluator) save(s *strategy.Signal, patterns []string, active bool) error {
+	latest, err := e.latest(s.Name)
+	if err != nil {
+		return err
+	}
+	version := int64(1)
+	if latest != nil {
+		version = latest.Version + 1
+	}
+	sr, err := db.NewSignalRecord(s, patterns, version, active)
+	if err != nil {
+		return err
+	}
+	return e.provider.dbClient.InsertSignalRecord(sr)
+}
+
+// apply adds the signal to the evaluator if it's active, drops it otherwise, and saves it as
+// a new version. The signal stays on the evaluator if the database isn't available.
+func (e *evaluator) apply(s *strategy.Signal, patterns []string, active bool) error {
+	e.registryMutex.Lock()
+	defer e.registryMutex.Unlock()
+	var err error
+	if active {
+		err = e.replace(patterns, s)
+	} else {
+		err = e.drop(s.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if err := e.save(s, patterns, active); err != nil {
+		e.logger.Error.Println(e.newLog(s.Name, "failed to save the signal with err: "+err.Error()))
+	}
+	return nil
+}
+
+// update applies the patch on the signal of the given name. The current signal is the one on the
+// evaluator, or the latest version on the database for the inactive ones.
+func (e *evaluator) update(name string, patch *SignalPatch) error {
+	var current *strategy.Signal
+	patterns, active := e.getPatterns(name)
+	if active {
+		if ss := e.getByNames([]string{name}); len(ss) > 0 {
+			current = ss[0]
+		}
+	} else {
+		latest, err := e.latest(name)
+		if err != nil {
+			return err
+		}
+		if latest == nil {
+			return errors.New("unknown signal " + name)
+		}
+		if current, err = latest.GetSignal(); err != nil {
+			return err
+		}
+		patterns = latest.Patterns
+	}
+	if len(patch.Signal) > 0 {
+		s, err := strategy.NewSignalFromBytes(patch.Signal)
+		if err != nil {
+			return err
+		}
+		if s.Name != name {
+			return errors.New("the signal name must not change")
+		}
+		current = s
+	}
+	if len(patch.Patterns) > 0 {
+		patterns = patch.Patterns
+	}
+	if patch.Active != nil {
+		active = *patch.Active
+	}
+	if current == nil {
+		return errors.New("unknown signal " + name)
+	}
+	return e.apply(current, patterns, active)
+}
+
+// expire drops the onetime signal of the given name once it has fired, it's saved as an inactive
+// version so it isn't loaded again.
+func (e *evaluator) expire(name string) error {
+	if _, ok := e.getPatterns(name); !ok {
+		return nil
+	}
+	inactive := false
+	return e.update(name, &SignalPatch{Active: &inactive})
+}
+
+// rollback restores the given version of the signal, it's saved as a new version.
+func (e *evaluator) rollback(name string, version int64) error {
+	srs, err := e.records(name)
+	if err != nil {
+		return err
+	}
+	for _, sr := range srs {
+		if sr.Version != version {
+			continue
+		}
+		s, err := sr.GetSignal()
+		if err != nil {
+			return err
+		}
+		return e.apply(s, sr.Patterns, sr.Active)
+	}
+	return errors.New("unknown version " + strconv.FormatInt(version, 10) + " of signal " + name)
+}
+
+// load adds the latest version of all the active signals on the database to the evaluator.
+func (e *evaluator) load() error {
+	if e.provider == nil || e.provider.dbClient == nil {
+		return errors.New("missing database client")
+	}
+	srs, err := e.provider.dbClient.GetSignalRecords(nil)
+	if err != nil {
+		return err
+	}
+	for _, sr := range db.LatestSignalRecords(srs) {
+		if !sr.Active {
+			continue
+		}
+		s, err := sr.GetSignal()
+		if err != nil {
+			e.logger.Error.Println(e.newLog(sr.Name, "failed to load the signal with err: "+err.Error()))
+			continue
+		}
+		if err := e.replace(sr.Patterns, s); err != nil {
+			e.logger.Error.Println(e.newLog(sr.Name, "failed to load the signal with err: "+err.Error()))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package market
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	db "follow.markets/internal/pkg/database"
+	"follow.markets/internal/pkg/runner"
+	"follow.markets/internal/pkg/strategy"
+)
+
+// testRegistryClient keeps the signal records in memory, the other methods of the database
+// client aren't used by the registry.
+type testRegistryClient struct {
+	db.Client
+	sync.Mutex
+	records []*db.SignalRecord
+}
+
+func (c *testRegistryClient) InsertSignalRecord(sr *db.SignalRecord) error {
+	c.Lock()
+	defer c.Unlock()
+	c.records = append(c.records, sr)
+	return nil
+}
+
+func (c *testRegistryClient) GetSignalRecords(name *string) ([]*db.SignalRecord, error) {
+	c.Lock()
+	defer c.Unlock()
+	var out []*db.SignalRecord
+	for _, sr := range c.records {
+		if name == nil || sr.Name == *name {
+			out = append(out, sr)
+		}
+	}
+	return out, nil
+}
+
+func Test_Registry(t *testing.T) {
+	client := &testRegistryClient{}
+	e := newTestEvaluator()
+	e.provider = &provider{dbClient: client}
+
+	s, err := strategy.NewSignalFromBytes([]byte(`{"name": "registry", "expression": "CLOSE > 1"}`))
+	assert.EqualValues(t, nil, err)
+	assert.EqualValues(t, nil, e.apply(s, []string{"BTC"}, true))
+	latest, err := e.latest("registry")
+	assert.EqualValues(t, nil, err)
+	assert.EqualValues(t, 1, latest.Version)
+
+	// an update is saved as the next version and replaces the signal on the evaluator.
+	assert.EqualValues(t, nil, e.update("registry", &SignalPatch{Signal: []byte(`{"name": "registry", "expression": "CLOSE > 2"}`)}))
+	assert.EqualValues(t, "CLOSE > 2", e.getByNames([]string{"registry"})[0].Expression)
+	assert.EqualValues(t, "the signal name must not change", e.update("registry", &SignalPatch{Signal: []byte(`{"name": "other", "expression": "CLOSE > 2"}`)}).Error())
+
+	// the inactive signals are dropped from the evaluator, and updated from their latest version.
+	inactive, active := false, true
+	assert.EqualValues(t, nil, e.update("registry", &SignalPatch{Active: &inactive}))
+	_, ok := e.getPatterns("registry")
+	assert.EqualValues(t, false, ok)
+	assert.EqualValues(t, nil, e.update("registry", &SignalPatch{Active: &active, Patterns: []string{"ETH"}}))
+	patterns, ok := e.getPatterns("registry")
+	assert.EqualValues(t, true, ok)
+	assert.EqualValues(t, []string{"ETH"}, patterns)
+	assert.EqualValues(t, "CLOSE > 2", e.getByNames([]string{"registry"})[0].Expression)
+	assert.EqualValues(t, "unknown signal missing", e.update("missing", &SignalPatch{Active: &active}).Error())
+
+	// a rollback restores the version as a new one.
+	assert.EqualValues(t, nil, e.rollback("registry", 1))
+	assert.EqualValues(t, "CLOSE > 1", e.getByNames([]string{"registry"})[0].Expression)
+	patterns, _ = e.getPatterns("registry")
+	assert.EqualValues(t, []string{"BTC"}, patterns)
+	latest, _ = e.latest("registry")
+	assert.EqualValues(t, 5, latest.Version)
+	assert.EqualValues(t, "unknown version 9 of signal registry", e.rollback("registry", 9).Error())
+
+	// the versions of concurrent changes are in order.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.EqualValues(t, nil, e.update("registry", &SignalPatch{Active: &active}))
+		}()
+	}
+	wg.Wait()
+	srs, _ := e.records("registry")
+	assert.EqualValues(t, 15, len(srs))
+	for i, sr := range srs {
+		assert.EqualValues(t, i+1, sr.Version)
+	}
+}
+
+func Test_Registry_Onetime(t *testing.T) {
+	client := &testRegistryClient{}
+	e := newTestEvaluator()
+	e.provider = &provider{dbClient: client}
+	go func() {
+		for range e.communicator.evaluator2Trader {
+		}
+	}()
+	go func() {
+		for range e.communicator.evaluator2Notifier {
+		}
+	}()
+
+	s, err := strategy.NewSignalFromBytes([]byte(`{"name": "onetime", "expression": "CLOSE > 1", "track_type": "ONETIME"}`))
+	assert.EqualValues(t, nil, err)
+	assert.EqualValues(t, nil, e.apply(s, []string{"BTC"}, true))
+
+	// the fired onetime signal is saved as inactive, it isn't loaded again.
+	e.dispatch(runner.NewRunner("BTCUSDT", nil), []*firing{{signal: s}})
+	_, ok := e.getPatterns("onetime")
+	assert.EqualValues(t, false, ok)
+	latest := db.LatestSignalRecords(client.records)
+	assert.EqualValues(t, 1, len(latest))
+	assert.EqualValues(t, 2, latest[0].Version)
+	assert.EqualValues(t, false, latest[0].Active)
+	assert.EqualValues(t, []string{"BTC"}, latest[0].Patterns)
+	assert.EqualValues(t, nil, e.load())
+	_, ok = e.getPatterns("onetime")
+	assert.EqualValues(t, false, ok)
+}
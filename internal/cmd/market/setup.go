@@ -21,6 +21,14 @@ type setup struct {
 	signal   *strategy.Signal
 	channels *streamingChannels
 
+	// the position is set once the order is filled if the signal has its own exits.
+	position   *strategy.Position
+	exitReason string
+
+	// the setup of the first leg if the setup is the second leg of a pairs signal, the second
+	// leg is closed with the first one.
+	first *setup
+
 	orderID        int64
 	orderTime      int64
 	orderSide      string
@@ -84,6 +92,32 @@ func newSetup(r *runner.Runner, s *strategy.Signal, leverage big.Decimal, o inte
 	}
 }
 
+// the exit reason of the first leg of a pairs signal whose second leg couldn't be placed.
+const unhedgedExit = "unhedged"
+
+// isUnhedged returns true if the setup is the first leg of a pairs signal being unwound.
+func (st *setup) isUnhedged() bool {
+	return strings.HasPrefix(st.exitReason, unhedgedExit)
+}
+
+// closingSide returns the side of the order closing the position of the setup.
+func (st *setup) closingSide() string {
+	if strings.ToUpper(st.orderSide) == "BUY" {
+		return "SELL"
+	}
+	return "BUY"
+}
+
+// isDone returns true if the position of the setup is closed or its order is never filled.
+func (st *setup) isDone() bool {
+	switch st.orderStatus {
+	case "CANCELED", "REJECTED", "EXPIRED":
+		return true
+	default:
+		return st.isClose
+	}
+}
+
 // binSpotUpdateTrade update the setupt with new trade activities,
 // it adds filled quantity, recomputes average filled price
 // and logs trades.
@@ -191,6 +225,7 @@ order side:     %s,
 order quantity: %s,
 orer price:     %s,
 order status:   %s,
+exit reason:    %s,
 |-------------------------------|
 |           RESULT              | 
 ---------------------------------
@@ -213,6 +248,7 @@ n. of trades:       %d,
 		st.orderQtity,
 		st.orderPrice,
 		st.orderStatus,
+		st.exitReason,
 		st.pnl.Mul(big.NewDecimal(100.0)).FormattedString(2)+"%",
 		st.pnl.Mul(st.usedLeverage).Mul(st.avgFilledPrice.Mul(st.accFilledQtity)).FormattedString(2),
 		st.avgFilledPrice.FormattedString(8),
@@ -87,3 +87,22 @@ func Test_Setup_BinFutuUpdate(t *testing.T) {
 	assert.EqualValues(t, "9.20", st.avgFilledPrice.FormattedString(2))
 	assert.EqualValues(t, "20.00", st.accFilledQtity.FormattedString(2))
 }
+
+func Test_Setup_Unhedged(t *testing.T) {
+	r := runner.NewRunner("BTCUSDT", runner.NewRunnerDefaultConfigs())
+	s, err := strategy.NewSignalFromBytes([]byte(`{"name": "pair", "expression": "CLOSE > 1", "pair": {"leg": "ETHUSDT"}}`))
+	assert.EqualValues(t, nil, err)
+	st := newSetup(r, s, big.ONE, &bn.CreateOrderResponse{Side: "BUY", Price: "10", OrigQuantity: "20"})
+	st.avgFilledPrice = big.NewDecimal(10)
+	st.accFilledQtity = big.NewDecimal(20)
+
+	tr := &trader{lossTolerance: big.NewDecimal(0.01), profitMargin: big.NewDecimal(0.02)}
+	ok, _, _ := tr.shouldClose(st, big.NewDecimal(10))
+	assert.EqualValues(t, false, ok)
+
+	// the first leg of a pair without its second leg is closed at once.
+	st.exitReason = unhedgedExit + ": cannot find a price to place the second leg"
+	assert.EqualValues(t, true, st.isUnhedged())
+	ok, _, _ = tr.shouldClose(st, big.NewDecimal(10))
+	assert.EqualValues(t, true, ok)
+}
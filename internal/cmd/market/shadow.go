@@ -0,0 +1,175 @@
+package market
+
+import (
+	"errors"
+	"time"
+
+	"follow.markets/internal/pkg/runner"
+	"follow.markets/internal/pkg/strategy"
+)
+
+// maxShadowRecords is the maximum number of records kept for each side of a shadowed signal.
+const maxShadowRecords = 1000
+
+// ShadowRecord is a firing of a signal on a runner, either of the shadow version or of the live
+// version of a shadowed signal, with the close of the runner when it fired.
+type ShadowRecord struct {
+	Signal string    `json:"signal"`
+	Runner string    `json:"runner"`
+	Shadow bool      `json:"shadow"`
+	Price  float64   `json:"price"`
+	Time   time.Time `json:"time"`
+}
+
+// ShadowComparison holds the live and the shadow versions of a signal side by side, with what
+// each of them did since the shadow was added.
+type ShadowComparison struct {
+	Name           string           `json:"name"`
+	Since          time.Time        `json:"since"`
+	Live           *strategy.Signal `json:"live,omitempty"`
+	LivePatterns   []string         `json:"live_patterns,omitempty"`
+	LiveRecords    []*ShadowRecord  `json:"live_records"`
+	Shadow         *strategy.Signal `json:"shadow"`
+	ShadowPatterns []string         `json:"shadow_patterns"`
+	ShadowRecords  []*ShadowRecord  `json:"shadow_records"`
+}
+
+// shadowState holds the time the shadow version of a signal was added and the records of its
+// firings and of the live version's.
+type shadowState struct {
+	since   time.Time
+	live    []*ShadowRecord
+	shadows []*ShadowRecord
+}
+
+// shadowKey is the key of the state of the shadow version of a signal on a runner, kept apart
+// from the state of its live version.
+func shadowKey(s *strategy.Signal, r *runner.Runner) string {
+	return shadowStageName(s.Name) + "-" + r.GetUniqueName()
+}
+
+// shadowStageName is the name the stages of the shadow signal of the given name are kept by.
+func shadowStageName(name string) string {
+	return "shadow:" + name
+}
+
+// addShadow adds, or replaces, the shadow version of the signal of the same name. A shadow
+// signal is evaluated on the live stream like any other signal but it's neither notified nor
+// traded, its firings are recorded instead. The live version doesn't have to exist.
+func (e *evaluator) addShadow(patterns []string, s *strategy.Signal) error {
+	reges, err := compilePatterns(patterns)
+	if err != nil {
+		return err
+	}
+	e.Lock()
+	defer e.Unlock()
+	if err := s.CheckReferences(e); err != nil {
+		return err
+	}
+	s.Bind(e)
+	e.dropShadowState(s.Name)
+	e.shadows.Store(s.Name, emember{
+		name:     s.Name,
+		regex:    reges,
+		signals:  strategy.Signals{s},
+		patterns: patterns,
+		universe: s.Universe,
+	})
+	e.shadowMutex.Lock()
+	defer e.shadowMutex.Unlock()
+	e.shadowStates[s.Name] = &shadowState{since: time.Now()}
+	return nil
+}
+
+// dropShadow removes the shadow version of the signal of the given name, and its records.
+func (e *evaluator) dropShadow(name string) {
+	e.Lock()
+	if _, ok := e.shadows.Load(name); !ok {
+		e.Unlock()
+		return
+	}
+	e.shadows.Delete(name)
+	e.dropShadowState(name)
+	e.Unlock()
+	e.pruneStreams()
+}
+
+// dropShadowState drops the records and the stages of the shadow signal of the given name.
+func (e *evaluator) dropShadowState(name string) {
+	e.shadowMutex.Lock()
+	delete(e.shadowStates, name)
+	e.shadowMutex.Unlock()
+	e.resetStages(shadowStageName(name))
+}
+
+// promoteShadow replaces the live version of the signal of the given name with its shadow
+// version, saved as its next version, and drops the shadow.
+func (e *evaluator) promoteShadow(name string) error {
+	val, ok := e.shadows.Load(name)
+	if !ok {
+		return errors.New("unknown shadow signal " + name)
+	}
+	m := val.(emember)
+	if err := e.apply(m.signals[:1].Copy()[0], m.patterns, true); err != nil {
+		return err
+	}
+	e.dropShadow(name)
+	return nil
+}
+
+// getShadowsByRunner returns the shadow signals applicable to the given runner.
+func (e *evaluator) getShadowsByRunner(r *runner.Runner) strategy.Signals {
+	return getByRunner(e.shadows, r)
+}
+
+// record records the firing of the signal on the runner, for the shadowed signals only.
+func (e *evaluator) record(r *runner.Runner, s *strategy.Signal, shadow bool) {
+	rec := &ShadowRecord{Signal: s.Name, Runner: r.GetUniqueName(), Shadow: shadow, Time: time.Now()}
+	if c := r.LastCandle(r.SmallestFrame()); c != nil {
+		rec.Price = c.ClosePrice.Float()
+	}
+	e.shadowMutex.Lock()
+	defer e.shadowMutex.Unlock()
+	st, ok := e.shadowStates[s.Name]
+	if !ok {
+		return
+	}
+	if shadow {
+		st.shadows = appendShadowRecord(st.shadows, rec)
+	} else {
+		st.live = appendShadowRecord(st.live, rec)
+	}
+}
+
+func appendShadowRecord(records []*ShadowRecord, rec *ShadowRecord) []*ShadowRecord {
+	records = append(records, rec)
+	if len(records) > maxShadowRecords {
+		records = records[len(records)-maxShadowRecords:]
+	}
+	return records
+}
+
+// compareShadows returns the comparisons of the shadow signals of the given names, of all the
+// shadow signals if there is no name.
+func (e *evaluator) compareShadows(names []string) []*ShadowComparison {
+	out := []*ShadowComparison{}
+	for _, s := range getByNames(e.shadows, names) {
+		c := &ShadowComparison{Name: s.Name, Shadow: s, LiveRecords: []*ShadowRecord{}, ShadowRecords: []*ShadowRecord{}}
+		if val, ok := e.shadows.Load(s.Name); ok {
+			c.ShadowPatterns = val.(emember).patterns
+		}
+		if live, ok := e.GetSignal(s.Name); ok {
+			c.Live = live
+			c.LivePatterns, _ = e.getPatterns(s.Name)
+		}
+		e.shadowMutex.Lock()
+		if st, ok := e.shadowStates[s.Name]; ok {
+			c.Since = st.since
+			c.LiveRecords = append(c.LiveRecords, st.live...)
+			c.ShadowRecords = append(c.ShadowRecords, st.shadows...)
+		}
+		e.shadowMutex.Unlock()
+		out = append(out, c)
+	}
+	return out
+}
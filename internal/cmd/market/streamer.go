@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	bn "github.com/adshao/go-binance/v2"
@@ -17,6 +18,10 @@ import (
 
 const (
 	waitToInitChannel = 3
+	maxSnapshotTries  = 5
+	maxSnapshotWait   = 30 * time.Second
+
+	openInterestPollingInterval = 30 * time.Second
 )
 
 type streamer struct {
@@ -126,12 +131,11 @@ func (s *streamer) processRequest(msg *message, a Agent) {
 		s.unsubscribe(r.GetUniqueName(agent))
 		cs.close()
 	} else {
-		bStopC, tStopC, dStopC := s.subscribe(r, cs)
 		s.controllers.Store(r.GetUniqueName(agent),
 			controller{
 				name:  r.GetUniqueName(agent),
 				from:  a,
-				stops: []chan struct{}{bStopC, tStopC, dStopC},
+				stops: s.subscribe(r, cs),
 			},
 		)
 	}
@@ -141,8 +145,9 @@ func (s *streamer) processRequest(msg *message, a Agent) {
 	}
 }
 
-// subscribe handles subscribing to the market data for a runner.
-func (s *streamer) subscribe(r *runner.Runner, cs *streamingChannels) (chan struct{}, chan struct{}, chan struct{}) {
+// subscribe handles subscribing to the market data for a runner. It returns the stop
+// channels of all the subscribed streams.
+func (s *streamer) subscribe(r *runner.Runner, cs *streamingChannels) []chan struct{} {
 	s.Lock()
 	defer s.Unlock()
 	// cash handlers
@@ -152,10 +157,13 @@ func (s *streamer) subscribe(r *runner.Runner, cs *streamingChannels) (chan stru
 		}
 	}
 	klineHandler := func(event *bn.WsKlineEvent) {
-		if !event.Kline.IsFinal {
+		if event.Kline.TradeNum == 0 || big.NewFromString(event.Kline.Volume).EQ(big.ZERO) {
 			return
 		}
-		if event.Kline.TradeNum == 0 || big.NewFromString(event.Kline.Volume).EQ(big.ZERO) {
+		if !event.Kline.IsFinal {
+			if cs.partial != nil && r.IsIntrabar() {
+				cs.partial <- tax.ConvertBinanceStreamingKline(event, nil)
+			}
 			return
 		}
 		if cs.bar != nil {
@@ -174,10 +182,13 @@ func (s *streamer) subscribe(r *runner.Runner, cs *streamingChannels) (chan stru
 		}
 	}
 	futuKlineHandler := func(event *bnf.WsKlineEvent) {
-		if !event.Kline.IsFinal {
+		if event.Kline.TradeNum == 0 || big.NewFromString(event.Kline.Volume).EQ(big.ZERO) {
 			return
 		}
-		if event.Kline.TradeNum == 0 || big.NewFromString(event.Kline.Volume).EQ(big.ZERO) {
+		if !event.Kline.IsFinal {
+			if cs.partial != nil && r.IsIntrabar() {
+				cs.partial <- tax.ConvertBinanceFuturesStreamingKline(event, nil)
+			}
 			return
 		}
 		if cs.bar != nil {
@@ -189,10 +200,33 @@ func (s *streamer) subscribe(r *runner.Runner, cs *streamingChannels) (chan stru
 			cs.depth <- event
 		}
 	}
-	var bStopC, tStopC, dStopC chan struct{}
+	futuMarkPriceHandler := func(event *bnf.WsMarkPriceEvent) {
+		if cs.derivative != nil {
+			cs.derivative <- tax.ConvertBinanceFuturesMarkPrice(event)
+		}
+	}
+	futuLiquidationHandler := func(event *bnf.WsLiquidationOrderEvent) {
+		if cs.derivative != nil {
+			cs.derivative <- tax.ConvertBinanceFuturesLiquidation(event)
+		}
+	}
+	futuOpenInterestHandler := func(oi *tax.OpenInterest) {
+		if cs.derivative != nil {
+			cs.derivative <- oi
+		}
+	}
+	// order book handlers
+	book := s.newBookSync(r)
+	bookHandler := func(event *bn.WsDepthEvent) {
+		s.syncOrderBook(book, tax.NewDepthUpdateFromBinanceSpot(event), cs.book)
+	}
+	futuBookHandler := func(event *bnf.WsDepthEvent) {
+		s.syncOrderBook(book, tax.NewDepthUpdateFromBinanceFutures(event), cs.book)
+	}
+	var bStopC, tStopC, dStopC, obStopC, mpStopC, lqStopC, oiStopC chan struct{}
 	switch r.GetMarketType() {
 	case runner.Cash:
-		if cs.bar != nil {
+		if cs.bar != nil || cs.partial != nil {
 			bStopC = s.streamingBinanceKline(r.GetName(), bStopC, klineHandler)
 		}
 		if cs.trade != nil {
@@ -201,8 +235,11 @@ func (s *streamer) subscribe(r *runner.Runner, cs *streamingChannels) (chan stru
 		if cs.depth != nil {
 			dStopC = s.streamingBinancePartitialDepth(r.GetName(), dStopC, depthHandler)
 		}
+		if cs.book != nil {
+			obStopC = s.streamingBinanceDiffDepth(r.GetName(), obStopC, bookHandler)
+		}
 	case runner.Futures:
-		if cs.bar != nil {
+		if cs.bar != nil || cs.partial != nil {
 			bStopC = s.streamingBinanceFuturesKline(r.GetName(), bStopC, futuKlineHandler)
 		}
 		if cs.trade != nil {
@@ -211,8 +248,88 @@ func (s *streamer) subscribe(r *runner.Runner, cs *streamingChannels) (chan stru
 		if cs.depth != nil {
 			dStopC = s.streamingBinanceFuturesPartitialDepth(r.GetName(), dStopC, futuDepthHandler)
 		}
+		if cs.book != nil {
+			obStopC = s.streamingBinanceFuturesDiffDepth(r.GetName(), obStopC, futuBookHandler)
+		}
+		if cs.derivative != nil {
+			mpStopC = s.streamingBinanceFuturesMarkPrice(r.GetName(), mpStopC, futuMarkPriceHandler)
+			lqStopC = s.streamingBinanceFuturesLiquidation(r.GetName(), lqStopC, futuLiquidationHandler)
+			oiStopC = s.pollingBinanceFuturesOpenInterest(r.GetName(), futuOpenInterestHandler)
+		}
+	}
+	if cs.book != nil {
+		s.resyncOrderBook(book)
+	}
+	return []chan struct{}{bStopC, tStopC, dStopC, obStopC, mpStopC, lqStopC, oiStopC}
+}
+
+// bookSync is the local order book of a runner and the snapshot fetching it, there is at most
+// one snapshot being fetched for a book at a time.
+type bookSync struct {
+	runner       *runner.Runner
+	book         *tax.OrderBook
+	fetch        func(*tax.OrderBook) error
+	snapshotting int32
+}
+
+// newBookSync returns the order book of the runner, synced with the snapshots of the provider.
+func (s *streamer) newBookSync(r *runner.Runner) *bookSync {
+	bs := &bookSync{runner: r, book: tax.NewOrderBook(r.GetName(), r.GetMarketType() == runner.Futures)}
+	switch r.GetMarketType() {
+	case runner.Cash:
+		bs.fetch = s.provider.fetchBinanceSpotOrderBook
+	case runner.Futures:
+		bs.fetch = s.provider.fetchBinanceFuturesOrderBook
+	}
+	return bs
+}
+
+// syncOrderBook applies a diff-depth update to the local order book of a runner and broadcasts
+// the book once it is synced, a slow consumer misses the book rather than stalling the stream.
+// It requests a new snapshot when a gap in the sequence is detected, or when the book is still
+// waiting for one after the previous snapshot gave up.
+func (s *streamer) syncOrderBook(bs *bookSync, u *tax.DepthUpdate, c chan *tax.OrderBook) {
+	switch err := bs.book.Update(u); err {
+	case nil:
+		if c == nil {
+			return
+		}
+		select {
+		case c <- bs.book:
+		default:
+		}
+	case tax.ErrOrderBookGap:
+		s.logger.Warning.Println(s.newLog(bs.runner.GetUniqueName(), err.Error()))
+		s.resyncOrderBook(bs)
+	case tax.ErrOrderBookNotSynced:
+		s.resyncOrderBook(bs)
+	}
+}
+
+// resyncOrderBook fetches a new snapshot of the book, unless one is already being fetched.
+func (s *streamer) resyncOrderBook(bs *bookSync) {
+	if bs.fetch == nil || !atomic.CompareAndSwapInt32(&bs.snapshotting, 0, 1) {
+		return
+	}
+	go s.snapshotOrderBook(bs)
+}
+
+// snapshotOrderBook fetches the order book snapshot from the provider and syncs it with the
+// buffered diff-depth updates. It retries with a backoff until the book is synced, or gives up
+// after a few tries until the next update of the book asks for a snapshot again.
+func (s *streamer) snapshotOrderBook(bs *bookSync) {
+	defer atomic.StoreInt32(&bs.snapshotting, 0)
+	wait := time.Second
+	for tries := 0; tries < maxSnapshotTries && !bs.book.IsSynced(); tries++ {
+		// waits for the diff-depth stream to buffer some events before fetching the snapshot.
+		time.Sleep(wait)
+		if err := bs.fetch(bs.book); err != nil {
+			s.logger.Error.Println(s.newLog(bs.runner.GetUniqueName(), err.Error()))
+		}
+		if wait *= 2; wait > maxSnapshotWait {
+			wait = maxSnapshotWait
+		}
 	}
-	return bStopC, tStopC, dStopC
 }
 
 // unsubscribe handles unsubscribing to the market data for a runner.
@@ -354,6 +471,111 @@ func (s *streamer) streamingBinanceFuturesPartitialDepth(name string,
 	return stop
 }
 
+func (s *streamer) streamingBinanceDiffDepth(name string,
+	stop chan struct{}, depthHandler func(e *bn.WsDepthEvent)) chan struct{} {
+	isError, isInit := false, true
+	errorHandler := func(err error) { s.logger.Error.Println(s.newLog(name, err.Error())); isError = true }
+	go func() {
+		var err error
+		var done chan struct{}
+		for isInit || isError {
+			done, stop, err = bn.WsDepthServe100Ms(name, depthHandler, errorHandler)
+			if err != nil {
+				s.logger.Error.Println(s.newLog(name, err.Error()))
+			}
+			isInit, isError = false, false
+			<-done
+		}
+	}()
+	time.Sleep(time.Second * waitToInitChannel)
+	return stop
+}
+
+func (s *streamer) streamingBinanceFuturesDiffDepth(name string,
+	stop chan struct{}, depthHandler func(e *bnf.WsDepthEvent)) chan struct{} {
+	isError, isInit := false, true
+	errorHandler := func(err error) { s.logger.Error.Println(s.newLog(name, err.Error())); isError = true }
+	go func() {
+		var err error
+		var done chan struct{}
+		for isInit || isError {
+			done, stop, err = bnf.WsDiffDepthServeWithRate(name, time.Duration(100*time.Millisecond), depthHandler, errorHandler)
+			if err != nil {
+				s.logger.Error.Println(s.newLog(name, err.Error()))
+			}
+			isInit, isError = false, false
+			<-done
+		}
+	}()
+	time.Sleep(time.Second * waitToInitChannel)
+	return stop
+}
+
+func (s *streamer) streamingBinanceFuturesMarkPrice(name string,
+	stop chan struct{}, markPriceHandler func(e *bnf.WsMarkPriceEvent)) chan struct{} {
+	isError, isInit := false, true
+	errorHandler := func(err error) { s.logger.Error.Println(s.newLog(name, err.Error())); isError = true }
+	go func() {
+		var err error
+		var done chan struct{}
+		for isInit || isError {
+			done, stop, err = bnf.WsMarkPriceServe(name, markPriceHandler, errorHandler)
+			if err != nil {
+				s.logger.Error.Println(s.newLog(name, err.Error()))
+			}
+			isInit, isError = false, false
+			<-done
+		}
+	}()
+	time.Sleep(time.Second * waitToInitChannel)
+	return stop
+}
+
+func (s *streamer) streamingBinanceFuturesLiquidation(name string,
+	stop chan struct{}, liquidationHandler func(e *bnf.WsLiquidationOrderEvent)) chan struct{} {
+	isError, isInit := false, true
+	errorHandler := func(err error) { s.logger.Error.Println(s.newLog(name, err.Error())); isError = true }
+	go func() {
+		var err error
+		var done chan struct{}
+		for isInit || isError {
+			done, stop, err = bnf.WsLiquidationOrderServe(name, liquidationHandler, errorHandler)
+			if err != nil {
+				s.logger.Error.Println(s.newLog(name, err.Error()))
+			}
+			isInit, isError = false, false
+			<-done
+		}
+	}()
+	time.Sleep(time.Second * waitToInitChannel)
+	return stop
+}
+
+// pollingBinanceFuturesOpenInterest polls the open interest from the provider since Binance
+// doesn't stream it. The polling stops when the returned channel receives a signal.
+func (s *streamer) pollingBinanceFuturesOpenInterest(name string,
+	openInterestHandler func(oi *tax.OpenInterest)) chan struct{} {
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(openInterestPollingInterval)
+		defer ticker.Stop()
+		for {
+			oi, err := s.provider.fetchBinanceFuturesOpenInterest(name)
+			if err != nil {
+				s.logger.Error.Println(s.newLog(name, err.Error()))
+			} else {
+				openInterestHandler(oi)
+			}
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return stop
+}
+
 // returns a log for the streamer.
 func (s *streamer) newLog(name, message string) string {
 	return fmt.Sprintf("[streamer] %s: %s", name, message)
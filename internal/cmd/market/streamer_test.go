@@ -2,6 +2,7 @@ package market
 
 import (
 	"fmt"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -10,6 +11,7 @@ import (
 	"follow.markets/internal/pkg/runner"
 	tax "follow.markets/internal/pkg/techanex"
 	"follow.markets/pkg/config"
+	"follow.markets/pkg/log"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -119,3 +121,32 @@ func Test_Streamer(t *testing.T) {
 	//time.Sleep(time.Second * 2)
 	//assert.EqualValues(t, 1, len(streamer.streamList(EVALUATOR)))
 }
+
+func Test_Streamer_OrderBookSync(t *testing.T) {
+	s := &streamer{logger: log.NewLogger()}
+	var fetches int32
+	bs := &bookSync{
+		runner: runner.NewRunner("BTCUSDT", nil),
+		book:   tax.NewOrderBook("BTCUSDT", false),
+		fetch: func(book *tax.OrderBook) error {
+			atomic.AddInt32(&fetches, 1)
+			return book.Snapshot(10, nil, nil)
+		},
+	}
+	c := make(chan *tax.OrderBook, 1)
+
+	// the updates waiting for a snapshot share the same one.
+	for i := 0; i < 5; i++ {
+		s.syncOrderBook(bs, &tax.DepthUpdate{FirstUpdateID: 11, LastUpdateID: 12}, c)
+	}
+	time.Sleep(1500 * time.Millisecond)
+	assert.EqualValues(t, 1, atomic.LoadInt32(&fetches))
+	assert.EqualValues(t, true, bs.book.IsSynced())
+	assert.EqualValues(t, int64(12), bs.book.LastUpdateID())
+
+	// a slow consumer doesn't stall the stream.
+	s.syncOrderBook(bs, &tax.DepthUpdate{FirstUpdateID: 13, LastUpdateID: 14}, c)
+	s.syncOrderBook(bs, &tax.DepthUpdate{FirstUpdateID: 15, LastUpdateID: 16}, c)
+	assert.EqualValues(t, 1, len(c))
+	assert.EqualValues(t, int64(16), bs.book.LastUpdateID())
+}
@@ -9,6 +9,7 @@ import (
 	ta "github.com/heyphat/techan"
 
 	db "follow.markets/internal/pkg/database"
+	"follow.markets/internal/pkg/strategy"
 	"follow.markets/pkg/config"
 	"follow.markets/pkg/log"
 )
@@ -16,6 +17,9 @@ import (
 type tester struct {
 	savePath string
 
+	// the sizing of signals without their own, the whole balance is traded without it.
+	sizing *strategy.Sizing
+
 	// shared properties with other market participants
 	logger   *log.Logger
 	provider *provider
@@ -25,8 +29,13 @@ func newTester(participants *sharedParticipants, configs *config.Configs) (*test
 	if participants == nil || participants.communicator == nil || participants.logger == nil {
 		return nil, errors.New("missing shared participants")
 	}
+	sizing, err := newSizing(configs.Market.Tester.Sizing)
+	if err != nil {
+		return nil, err
+	}
 	return &tester{
 		savePath: configs.Market.Tester.SavePath,
+		sizing:   sizing,
 		logger:   participants.logger,
 		provider: participants.provider,
 	}, nil
@@ -59,6 +68,10 @@ func (t *tester) initTest(id int64) ([]*backtest, error) {
 	if err != nil {
 		return nil, err
 	}
+	// the backtests don't have the firing history of other signals.
+	if data.Signal != nil && len(data.Signal.References()) > 0 {
+		return nil, errors.New("signals referring to other signals can't be backtested")
+	}
 	tickers := []string{}
 	if data.NRunners > 0 {
 		gainers, err := t.provider.fetchRunners(true, int(data.NRunners))
@@ -75,7 +88,7 @@ func (t *tester) initTest(id int64) ([]*backtest, error) {
 	}
 	var out []*backtest
 	for _, ticker := range tickers {
-		backtest := newBacktest(data.Copy(&ticker))
+		backtest := newBacktest(data.Copy(&ticker), t.sizing)
 		backtestResultID, err := t.provider.dbClient.CreateBacktestResultItem(backtest.bt)
 		if err != nil {
 			return nil, err
@@ -107,10 +120,14 @@ func (t *tester) runTest(id int64, bt *backtest) (*backtest, error) {
 			if !ok {
 				price = c.ClosePrice
 			}
+			amount, ok := bt.quantity(price)
+			if !ok {
+				continue
+			}
 			bt.rcs.Operate(ta.Order{
 				Side:          ta.OrderSideFromString(bt.s.EntryRule.Signal.BacktestSide("BUY")),
 				Price:         price,
-				Amount:        bt.balance.Div(c.ClosePrice),
+				Amount:        amount,
 				Security:      bt.r.GetName(),
 				ExecutionTime: c.Period.Start,
 			})
@@ -1,7 +1,6 @@
 package market
 
 import (
-	"fmt"
 	"testing"
 
 	"follow.markets/pkg/config"
@@ -43,7 +42,6 @@ func Test_Tester_NotionTest(t *testing.T) {
 	tester, err := newTester(initSharedParticipants(configs), configs)
 	assert.EqualValues(t, nil, err)
 
-	rs, err := tester.test(1645593180000)
+	err = tester.execute(1645593180000)
 	assert.EqualValues(t, nil, err)
-	fmt.Println(rs)
 }
@@ -15,6 +15,7 @@ import (
 
 	db "follow.markets/internal/pkg/database"
 	"follow.markets/internal/pkg/runner"
+	"follow.markets/internal/pkg/strategy"
 	tax "follow.markets/internal/pkg/techanex"
 	"follow.markets/pkg/config"
 	"follow.markets/pkg/log"
@@ -48,6 +49,9 @@ type trader struct {
 	profitMargin  big.Decimal
 	lossTolerance big.Decimal
 
+	// the sizing of signals without their own, the minimum balance is traded without it.
+	sizing *strategy.Sizing
+
 	// shared properties with other market participants
 	logger       *log.Logger
 	provider     *provider
@@ -141,9 +145,34 @@ func (t *trader) updateConfigs(configs *config.Configs) error {
 		t.minProfitPerTrade = big.NewDecimal(configs.Market.Trader.MinProfitPerTrade)
 	}
 	t.quoteCurrency = configs.Market.Base.Crypto.QuoteCurrency
+	if t.sizing, err = newSizing(configs.Market.Trader.Sizing); err != nil {
+		return err
+	}
 	return nil
 }
 
+// newSizing converts the sizing configuration, it returns nil without a configuration.
+func newSizing(c *config.Sizing) (*strategy.Sizing, error) {
+	if c == nil {
+		return nil, nil
+	}
+	sz := &strategy.Sizing{
+		Model:      c.Model,
+		Amount:     c.Amount,
+		Percent:    c.Percent,
+		ATR:        c.ATR,
+		ATRWindow:  c.ATRWindow,
+		ATRPeriod:  c.ATRPeriod,
+		WinRate:    c.WinRate,
+		Payoff:     c.Payoff,
+		MaxPercent: c.MaxPercent,
+	}
+	if err := sz.Validate(); err != nil {
+		return nil, err
+	}
+	return sz, nil
+}
+
 // isConnected returns true when the trader is connected to other market participants, false otherwise.
 func (t *trader) isConnected() bool { return t.connected }
 
@@ -347,6 +376,32 @@ func (t *trader) isEnoughBalance(r *runner.Runner) bool {
 	return false
 }
 
+// equity returns the balance of the quote currency on the account of the runner's market.
+func (t *trader) equity(r *runner.Runner) big.Decimal {
+	quote := t.quoteCurrency + t.quoteCurrency
+	switch r.GetMarketType() {
+	case runner.Cash:
+		if val, ok := t.binSpotBalances.Load(quote); ok {
+			return big.NewFromString(val.(bn.Balance).Free)
+		}
+	case runner.Futures:
+		if val, ok := t.binFutuBalances.Load(quote); ok {
+			return big.NewFromString(val.(bnf.Balance).Balance)
+		}
+	}
+	return big.ZERO
+}
+
+// quantity returns the quantity of an order opening a position of the signal on the runner,
+// sized by the signal's or the trader's sizing model, or the minimum balance without any.
+func (t *trader) quantity(r *runner.Runner, s *strategy.Signal, price, leverage big.Decimal) (big.Decimal, bool) {
+	sz := s.GetSizing(t.sizing)
+	if sz == nil {
+		return t.minBalance.Mul(leverage).Div(price), true
+	}
+	return sz.Quantity(r, *s, t.equity(r), price, leverage)
+}
+
 // isRecentlyTraded checks if the runner was being recently traded, within the period of
 // waiting for order filled.
 func (t *trader) isRecentlyTraded(r *runner.Runner) bool {
@@ -380,12 +435,34 @@ func (t *trader) initialChecks(r *runner.Runner) bool {
 	return true
 }
 
-// shouldClose checks if the current price exceeds the limit given by the loss tolerance
-// or the current price surpasses the profit margin. It also returns current PNL and PNL in dollar.
+// shouldClose checks if the position of the setup meets one of the exits of its signal, or
+// without them, if the current price exceeds the limit given by the loss tolerance or the current
+// price surpasses the profit margin. The second leg of a pairs signal is closed with the first
+// leg. It also returns current PNL and PNL in dollar.
 func (t *trader) shouldClose(st *setup, currentPrice big.Decimal) (bool, big.Decimal, big.Decimal) {
 	if currentPrice.EQ(big.ZERO) || st.avgFilledPrice.EQ(big.ZERO) {
 		return true, big.ZERO, big.ZERO
 	}
+	isClose, pnl, pnlDollar := t.exceedsRiskLimits(st, currentPrice)
+	if st.isUnhedged() {
+		return true, pnl, pnlDollar
+	}
+	if st.first != nil {
+		return st.first.isDone(), pnl, pnlDollar
+	}
+	if st.position == nil {
+		return isClose, pnl, pnlDollar
+	}
+	reason, isExit := st.signal.ShouldExit(st.runner, st.position, currentPrice, time.Now())
+	if isExit {
+		st.exitReason = reason
+	}
+	return isExit, pnl, pnlDollar
+}
+
+// exceedsRiskLimits checks if the current price exceeds the limit given by the loss tolerance
+// or the current price surpasses the profit margin. It also returns current PNL and PNL in dollar.
+func (t *trader) exceedsRiskLimits(st *setup, currentPrice big.Decimal) (bool, big.Decimal, big.Decimal) {
 	isFutures := st.runner.GetMarketType() == runner.Futures
 	isCash := st.runner.GetMarketType() == runner.Cash
 	isBuy := strings.ToUpper(st.orderSide) == "BUY"
@@ -408,7 +485,6 @@ func (t *trader) shouldClose(st *setup, currentPrice big.Decimal) (bool, big.Dec
 		return (isCash && pnl.GTE(t.profitMargin)) || (isFutures && pnlDollar.GTE(t.minProfitPerTrade)), pnl, pnlDollar
 		//return pnlDollar.GTE(t.minProfitPerTrade), pnl, pnlDollar
 	}
-	return false, big.ZERO, big.ZERO
 }
 
 // placeMarketOrder places a market order on a given runner.
@@ -469,7 +545,10 @@ func (t *trader) cancleOpenOrder(r *runner.Runner, oid int64) error {
 
 // processEvaluatorRequest take care of requests from the evaluator,
 // which will place trades if the given runner passes the initialChecks method and
-// the given signal gives a valid limit price.
+// the given signal gives a valid limit price. A pairs signal comes with its second leg,
+// which is traded on the other side once the first leg is placed, both legs must pass
+// the initialChecks and the short leg must be on futures. The first leg is unwound if the
+// second leg can't be placed.
 func (t *trader) processEvaluatorRequest(msg *message) error {
 	if msg.request.what.runner == nil || msg.request.what.signal == nil {
 		return errors.New("missing runner or signal")
@@ -478,54 +557,118 @@ func (t *trader) processEvaluatorRequest(msg *message) error {
 		return nil
 	}
 	r, s := msg.request.what.runner, msg.request.what.signal
+	leg, isPair := msg.request.what.dynamic.(*runner.Runner)
+	if isPair && !t.initialChecks(leg) {
+		return nil
+	}
+	if isPair {
+		// a cash account can't sell an asset it doesn't hold.
+		short := leg
+		if s.OpenTradingSide() == "SELL" {
+			short = r
+		}
+		if short.GetMarketType() != runner.Futures {
+			return errors.New("the short leg " + short.GetUniqueName() + " of a pairs signal must be on futures")
+		}
+	}
 	price, ok := s.TradeExecutionPrice(r)
 	if !ok {
 		t.logger.Warning.Println(t.newLog("cannot find a price to place trade"))
 		return nil
 	}
+	leverage := big.ONE
+	if r.GetMarketType() == runner.Futures {
+		leverage = t.maxLeverage
+	}
+	qty, ok := t.quantity(r, s, price, leverage)
+	if !ok {
+		t.logger.Warning.Println(t.newLog("cannot size a position to place trade"))
+		return nil
+	}
+	st, err := t.placeLimitOrder(r, s, s.OpenTradingSide(), price, qty, leverage, nil)
+	if err != nil || st == nil || !isPair {
+		return err
+	}
+	legPrice, ok := s.TradeExecutionPrice(leg)
+	if !ok {
+		return t.unwind(st, "cannot find a price to place the second leg")
+	}
+	legQty, ok := s.Pair.LegQuantity(qty, price, legPrice)
+	if !ok {
+		return t.unwind(st, "cannot size the second leg to place trade")
+	}
+	legLeverage := big.ONE
+	if leg.GetMarketType() == runner.Futures {
+		legLeverage = t.maxLeverage
+	}
+	legSt, err := t.placeLimitOrder(leg, s, s.CloseTradingSide(), legPrice, legQty, legLeverage, st)
+	if err != nil {
+		return t.unwind(st, "cannot place the second leg: "+err.Error())
+	}
+	if legSt == nil {
+		return t.unwind(st, "the market of the second leg isn't supported")
+	}
+	return nil
+}
+
+// unwind cancels the order of the first leg of a pairs signal whose second leg can't be placed,
+// its filled quantity is closed by its monitor, so that the pair is never left unhedged. The
+// setup is reported with the reason when its monitor is done.
+func (t *trader) unwind(st *setup, reason string) error {
+	st.exitReason = unhedgedExit + ": " + reason
+	if err := t.cancleOpenOrder(st.runner, st.orderID); err != nil {
+		t.logger.Error.Println(t.newLog(err.Error()))
+	}
+	return errors.New(reason + ", unwinding the first leg " + st.runner.GetUniqueName())
+}
+
+// placeLimitOrder places a limit order on the runner for the signal, and monitors the trade
+// of its setup, the first leg's setup is given for the second leg of a pairs signal. It returns
+// a nil setup if the market isn't supported.
+func (t *trader) placeLimitOrder(r *runner.Runner, s *strategy.Signal, side string, price, qty, leverage big.Decimal, first *setup) (*setup, error) {
+	var st *setup
 	switch r.GetMarketType() {
 	case runner.Cash:
 		pricePrecision, quantityPrecision, err := t.provider.fetchBinSpotExchangeInfo(r.GetName())
 		if err != nil {
-			return err
+			return nil, err
 		}
 		o, err := t.provider.binSpot.NewCreateOrderService().
 			Symbol(r.GetName()).
-			Side(bn.SideType(s.OpenTradingSide())).
+			Side(bn.SideType(side)).
 			Type(bn.OrderTypeLimit).
 			TimeInForce(bn.TimeInForceTypeGTC).
 			Price(price.FormattedString(pricePrecision)).
-			Quantity(t.minBalance.Div(price).FormattedString(quantityPrecision)).
+			Quantity(qty.FormattedString(quantityPrecision)).
 			Do(context.Background())
 		if err != nil {
-			return err
+			return nil, err
 		}
-		st := newSetup(r, s, big.ONE, o)
-		t.binTrades.Store(r.GetUniqueName(), st)
-		go t.monitorBinTrade(st)
+		st = newSetup(r, s, big.ONE, o)
 	case runner.Futures:
 		pricePrecision, quantityPrecision, err := t.provider.fetchBinFutuExchangeInfo(r.GetName())
 		if err != nil {
-			return err
+			return nil, err
 		}
 		o, err := t.provider.binFutu.NewCreateOrderService().
 			Symbol(r.GetName()).
-			Side(bnf.SideType(s.OpenTradingSide())).
+			Side(bnf.SideType(side)).
 			Type(bnf.OrderTypeLimit).
 			TimeInForce(bnf.TimeInForceTypeGTC).
 			Price(price.FormattedString(pricePrecision)).
-			Quantity(t.minBalance.Mul(t.maxLeverage).Div(price).FormattedString(quantityPrecision)).
+			Quantity(qty.FormattedString(quantityPrecision)).
 			Do(context.Background())
 		if err != nil {
-			return err
+			return nil, err
 		}
-		st := newSetup(r, s, t.maxLeverage, o)
-		t.binTrades.Store(r.GetUniqueName(), st)
-		go t.monitorBinTrade(st)
+		st = newSetup(r, s, leverage, o)
 	default:
-		return nil
+		return nil, nil
 	}
-	return nil
+	st.first = first
+	t.binTrades.Store(r.GetUniqueName(), st)
+	go t.monitorBinTrade(st)
+	return st, nil
 }
 
 // monitorBinSpotTrade monitors the trade after an order is placed successfully.
@@ -544,6 +687,12 @@ func (t *trader) monitorBinTrade(st *setup) {
 			st.orderStatus == "REJECTED" ||
 			st.orderStatus == "EXPIRED" ||
 			st.orderStatus == "PENDING_CANCEL" {
+			// the quantity filled before an unwound first leg was cancelled is closed too.
+			if st.isUnhedged() && st.accFilledQtity.GT(big.ZERO) {
+				if err := t.placeMarketOrder(st.runner, st.closingSide(), st.accFilledQtity.String()); err != nil {
+					t.logger.Error.Println(t.newLog(err.Error()))
+				}
+			}
 			return
 		}
 		if time.Now().Sub(nw) > maxWait {
@@ -559,6 +708,9 @@ func (t *trader) monitorBinTrade(st *setup) {
 	for st.avgFilledPrice.EQ(big.ZERO) {
 		time.Sleep(time.Second)
 	}
+	if st.signal.HasExit() && st.first == nil {
+		st.position = st.signal.NewPosition(st.runner, st.orderSide, st.avgFilledPrice, time.Now())
+	}
 	st.channels = &streamingChannels{depth: make(chan interface{}, 20)}
 	t.registerStreamingChannel(st)
 	bestPrice, isCash := tax.NewPriceLevel(), st.runner.GetMarketType() == runner.Cash
@@ -589,7 +741,7 @@ func (t *trader) monitorBinTrade(st *setup) {
 			}
 			balance = val.(bnf.Balance).Balance
 		}
-		if err := t.placeMarketOrder(st.runner, st.signal.CloseTradingSide(), balance); err != nil {
+		if err := t.placeMarketOrder(st.runner, st.closingSide(), balance); err != nil {
 			t.logger.Error.Println(t.newLog(err.Error()))
 		}
 		st.lastUpdatedAt = time.Now().Unix() * 1000
@@ -3,6 +3,7 @@ package market
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -13,6 +14,11 @@ import (
 	tax "follow.markets/internal/pkg/techanex"
 )
 
+const (
+	fundingRateHistorySize  = 100
+	openInterestHistorySize = 500
+)
+
 type watcher struct {
 	sync.Mutex
 	connected bool
@@ -55,6 +61,16 @@ func (w *watcher) get(name string) *runner.Runner {
 	return nil
 }
 
+// list returns all the runners on the watchlist.
+func (w *watcher) list() []*runner.Runner {
+	var out []*runner.Runner
+	w.runners.Range(func(key, value interface{}) bool {
+		out = append(out, value.(*wmember).runner)
+		return true
+	})
+	return out
+}
+
 // watchlist returns a watchlist where tickers are being monitored and reported.
 func (w *watcher) watchlist() []string {
 	tickers := []string{}
@@ -110,7 +126,8 @@ func (w *watcher) watch(ticker string, rc *runner.RunnerConfigs, fd *runner.Fund
 	m := &wmember{
 		runner: runner.NewRunner(ticker, rc),
 		channels: &streamingChannels{
-			bar: make(chan *ta.Candle, 2),
+			bar:     make(chan *ta.Candle, 2),
+			partial: make(chan *ta.Candle, 10),
 		},
 	}
 	if w.isWatchingOn(m.runner.GetUniqueName()) {
@@ -119,6 +136,9 @@ func (w *watcher) watch(ticker string, rc *runner.RunnerConfigs, fd *runner.Fund
 	if fd != nil {
 		m.runner.SetFundamental(fd)
 	}
+	if rc.Market == runner.Futures {
+		m.channels.derivative = make(chan interface{}, 10)
+	}
 	for _, f := range m.runner.GetConfigs().LFrames {
 		var candles []*ta.Candle
 		switch rc.Market {
@@ -140,6 +160,11 @@ func (w *watcher) watch(ticker string, rc *runner.RunnerConfigs, fd *runner.Fund
 			return errors.New(fmt.Sprintf("failed to sync %v candles on initialization", f))
 		}
 	}
+	if rc.Market == runner.Futures {
+		if err := w.initDerivatives(m.runner); err != nil {
+			w.logger.Warning.Println(w.newLog(m.runner.GetUniqueName(), err.Error()))
+		}
+	}
 	w.Lock()
 	defer w.Unlock()
 	w.runners.Store(m.runner.GetUniqueName(), m)
@@ -156,12 +181,37 @@ func (w *watcher) await(mem *wmember) {
 		if mem.channels.bar == nil {
 			return
 		}
-		for msg := range mem.channels.bar {
-			if !mem.runner.SyncCandle(msg) {
-				w.logger.Error.Println(w.newLog(mem.runner.GetName(), "failed to sync new candle on watching"))
-				continue
+		// final and partial candles are synced in the same routine, so the runner's lines
+		// are never updated concurrently.
+		for {
+			select {
+			case msg, ok := <-mem.channels.bar:
+				if !ok {
+					return
+				}
+				if !mem.runner.SyncCandle(msg) {
+					w.logger.Error.Println(w.newLog(mem.runner.GetName(), "failed to sync new candle on watching"))
+					continue
+				}
+				w.syncBasis(mem.runner)
+				w.communicator.watcher2Evaluator <- w.communicator.newMessage(mem.runner, nil, nil, nil, nil)
+			case msg, ok := <-mem.channels.partial:
+				if !ok {
+					return
+				}
+				// stale partial candles, received after the final one, are skipped. The partial
+				// candle is only synced to a view of the runner, evaluated by the intrabar signals.
+				view, ok := mem.runner.WithPartialCandle(msg)
+				if !ok {
+					continue
+				}
+				w.communicator.watcher2Evaluator <- w.communicator.newMessage(mem.runner, nil, nil, view, nil)
+			case msg, ok := <-mem.channels.derivative:
+				if !ok {
+					return
+				}
+				w.syncDerivative(mem.runner, msg)
 			}
-			w.communicator.watcher2Evaluator <- w.communicator.newMessage(mem.runner, nil, nil, nil, nil)
 		}
 	}()
 	go func() {
@@ -177,6 +227,115 @@ func (w *watcher) await(mem *wmember) {
 	}
 }
 
+// initDerivatives initializes the futures market data of the runner with the funding rate
+// and the open interest history. The history is synced in chronological order since the
+// derivative series only goes forward.
+func (w *watcher) initDerivatives(r *runner.Runner) error {
+	type event struct {
+		time time.Time
+		data interface{}
+	}
+	var events []event
+	rates, err := w.provider.fetchBinanceFuturesFundingRates(r.GetName(), fundingRateHistorySize)
+	if err != nil {
+		return err
+	}
+	for _, rate := range rates {
+		events = append(events, event{time: rate.Time, data: rate})
+	}
+	fetched := make(map[string]bool)
+	for _, f := range r.GetConfigs().LFrames {
+		period := binanceOpenInterestPeriod(f)
+		if fetched[period] {
+			continue
+		}
+		fetched[period] = true
+		ois, err := w.provider.fetchBinanceFuturesOpenInterestHistory(r.GetName(), period, openInterestHistorySize)
+		if err != nil {
+			return err
+		}
+		for _, oi := range ois {
+			events = append(events, event{time: oi.Time, data: oi})
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].time.Before(events[j].time) })
+	for _, e := range events {
+		w.syncDerivative(r, e.data)
+	}
+	return nil
+}
+
+// syncDerivative syncs the futures market data to the runner.
+func (w *watcher) syncDerivative(r *runner.Runner, data interface{}) {
+	switch d := data.(type) {
+	case *tax.MarkPrice:
+		r.SyncMarkPrice(d)
+	case *tax.OpenInterest:
+		r.SyncOpenInterest(d)
+	case *tax.FundingRate:
+		r.SyncFundingRate(d)
+	case *tax.Liquidation:
+		r.SyncLiquidation(d)
+	}
+}
+
+// pair returns the runner of the same symbol on the other market, the perpetual runner for
+// a spot runner and vice versa. It returns nil if the other runner isn't on the watchlist.
+func (w *watcher) pair(r *runner.Runner) *runner.Runner {
+	switch r.GetMarketType() {
+	case runner.Cash:
+		if p := w.get(r.GetName() + "PERP"); p != nil && p.GetMarketType() == runner.Futures {
+			return p
+		}
+	case runner.Futures:
+		if p := w.get(r.GetName()); p != nil && p.GetMarketType() == runner.Cash {
+			return p
+		}
+	}
+	return nil
+}
+
+// syncBasis syncs the basis of the runner and its pair on all common time frames. The basis
+// is only synced when the last candles of both runners are of the same period, so it's synced
+// by whichever runner receives the candle last.
+func (w *watcher) syncBasis(r *runner.Runner) {
+	p := w.pair(r)
+	if p == nil {
+		return
+	}
+	spot, perp := r, p
+	if r.GetMarketType() == runner.Futures {
+		spot, perp = p, r
+	}
+	for _, f := range spot.GetConfigs().LFrames {
+		sc, pc := spot.LastCandle(f), perp.LastCandle(f)
+		if sc == nil || pc == nil || !sc.Period.Start.Equal(pc.Period.Start) {
+			continue
+		}
+		bs := w.basisSeries(spot, perp, f)
+		var d *tax.Derivative
+		if ds, ok := perp.GetDerivatives(f); ok {
+			d, _ = ds.DerivativeAt(pc.Period.Start)
+		}
+		bs.Sync(sc, pc, d)
+		bs.Shrink(runner.MaxSize())
+	}
+}
+
+// basisSeries returns the basis series shared by the spot and the perpetual runner on the
+// given time frame, it creates a new series if there is none.
+func (w *watcher) basisSeries(spot, perp *runner.Runner, f time.Duration) *tax.BasisSeries {
+	w.Lock()
+	defer w.Unlock()
+	if bs, ok := spot.GetBasis(f); ok {
+		return bs
+	}
+	bs := tax.NewBasisSeries(tax.DefaultPremiumWindow)
+	spot.SetBasis(f, bs)
+	perp.SetBasis(f, bs)
+	return bs
+}
+
 // drop removes the given ticker from the watchlist. It closes all the streaming channels.
 func (w *watcher) drop(ticker string, rc *runner.RunnerConfigs) error {
 	if rc == nil {
@@ -193,6 +352,10 @@ func (w *watcher) drop(ticker string, rc *runner.RunnerConfigs) error {
 		w.logger.Error.Println(w.newLog(r.GetName(), "failed to deregister streaming data"))
 	}
 	w.runners.Delete(r.GetUniqueName())
+	if p := w.pair(r); p != nil {
+		p.ClearBasis()
+	}
+	r.ClearBasis()
 	return nil
 }
 
@@ -228,6 +391,22 @@ func (w *watcher) lastIndicators(ticker string) []*tax.Indicator {
 	return inds
 }
 
+// lastBases returns the last basis from all frames of a symbol watched on both the spot and
+// the perpetual market.
+func (w *watcher) lastBases(ticker string) []*tax.Basis {
+	bases := make([]*tax.Basis, 0)
+	r := w.get(ticker)
+	if r == nil {
+		return bases
+	}
+	for _, d := range r.GetConfigs().LFrames {
+		if bs, ok := r.GetBasis(d); ok && bs.LastBasis() != nil {
+			bases = append(bases, bs.LastBasis())
+		}
+	}
+	return bases
+}
+
 // connect connects the watcher to other market participants by listening to
 // decicated channels for communication.
 func (w *watcher) connect() {
@@ -241,6 +420,11 @@ func (w *watcher) connect() {
 			go w.processStreamerRequest(msg)
 		}
 	}()
+	go func() {
+		for msg := range w.communicator.evaluator2Watcher {
+			go w.processEvaluatorRequest(msg)
+		}
+	}()
 	w.connected = true
 }
 
@@ -265,6 +449,17 @@ func (w *watcher) processStreamerRequest(msg *message) {
 	//	close(msg.response)
 }
 
+// processEvaluatorRequest responds with the runner of the requested unique name, nil if it's
+// not on the watchlist, e.g. for the evaluator to find the second leg of a pairs signal.
+func (w *watcher) processEvaluatorRequest(msg *message) {
+	if msg.response == nil {
+		return
+	}
+	name, _ := msg.request.what.dynamic.(string)
+	msg.response <- w.communicator.newPayload(w.get(name), nil, nil, nil).addRequestID(&msg.request.requestID).addResponseID()
+	close(msg.response)
+}
+
 // newLog generates a new log with the format for the watcher
 func (w *watcher) newLog(ticker, message string) string {
 	return fmt.Sprintf("[watcher] %s: %s", ticker, message)
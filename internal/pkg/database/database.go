@@ -28,6 +28,10 @@ type Client interface {
 	UpdateBacktestStatus(id int64, st *BacktestStatus, isResult bool) error
 	UpdateBacktestResult(id int64, rs map[string]float64, ts ...*ta.Position) error
 	CreateBacktestResultItem(bt *Backtest) (int64, error)
+
+	// signal registry methods, all the versions of a signal are kept.
+	InsertSignalRecord(sr *SignalRecord) error
+	GetSignalRecords(name *string) ([]*SignalRecord, error)
 }
 
 // Create a new db client based on user configuration options.
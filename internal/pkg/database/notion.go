@@ -29,6 +29,7 @@ type Notion struct {
 	notisDB *notion.Database
 	btestDB *notion.Database
 	rtestDB *notion.Database
+	signlDB *notion.Database
 }
 
 func newNotionClient(configs *config.Configs) Notion {
@@ -51,6 +52,12 @@ func newNotionClient(configs *config.Configs) Notion {
 		n.logger.Error.Println(n.newLog(err.Error()))
 		return Notion{}
 	}
+	// the signal registry is optional, signals are only kept in memory without it.
+	if len(configs.Database.Notion.SignalDBID) > 0 {
+		if n.signlDB, err = n.client.Database.Get(context.Background(), notion.DatabaseID(configs.Database.Notion.SignalDBID)); err != nil {
+			n.logger.Error.Println(n.newLog(err.Error()))
+		}
+	}
 	n.isInitialized = true
 	return *n
 }
@@ -404,6 +411,49 @@ func (n Notion) UpdateBacktestResult(id int64, rs map[string]float64, ts ...*ta.
 	return nil
 }
 
+func (n Notion) InsertSignalRecord(sr *SignalRecord) error {
+	if !n.isInitialized || n.signlDB == nil {
+		return errors.New("signal DB hasn't been initialized.")
+	}
+	if sr == nil {
+		return errors.New("missing signal record")
+	}
+	if _, err := n.client.Page.Create(context.Background(), n.newPageRequest(notion.DatabaseID(n.configs.SignalDBID), sr.convertNotion())); err != nil {
+		n.logger.Error.Println(n.newLog(err.Error()))
+		return err
+	}
+	return nil
+}
+
+// GetSignalRecords returns all the versions of the signal of the given name, or of all the
+// signals if the name is missing, sorted by version.
+func (n Notion) GetSignalRecords(name *string) ([]*SignalRecord, error) {
+	if !n.isInitialized || n.signlDB == nil {
+		return nil, errors.New("signal DB hasn't been initialized.")
+	}
+	request := &notion.DatabaseQueryRequest{
+		Sorts: []notion.SortObject{notion.SortObject{Property: "Version", Direction: notion.SortOrderASC}},
+	}
+	if name != nil {
+		request.PropertyFilter = &notion.PropertyFilter{Property: "Name", Text: &notion.TextFilterCondition{Equals: *name}}
+	}
+	var out []*SignalRecord
+	for {
+		rsp, err := n.client.Database.Query(context.Background(), notion.DatabaseID(n.configs.SignalDBID), request)
+		if err != nil {
+			return nil, err
+		}
+		for _, page := range rsp.Results {
+			out = append(out, parseNotionSignalRecord(page))
+		}
+		if !rsp.HasMore {
+			break
+		}
+		request.StartCursor = rsp.NextCursor
+	}
+	return out, nil
+}
+
 func (n Notion) newLog(msg string) string {
 	return fmt.Sprintf("[notion]: %s", msg)
 }
@@ -0,0 +1,163 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	notion "github.com/heyphat/notionapi"
+
+	"follow.markets/internal/pkg/strategy"
+)
+
+// the max length of a notion rich text object.
+const notionTextLimit = 2000
+
+// SignalRecord is a version of a signal saved to the database with the patterns of the tickers
+// it's evaluated on. Every update of a signal is saved as a new record of the next version,
+// the latest version of a signal is the one to evaluate if it's active.
+type SignalRecord struct {
+	Name      string    `bson:"name" json:"name"`
+	Version   int64     `bson:"version" json:"version"`
+	Patterns  []string  `bson:"patterns" json:"patterns"`
+	OwnerID   *int64    `bson:"owner_id,omitempty" json:"owner_id,omitempty"`
+	Active    bool      `bson:"active" json:"active"`
+	Signal    string    `bson:"signal" json:"signal"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+// NewSignalRecord returns a record of the given signal. The signal is saved as json, a signal
+// given as an expression is saved without its compiled rule.
+func NewSignalRecord(s *strategy.Signal, patterns []string, version int64, active bool) (*SignalRecord, error) {
+	if s == nil {
+		return nil, errors.New("missing signal")
+	}
+	bts, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return &SignalRecord{
+		Name:      s.Name,
+		Version:   version,
+		Patterns:  patterns,
+		OwnerID:   s.OwnerID,
+		Active:    active,
+		Signal:    string(bts),
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// GetSignal returns the signal of the record.
+func (sr *SignalRecord) GetSignal() (*strategy.Signal, error) {
+	return strategy.NewSignalFromBytes([]byte(sr.Signal))
+}
+
+// LatestSignalRecords returns the latest version of each signal, sorted by name.
+func LatestSignalRecords(srs []*SignalRecord) []*SignalRecord {
+	latest := make(map[string]*SignalRecord)
+	for _, sr := range srs {
+		if l, ok := latest[sr.Name]; !ok || sr.Version > l.Version {
+			latest[sr.Name] = sr
+		}
+	}
+	out := make([]*SignalRecord, 0, len(latest))
+	for _, sr := range latest {
+		out = append(out, sr)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func (sr SignalRecord) convertNotion() map[string]notion.Property {
+	out := make(map[string]notion.Property, 7)
+	createdT := notion.Date(sr.CreatedAt)
+	out["Name"] = notion.TitleProperty{Title: []notion.RichText{notion.RichText{Text: notion.Text{Content: sr.Name}}}}
+	out["Version"] = notion.NumberProperty{Number: float64(sr.Version)}
+	// the patterns are regular expressions which might contain commas, they're saved as json.
+	patterns, _ := json.Marshal(sr.Patterns)
+	out["Patterns"] = notion.RichTextProperty{RichText: notionTexts(string(patterns))}
+	out["Active"] = notion.CheckboxProperty{Checkbox: sr.Active}
+	out["CreatedAt"] = notion.DateProperty{Date: notion.DateObject{Start: &createdT}}
+	if sr.OwnerID != nil {
+		out["OwnerID"] = notion.NumberProperty{Number: float64(*sr.OwnerID)}
+	}
+	out["Signal"] = notion.RichTextProperty{RichText: notionTexts(sr.Signal)}
+	return out
+}
+
+// notionTexts splits the given text into rich text objects, the text might be longer than a
+// rich text object allows. It's split on rune boundaries, a rune cut in half isn't valid utf-8.
+func notionTexts(text string) []notion.RichText {
+	var out []notion.RichText
+	for len(text) > 0 {
+		end := len(text)
+		if end > notionTextLimit {
+			end = notionTextLimit
+			for end > 0 && !utf8.RuneStart(text[end]) {
+				end--
+			}
+		}
+		out = append(out, notion.RichText{Text: notion.Text{Content: text[:end]}})
+		text = text[end:]
+	}
+	return out
+}
+
+// joinNotionTexts joins the rich text objects split by notionTexts.
+func joinNotionTexts(texts []notion.RichText) string {
+	var b strings.Builder
+	for _, t := range texts {
+		b.WriteString(t.Text.Content)
+	}
+	return b.String()
+}
+
+func parseNotionSignalRecord(page notion.Page) *SignalRecord {
+	sr := &SignalRecord{}
+	for k, v := range page.Properties {
+		switch k {
+		case "Name":
+			if p, ok := v.(*notion.TitleProperty); ok && len(p.Title) > 0 {
+				sr.Name = p.Title[0].Text.Content
+			}
+		case "Version":
+			if p, ok := v.(*notion.NumberProperty); ok {
+				sr.Version = int64(p.Number)
+			}
+		case "Patterns":
+			if p, ok := v.(*notion.RichTextProperty); ok {
+				sr.Patterns = parseNotionPatterns(joinNotionTexts(p.RichText))
+			}
+		case "OwnerID":
+			if p, ok := v.(*notion.NumberProperty); ok && p.Number != 0 {
+				id := int64(p.Number)
+				sr.OwnerID = &id
+			}
+		case "Active":
+			if p, ok := v.(*notion.CheckboxProperty); ok {
+				sr.Active = p.Checkbox
+			}
+		case "CreatedAt":
+			if p, ok := v.(*notion.DateProperty); ok && p.Date.Start != nil {
+				sr.CreatedAt = time.Time(*p.Date.Start)
+			}
+		case "Signal":
+			if p, ok := v.(*notion.RichTextProperty); ok {
+				sr.Signal = joinNotionTexts(p.RichText)
+			}
+		}
+	}
+	return sr
+}
+
+// parseNotionPatterns parses the patterns saved as json.
+func parseNotionPatterns(text string) []string {
+	var out []string
+	if err := json.Unmarshal([]byte(text), &out); err != nil {
+		return nil
+	}
+	return out
+}
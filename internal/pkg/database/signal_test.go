@@ -0,0 +1,94 @@
+package database
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	notion "github.com/heyphat/notionapi"
+	"github.com/stretchr/testify/assert"
+
+	"follow.markets/internal/pkg/strategy"
+)
+
+func Test_NewSignalRecord(t *testing.T) {
+	_, err := NewSignalRecord(nil, nil, 1, true)
+	assert.EqualValues(t, "missing signal", err.Error())
+
+	s, err := strategy.NewSignalFromBytes([]byte(`{"name": "record", "expression": "CLOSE > 1", "exit": {"expression": "CLOSE < 1"}}`))
+	assert.EqualValues(t, nil, err)
+	sr, err := NewSignalRecord(s, []string{"BTC"}, 2, true)
+	assert.EqualValues(t, nil, err)
+	assert.EqualValues(t, "record", sr.Name)
+	assert.EqualValues(t, 2, sr.Version)
+	assert.EqualValues(t, true, sr.Active)
+
+	// the signal decodes back from the record.
+	rs, err := sr.GetSignal()
+	assert.EqualValues(t, nil, err)
+	assert.EqualValues(t, "CLOSE > 1", rs.Expression)
+	assert.EqualValues(t, "CLOSE < 1", rs.Exit.Expression)
+	assert.EqualValues(t, true, rs.Rule != nil)
+}
+
+func Test_LatestSignalRecords(t *testing.T) {
+	srs := []*SignalRecord{
+		&SignalRecord{Name: "b", Version: 1},
+		&SignalRecord{Name: "a", Version: 2},
+		&SignalRecord{Name: "b", Version: 3},
+		&SignalRecord{Name: "a", Version: 1},
+	}
+	latest := LatestSignalRecords(srs)
+	assert.EqualValues(t, 2, len(latest))
+	assert.EqualValues(t, "a", latest[0].Name)
+	assert.EqualValues(t, 2, latest[0].Version)
+	assert.EqualValues(t, "b", latest[1].Name)
+	assert.EqualValues(t, 3, latest[1].Version)
+	assert.EqualValues(t, 0, len(LatestSignalRecords(nil)))
+}
+
+func Test_SignalRecord_Notion(t *testing.T) {
+	id := int64(7)
+	sr := &SignalRecord{
+		Name:     "record",
+		Version:  3,
+		Patterns: []string{"^(BTC|ETH){1,2}USDT$", strings.Repeat("A", 3*notionTextLimit)},
+		OwnerID:  &id,
+		Active:   true,
+		Signal:   `{"name": "record", "expression": "` + strings.Repeat("CLOSE > 1 AND ", 200) + `CLOSE > 1"}`,
+	}
+	ps := sr.convertNotion()
+	for _, k := range []string{"Patterns", "Signal"} {
+		for _, rt := range ps[k].(notion.RichTextProperty).RichText {
+			assert.EqualValues(t, true, len(rt.Text.Content) <= notionTextLimit)
+		}
+	}
+
+	// the pages queried from notion hold pointers to the properties.
+	page := notion.Page{Properties: notion.Properties{}}
+	for k, v := range ps {
+		p := reflect.New(reflect.TypeOf(v))
+		p.Elem().Set(reflect.ValueOf(v))
+		page.Properties[k] = p.Interface().(notion.Property)
+	}
+	out := parseNotionSignalRecord(page)
+	assert.EqualValues(t, sr.Name, out.Name)
+	assert.EqualValues(t, sr.Version, out.Version)
+	assert.EqualValues(t, sr.Patterns, out.Patterns)
+	assert.EqualValues(t, id, *out.OwnerID)
+	assert.EqualValues(t, true, out.Active)
+	assert.EqualValues(t, sr.Signal, out.Signal)
+
+	assert.EqualValues(t, 0, len(parseNotionPatterns("")))
+
+	// the texts are split on rune boundaries.
+	text := strings.Repeat("A", notionTextLimit-1) + "€" + strings.Repeat("B", notionTextLimit)
+	rts := notionTexts(text)
+	assert.EqualValues(t, 3, len(rts))
+	assert.EqualValues(t, notionTextLimit-1, len(rts[0].Text.Content))
+	for _, rt := range rts {
+		assert.EqualValues(t, true, utf8.ValidString(rt.Text.Content))
+	}
+	assert.EqualValues(t, text, joinNotionTexts(rts))
+}
@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	ta "github.com/heyphat/techan"
@@ -28,12 +29,18 @@ var (
 		24 * time.Hour,
 	}
 	maxSize = 1500
+
+	// tradeRetention is how long the recent trades are kept on the runner.
+	tradeRetention = 15 * time.Minute
 )
 
 func ChangeMaxSize(size int) {
 	maxSize = size
 }
 
+// MaxSize returns the maximum number of candles kept on a line.
+func MaxSize() int { return maxSize }
+
 type RunnerConfigs struct {
 	Asset    AssetClass
 	Market   MarketType
@@ -64,9 +71,10 @@ func NewRunnerDefaultConfigs() *RunnerConfigs {
 
 type Fundamental struct {
 	//cmcRank           int     `json:"cmc_rank"`
-	MaxSupply         float64 `json:"max_supply"`
-	TotalSupply       float64 `json:"total_supply"`
-	CirculatingSupply float64 `json:"circulating_supply"`
+	MaxSupply         float64   `json:"max_supply"`
+	TotalSupply       float64   `json:"total_supply"`
+	CirculatingSupply float64   `json:"circulating_supply"`
+	DateAdded         time.Time `json:"date_added"`
 }
 
 type Runner struct {
@@ -74,8 +82,16 @@ type Runner struct {
 
 	name        string
 	lines       map[time.Duration]*tax.Series
+	derivatives map[time.Duration]*tax.DerivativeSeries
+	basis       map[time.Duration]*tax.BasisSeries
 	configs     *RunnerConfigs
 	fundamental *Fundamental
+	book        *tax.OrderBook
+	trades      *tax.Trades
+	lastFinal   time.Time
+
+	// 1 if the runner has signals evaluated on its partial candles.
+	intrabar int32
 }
 
 func NewRunner(name string, configs *RunnerConfigs) *Runner {
@@ -87,19 +103,112 @@ func NewRunner(name string, configs *RunnerConfigs) *Runner {
 	for _, frame := range configs.LFrames {
 		lines[frame] = tax.NewSeries(configs.IConfigs)
 	}
+	// futures market data are only available for futures runners.
+	var derivatives map[time.Duration]*tax.DerivativeSeries
+	if configs.Market == Futures {
+		derivatives = make(map[time.Duration]*tax.DerivativeSeries, len(configs.LFrames))
+		for _, frame := range configs.LFrames {
+			derivatives[frame] = tax.NewDerivativeSeries(frame)
+		}
+	}
 	return &Runner{
-		name:    name,
-		lines:   lines,
-		configs: configs,
+		name:        name,
+		lines:       lines,
+		derivatives: derivatives,
+		basis:       make(map[time.Duration]*tax.BasisSeries),
+		configs:     configs,
+		trades:      tax.NewTrades(tradeRetention),
 	}
 }
 
 // SetFundamental set the fundamental values to the runner.
 func (r *Runner) SetFundamental(fund *Fundamental) { r.fundamental = fund }
 
+// SetOrderBook sets the local order book of the runner.
+func (r *Runner) SetOrderBook(book *tax.OrderBook) { r.Lock(); defer r.Unlock(); r.book = book }
+
+// GetOrderBook returns the local order book of the runner, false if the runner doesn't
+// have a synced order book.
+func (r *Runner) GetOrderBook() (*tax.OrderBook, bool) {
+	r.Lock()
+	defer r.Unlock()
+	if r.book == nil || !r.book.IsSynced() {
+		return nil, false
+	}
+	return r.book, true
+}
+
+// SyncTrade adds the given trade to the recent trades of the runner.
+func (r *Runner) SyncTrade(t *tax.Trade) { r.trades.Add(t) }
+
+// GetTrades returns the recent trades of the runner.
+func (r *Runner) GetTrades() *tax.Trades { return r.trades }
+
 // GetLines returns a line of type tax.Series based on the given time frame.
 func (r *Runner) GetLines(d time.Duration) (*tax.Series, bool) { k, v := r.lines[d]; return k, v }
 
+// GetDerivatives returns a series of futures market data based on the given time frame.
+func (r *Runner) GetDerivatives(d time.Duration) (*tax.DerivativeSeries, bool) {
+	k, v := r.derivatives[d]
+	return k, v
+}
+
+// SyncMarkPrice syncs the given mark price, index price and funding rate to the derivative series.
+func (r *Runner) SyncMarkPrice(m *tax.MarkPrice) bool {
+	return r.syncDerivatives(func(ds *tax.DerivativeSeries) bool { return ds.SyncMarkPrice(m) })
+}
+
+// SyncOpenInterest syncs the given open interest to the derivative series.
+func (r *Runner) SyncOpenInterest(o *tax.OpenInterest) bool {
+	return r.syncDerivatives(func(ds *tax.DerivativeSeries) bool { return ds.SyncOpenInterest(o) })
+}
+
+// SyncFundingRate syncs the given realized funding rate to the derivative series.
+func (r *Runner) SyncFundingRate(f *tax.FundingRate) bool {
+	return r.syncDerivatives(func(ds *tax.DerivativeSeries) bool { return ds.SyncFundingRate(f) })
+}
+
+// SyncLiquidation syncs the given liquidation order to the derivative series.
+func (r *Runner) SyncLiquidation(l *tax.Liquidation) bool {
+	return r.syncDerivatives(func(ds *tax.DerivativeSeries) bool { return ds.SyncLiquidation(l) })
+}
+
+func (r *Runner) syncDerivatives(sync func(ds *tax.DerivativeSeries) bool) bool {
+	if len(r.derivatives) == 0 {
+		return false
+	}
+	ok := true
+	for _, ds := range r.derivatives {
+		ok = sync(ds) && ok
+		ds.Shrink(maxSize)
+	}
+	return ok
+}
+
+// SetBasis sets the basis series of the given time frame. The series is shared between
+// the spot and the perpetual runner of the same symbol.
+func (r *Runner) SetBasis(d time.Duration, bs *tax.BasisSeries) {
+	r.Lock()
+	defer r.Unlock()
+	r.basis[d] = bs
+}
+
+// GetBasis returns the basis series of the given time frame, false if the runner isn't
+// paired with a runner of the other market.
+func (r *Runner) GetBasis(d time.Duration) (*tax.BasisSeries, bool) {
+	r.Lock()
+	defer r.Unlock()
+	k, v := r.basis[d]
+	return k, v
+}
+
+// ClearBasis removes all the basis series of the runner.
+func (r *Runner) ClearBasis() {
+	r.Lock()
+	defer r.Unlock()
+	r.basis = make(map[time.Duration]*tax.BasisSeries)
+}
+
 // GetConfigs returns the runner's configurations.
 func (r *Runner) GetConfigs() *RunnerConfigs { return r.configs }
 
@@ -171,6 +280,38 @@ func (r *Runner) GetMaxSupply() big.Decimal {
 	return big.NewDecimal(r.fundamental.MaxSupply)
 }
 
+// GetListingTime returns the time the runner was listed, it's unknown without the fundamental.
+func (r *Runner) GetListingTime() (time.Time, bool) {
+	if r == nil || r.fundamental == nil || r.fundamental.DateAdded.IsZero() {
+		return time.Time{}, false
+	}
+	return r.fundamental.DateAdded, true
+}
+
+// GetQuoteVolume returns the volume in the quote asset over the given duration until the last
+// candle, it's computed from the candles of the smallest frame.
+func (r *Runner) GetQuoteVolume(d time.Duration) big.Decimal {
+	line, ok := r.GetLines(r.SmallestFrame())
+	if !ok || line == nil {
+		return big.ZERO
+	}
+	line.RLock()
+	defer line.RUnlock()
+	if line.Candles.LastCandle() == nil {
+		return big.ZERO
+	}
+	start := line.Candles.LastCandle().Period.End.Add(-d)
+	out := big.ZERO
+	for i := len(line.Candles.Candles) - 1; i >= 0; i-- {
+		c := line.Candles.Candles[i]
+		if c.Period.Start.Before(start) {
+			break
+		}
+		out = out.Add(c.Volume.Mul(c.ClosePrice))
+	}
+	return out
+}
+
 // SyncCandle aggregate the lines with the values of the given candle.
 // The syncing process will be different for different lines based on its frame.
 // The given candle's period should always be the latest candle broadcasted by
@@ -185,9 +326,60 @@ func (r *Runner) SyncCandle(c *ta.Candle) bool {
 		}
 		series.Shrink(maxSize)
 	}
+	r.lastFinal = c.Period.Start
 	return true
 }
 
+// WithPartialCandle returns a view of the runner whose lines have the given non-final candle
+// synced to them, the lines of the runner itself are left as they are. The view shares the
+// rest of the runner. It returns false if the candle isn't newer than the last final candle
+// synced to the runner.
+func (r *Runner) WithPartialCandle(c *ta.Candle) (*Runner, bool) {
+	if c == nil {
+		panic(fmt.Errorf("error syncing candle: cannle cannot be nil"))
+	}
+	if !r.lastFinal.IsZero() && !c.Period.Start.After(r.lastFinal) {
+		return nil, false
+	}
+	r.Lock()
+	book := r.book
+	r.Unlock()
+	view := &Runner{
+		name:        r.name,
+		lines:       make(map[time.Duration]*tax.Series, len(r.lines)),
+		derivatives: r.derivatives,
+		basis:       r.basis,
+		configs:     r.configs,
+		fundamental: r.fundamental,
+		book:        book,
+		trades:      r.trades,
+		lastFinal:   r.lastFinal,
+		intrabar:    atomic.LoadInt32(&r.intrabar),
+	}
+	for frame, series := range r.lines {
+		frame := frame
+		ps, ok := series.WithPartialCandle(c, &frame)
+		if !ok {
+			return nil, false
+		}
+		view.lines[frame] = ps
+	}
+	return view, true
+}
+
+// SetIntrabar sets whether the runner has signals evaluated on its partial candles, the partial
+// candles are only streamed then.
+func (r *Runner) SetIntrabar(intrabar bool) {
+	var v int32
+	if intrabar {
+		v = 1
+	}
+	atomic.StoreInt32(&r.intrabar, v)
+}
+
+// IsIntrabar returns true if the runner has signals evaluated on its partial candles.
+func (r *Runner) IsIntrabar() bool { return atomic.LoadInt32(&r.intrabar) == 1 }
+
 // SmallestFrame return the smallest time duration of the line that the runner is holding.
 func (r *Runner) SmallestFrame() time.Duration {
 	frames := r.configs.LFrames
@@ -6,6 +6,7 @@ import (
 
 	bn "github.com/adshao/go-binance/v2"
 	ta "github.com/heyphat/techan"
+	"github.com/sdcoffey/big"
 	"github.com/stretchr/testify/assert"
 
 	tax "follow.markets/internal/pkg/techanex"
@@ -233,11 +234,93 @@ func Test_Fundamental(t *testing.T) {
 	mcap := runner.GetCap()
 	assert.EqualValues(t, "0.0", mcap.FormattedString(1))
 
-	runner.SetFundamental(fundamental)
+	runner.SetFundamental(&fundamental)
 
 	mcap = runner.GetCap()
 	assert.EqualValues(t, "1.0", mcap.FormattedString(1))
 
 	float := runner.GetFloat()
 	assert.EqualValues(t, "2.0", float.FormattedString(1))
+
+	_, ok = runner.GetListingTime()
+	assert.EqualValues(t, false, ok)
+	fundamental.DateAdded = time.Unix(1499040000, 0)
+	listed, ok := runner.GetListingTime()
+	assert.EqualValues(t, true, ok)
+	assert.EqualValues(t, 1499040000, listed.Unix())
+
+	// both candles are within the last 2 minutes, only the last one within the last minute.
+	assert.EqualValues(t, "59590.4", runner.GetQuoteVolume(2*time.Minute).FormattedString(1))
+	assert.EqualValues(t, "29795.2", runner.GetQuoteVolume(time.Minute).FormattedString(1))
+}
+
+func Test_WithPartialCandle(t *testing.T) {
+	configs := &RunnerConfigs{
+		LFrames:  []time.Duration{time.Minute, 5 * time.Minute},
+		IConfigs: tax.NewDefaultIndicatorConfigs(),
+	}
+	runner := NewRunner("BTCUSDT", configs)
+	kline := &bn.Kline{OpenTime: 1499040000000, Open: "1.0", High: "1.2", Low: "0.9", Close: "1.1", Volume: "10", TradeNum: 10}
+	assert.EqualValues(t, true, runner.SyncCandle(tax.ConvertBinanceKline(kline, nil)))
+
+	// a partial candle of the last final period is stale
+	_, ok := runner.WithPartialCandle(tax.ConvertBinanceKline(kline, nil))
+	assert.EqualValues(t, false, ok)
+
+	partial := &bn.Kline{OpenTime: 1499040060000, Open: "1.1", High: "2.0", Low: "1.0", Close: "1.9", Volume: "5", TradeNum: 5}
+	view, ok := runner.WithPartialCandle(tax.ConvertBinanceKline(partial, nil))
+	assert.EqualValues(t, true, ok)
+	assert.EqualValues(t, "BTCUSDT", view.GetName())
+	assert.EqualValues(t, "1.9", view.LastCandle(time.Minute).ClosePrice.FormattedString(1))
+	assert.EqualValues(t, "15", view.LastCandle(5*time.Minute).Volume.FormattedString(0))
+
+	// the runner itself never holds the partial candle.
+	assert.EqualValues(t, "1.1", runner.LastCandle(time.Minute).ClosePrice.FormattedString(1))
+	assert.EqualValues(t, "10", runner.LastCandle(5*time.Minute).Volume.FormattedString(0))
+
+	final := &bn.Kline{OpenTime: 1499040060000, Open: "1.1", High: "1.5", Low: "1.0", Close: "1.4", Volume: "8", TradeNum: 8}
+	assert.EqualValues(t, true, runner.SyncCandle(tax.ConvertBinanceKline(final, nil)))
+	assert.EqualValues(t, "1.4", runner.LastCandle(time.Minute).ClosePrice.FormattedString(1))
+	assert.EqualValues(t, "18", runner.LastCandle(5*time.Minute).Volume.FormattedString(0))
+
+	assert.EqualValues(t, false, runner.IsIntrabar())
+	runner.SetIntrabar(true)
+	assert.EqualValues(t, true, runner.IsIntrabar())
+}
+
+func Test_Derivatives(t *testing.T) {
+	runner := NewRunner("BTCUSDT", nil)
+	_, ok := runner.GetDerivatives(time.Minute)
+	assert.EqualValues(t, false, ok)
+
+	configs := NewRunnerDefaultConfigs()
+	configs.Market = Futures
+	runner = NewRunner("BTCUSDT", configs)
+	ok = runner.SyncOpenInterest(&tax.OpenInterest{Time: time.Unix(1499040000, 0), OpenInterest: big.NewFromInt(1000)})
+	assert.EqualValues(t, true, ok)
+	ok = runner.SyncOpenInterest(&tax.OpenInterest{Time: time.Unix(1499040300, 0), OpenInterest: big.NewFromInt(1100)})
+	assert.EqualValues(t, true, ok)
+
+	ds, ok := runner.GetDerivatives(time.Minute)
+	assert.EqualValues(t, true, ok)
+	assert.EqualValues(t, 2, ds.Len())
+	ds, _ = runner.GetDerivatives(5 * time.Minute)
+	assert.EqualValues(t, 2, ds.Len())
+	ds, _ = runner.GetDerivatives(15 * time.Minute)
+	assert.EqualValues(t, 1, ds.Len())
+	assert.EqualValues(t, "1100", ds.LastDerivative().OpenInterest.FormattedString(0))
+}
+
+func Test_Basis(t *testing.T) {
+	runner := NewRunner("BTCUSDT", nil)
+	_, ok := runner.GetBasis(time.Minute)
+	assert.EqualValues(t, false, ok)
+
+	runner.SetBasis(time.Minute, tax.NewBasisSeries(0))
+	_, ok = runner.GetBasis(time.Minute)
+	assert.EqualValues(t, true, ok)
+
+	runner.ClearBasis()
+	_, ok = runner.GetBasis(time.Minute)
+	assert.EqualValues(t, false, ok)
 }
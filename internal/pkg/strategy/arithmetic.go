@@ -0,0 +1,127 @@
+package strategy
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/sdcoffey/big"
+
+	"follow.markets/internal/pkg/runner"
+	tax "follow.markets/internal/pkg/techanex"
+	"follow.markets/pkg/util"
+)
+
+type ArithmeticOperator string
+
+const (
+	Add       ArithmeticOperator = "ADD"
+	Subtract  ArithmeticOperator = "SUB"
+	Multiply  ArithmeticOperator = "MUL"
+	Divide    ArithmeticOperator = "DIV"
+	Absolute  ArithmeticOperator = "ABS"
+	Minimum   ArithmeticOperator = "MIN"
+	Maximum   ArithmeticOperator = "MAX"
+	PctChange ArithmeticOperator = "PCT_CHANGE"
+)
+
+var (
+	arithmeticOperators = []string{"ADD", "SUB", "MUL", "DIV", "ABS", "MIN", "MAX", "PCT_CHANGE"}
+
+	// the symbols of the infix operators, used in descriptions.
+	arithmeticSymbols = map[ArithmeticOperator]string{Add: " + ", Subtract: " - ", Multiply: " * ", Divide: " / "}
+)
+
+// Arithmetic combines the values of its operands. ADD, SUB, MUL, DIV apply from left to right
+// on two operands or more, MIN and MAX take two operands or more, ABS takes one operand and
+// PCT_CHANGE takes two operands, it's the change of the first over the second one in percent.
+type Arithmetic struct {
+	Opt      ArithmeticOperator `json:"opt"`
+	Operands []*Comparable      `json:"operands"`
+}
+
+func (a *Arithmetic) copy() *Arithmetic {
+	if a == nil {
+		return nil
+	}
+	var na Arithmetic
+	na.Opt = a.Opt
+	for _, o := range a.Operands {
+		na.Operands = append(na.Operands, o.copy())
+	}
+	return &na
+}
+
+func (a *Arithmetic) validate() error {
+	opt := ArithmeticOperator(strings.ToUpper(string(a.Opt)))
+	if !util.StringSliceContains(arithmeticOperators, string(opt)) {
+		return atPath(errors.New("invalid arithmetic operator"), "opt")
+	}
+	switch {
+	case opt == Absolute && len(a.Operands) != 1:
+		return atPath(errors.New("ABS takes one operand"), "operands")
+	case opt == PctChange && len(a.Operands) != 2:
+		return atPath(errors.New("PCT_CHANGE takes two operands"), "operands")
+	case opt != Absolute && len(a.Operands) < 2:
+		return atPath(errors.New("missing arithmetic operands"), "operands")
+	}
+	for i, o := range a.Operands {
+		if err := o.validate(); err != nil {
+			return atPath(err, indexPath("operands", i))
+		}
+	}
+	return nil
+}
+
+// mapArithmetic maps the operands on the bar shifted by the comparable's time frame and
+// combines their values. It fails if any operand fails to map or on a division by zero.
+func (c *Comparable) mapArithmetic(r *runner.Runner, t *tax.Trade) (string, big.Decimal, bool) {
+	opt := ArithmeticOperator(strings.ToUpper(string(c.Math.Opt)))
+	messages := make([]string, len(c.Math.Operands))
+	values := make([]big.Decimal, len(c.Math.Operands))
+	for i, o := range c.Math.Operands {
+		mess, val, ok := o.mapDecimalAt(r, t, c.TimeFrame)
+		if !ok {
+			return "", big.ZERO, false
+		}
+		messages[i], values[i] = mess, val
+	}
+	if symbol, ok := arithmeticSymbols[opt]; ok {
+		out := values[0]
+		for _, v := range values[1:] {
+			switch opt {
+			case Add:
+				out = out.Add(v)
+			case Subtract:
+				out = out.Sub(v)
+			case Multiply:
+				out = out.Mul(v)
+			case Divide:
+				if v.EQ(big.ZERO) {
+					return "", big.ZERO, false
+				}
+				out = out.Div(v)
+			}
+		}
+		return "(" + strings.Join(messages, symbol) + ")", out, true
+	}
+	mess := string(opt) + "(" + strings.Join(messages, ", ") + ")"
+	switch opt {
+	case Absolute:
+		return mess, values[0].Abs(), true
+	case Minimum, Maximum:
+		out := values[0]
+		for _, v := range values[1:] {
+			if (opt == Minimum && v.LT(out)) || (opt == Maximum && v.GT(out)) {
+				out = v
+			}
+		}
+		return mess, out, true
+	case PctChange:
+		if values[1].EQ(big.ZERO) {
+			return "", big.ZERO, false
+		}
+		return mess, values[0].Sub(values[1]).Div(values[1]).Mul(big.NewFromInt(100)), true
+	default:
+		return "", big.ZERO, false
+	}
+}
@@ -0,0 +1,62 @@
+package strategy
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Arithmetic(t *testing.T) {
+	// the open price of all candles is 1.
+	r := newTestRunner(t, "2", "4")
+
+	cases := []struct {
+		expr  string
+		value string
+		ok    bool
+	}{
+		{"(CLOSE - OPEN) / OPEN", "3.000", true},
+		{"CLOSE - OPEN - 1", "2.000", true},
+		{"-CLOSE + 1", "-3.000", true},
+		{"CLOSE * 2 + OPEN", "9.000", true},
+		{"pct_change(CLOSE, CLOSE[1])", "100.000", true},
+		{"pct_change(CLOSE, CLOSE)[1]", "0.000", true},
+		{"abs(OPEN - CLOSE)", "3.000", true},
+		{"max(OPEN, CLOSE[1], 3)", "3.000", true},
+		{"min(OPEN, CLOSE[1], 3)", "1.000", true},
+		{"CLOSE / (OPEN - 1)", "", false},
+	}
+	for _, c := range cases {
+		rule, err := CompileExpression(c.expr + " > 0")
+		assert.EqualValues(t, nil, err, c.expr)
+		if err != nil {
+			continue
+		}
+		comparable := rule.Condition.This
+		_, val, ok := comparable.mapDecimal(r, nil)
+		assert.EqualValues(t, c.ok, ok, c.expr)
+		if ok {
+			assert.EqualValues(t, c.value, val.FormattedString(3), c.expr)
+		}
+	}
+
+	raw := []byte(`{"math": {"opt": "DIV", "operands": [{"time_period": 60, "candle": {"name": "CLOSE"}}, {"constant": 4}]}}`)
+	var comparable Comparable
+	assert.EqualValues(t, nil, json.Unmarshal(raw, &comparable))
+	assert.EqualValues(t, nil, comparable.validate())
+	mess, val, ok := comparable.mapDecimal(r, nil)
+	assert.EqualValues(t, true, ok)
+	assert.EqualValues(t, "1", val.FormattedString(0))
+	assert.EqualValues(t, "(Candle: CLOSE@4.000 / 4.000)", mess)
+
+	comparable.Math.Operands = comparable.Math.Operands[:1]
+	assert.EqualValues(t, "math.operands: missing arithmetic operands", comparable.validate().Error())
+
+	rule, err := CompileExpression("(CLOSE - OPEN) / OPEN rising")
+	assert.EqualValues(t, nil, err)
+	assert.EqualValues(t, true, rule.evaluate(r, nil))
+
+	_, err = CompileExpression("abs(CLOSE, OPEN) > 1")
+	assert.EqualValues(t, "line 1, column 1: ABS takes one operand", err.Error())
+}
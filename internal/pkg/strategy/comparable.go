@@ -43,6 +43,20 @@ type Comparable struct {
 	Candle      *ComparableObject `json:"candle,omitempty"`
 	Indicator   *ComparableObject `json:"indicator,omitempty"`
 	Fundamental *ComparableObject `json:"fundamental,omitempty"`
+	Depth       *ComparableObject `json:"depth,omitempty"`
+	Trade       *ComparableObject `json:"trade,omitempty"`
+	Futures     *ComparableObject `json:"futures,omitempty"`
+	Basis       *ComparableObject `json:"basis,omitempty"`
+	Spread      *ComparableObject `json:"spread,omitempty"`
+
+	// a comparable is either one of the values above, a constant, an arithmetic combination
+	// of other comparables or the number of bars since a rule was last satisfied.
+	Constant  *float64    `json:"constant,omitempty"`
+	Math      *Arithmetic `json:"math,omitempty"`
+	BarsSince *BarsSince  `json:"bars_since,omitempty"`
+
+	// the second leg of a spread comparable, see Signal.SetLeg.
+	leg *pairLeg
 }
 
 func (c *Comparable) copy() *Comparable {
@@ -55,6 +69,15 @@ func (c *Comparable) copy() *Comparable {
 	nc.Candle = c.Candle.copy()
 	nc.Indicator = c.Indicator.copy()
 	nc.Fundamental = c.Fundamental.copy()
+	nc.Depth = c.Depth.copy()
+	nc.Trade = c.Trade.copy()
+	nc.Futures = c.Futures.copy()
+	nc.Basis = c.Basis.copy()
+	nc.Spread = c.Spread.copy()
+	nc.leg = c.leg
+	nc.Constant = c.Constant
+	nc.Math = c.Math.copy()
+	nc.BarsSince = c.BarsSince.copy()
 	return &nc
 }
 
@@ -62,42 +85,142 @@ func (c *Comparable) convertTimePeriod() time.Duration {
 	return time.Duration(c.TimePeriod) * time.Second
 }
 
+// isStreamed returns true if the comparable maps to the streamed order book or trades,
+// which are not bound to any time period.
+func (c *Comparable) isStreamed() bool {
+	return c != nil && (c.Depth != nil || c.Trade != nil)
+}
+
 func (c *Comparable) validate() error {
 	if c == nil {
 		return errors.New("comparable must not be nil")
 	}
-	if c.Candle == nil && c.Indicator == nil && c.Fundamental == nil {
+	if c.Candle == nil && c.Indicator == nil && c.Fundamental == nil && c.Depth == nil && c.Trade == nil && c.Futures == nil && c.Basis == nil && c.Spread == nil && c.Constant == nil && c.Math == nil && c.BarsSince == nil {
 		return errors.New("missing comparable values")
 	}
-	if !util.Int64SliceContains(AcceptablePeriods, int64(c.TimePeriod)) {
-		return errors.New("unknown time period")
-	}
 	if c.TimeFrame < 0 {
-		return errors.New("invalid time frame")
+		return atPath(errors.New("invalid time frame"), "time_frame")
+	}
+	if c.Constant != nil {
+		return nil
+	}
+	// the time period of an arithmetic comparable is the one of its operands.
+	if c.Math != nil {
+		return atPath(c.Math.validate(), "math")
+	}
+	if c.BarsSince != nil {
+		return atPath(c.BarsSince.validate(), "bars_since")
+	}
+	if !(c.isStreamed() && c.TimePeriod == 0) && !util.Int64SliceContains(AcceptablePeriods, int64(c.TimePeriod)) {
+		return atPath(errors.New("unknown time period"), "time_period")
 	}
 	if c.Candle != nil && !util.StringSliceContains(candleLevels, string(c.Candle.Name)) {
-		return errors.New("invalid candle level")
+		return atPath(errors.New("invalid candle level"), "candle.name")
 	}
-	if c.Indicator != nil && (!util.StringSliceContains(tax.AvailableIndicators(), string(c.Indicator.Name)) || len(c.Indicator.Config) == 0) {
-		return errors.New("invalid indicator name or config")
+	if c.Indicator != nil && !util.StringSliceContains(tax.AvailableIndicators(), string(c.Indicator.Name)) {
+		return atPath(errors.New("invalid indicator name or config"), "indicator.name")
+	}
+	if c.Indicator != nil && len(c.Indicator.Config) == 0 {
+		return atPath(errors.New("invalid indicator name or config"), "indicator.config")
 	}
 	if c.Fundamental != nil && (!util.StringSliceContains(fundamentals, string(c.Fundamental.Name))) {
-		return errors.New("invalid fundamental name")
+		return atPath(errors.New("invalid fundamental name"), "fundamental.name")
+	}
+	if c.Depth != nil && !util.StringSliceContains(depthLevels, string(c.Depth.Name)) {
+		return atPath(errors.New("invalid depth level"), "depth.name")
+	}
+	if c.Trade != nil && !util.StringSliceContains(tradeLevels, string(c.Trade.Name)) {
+		return atPath(errors.New("invalid trade level"), "trade.name")
+	}
+	if c.Futures != nil && !util.StringSliceContains(futuresLevels, string(c.Futures.Name)) {
+		return atPath(errors.New("invalid futures level"), "futures.name")
+	}
+	if c.Basis != nil && !util.StringSliceContains(basisLevels, string(c.Basis.Name)) {
+		return atPath(errors.New("invalid basis level"), "basis.name")
+	}
+	if c.Spread != nil && !util.StringSliceContains(spreadLevels, string(c.Spread.Name)) {
+		return atPath(errors.New("invalid spread level"), "spread.name")
+	}
+	if c.Trade != nil && TradeLevel(c.Trade.Name) == TradeLargePrints {
+		if _, ok := c.Trade.Config["min_value"]; !ok {
+			return atPath(errors.New("missing min_value config for large prints"), "trade.config")
+		}
 	}
 	return nil
 }
 
+// flatten returns the comparable and all the comparables of its arithmetic operands and of
+// the conditions of its bars since rule.
+func (c *Comparable) flatten() []*Comparable {
+	if c == nil {
+		return nil
+	}
+	out := []*Comparable{c}
+	if c.Math != nil {
+		for _, o := range c.Math.Operands {
+			out = append(out, o.flatten()...)
+		}
+	}
+	if c.BarsSince != nil {
+		for _, cd := range c.BarsSince.Rule.conditions() {
+			out = append(out, cd.This.flatten()...)
+			out = append(out, cd.That.flatten()...)
+		}
+	}
+	return out
+}
+
+// timePeriod returns the time period of the comparable, for an arithmetic comparable it's the
+// first time period of its operands.
+func (c *Comparable) timePeriod() time.Duration {
+	for _, fc := range c.flatten() {
+		if fc.TimePeriod > 0 {
+			return fc.convertTimePeriod()
+		}
+	}
+	return 0
+}
+
+// mapDecimalAt maps the comparable to its value the given number of bars before the last one.
+// The streamed values, order book and trades, have no history, only their fixed levels are
+// available on previous bars.
+func (c *Comparable) mapDecimalAt(r *runner.Runner, t *tax.Trade, shift int) (string, big.Decimal, bool) {
+	if shift == 0 {
+		return c.mapDecimal(r, t)
+	}
+	if (c.Depth != nil && DepthLevel(c.Depth.Name) != DepthFixed) || (c.Trade != nil && TradeLevel(c.Trade.Name) != TradeFixed) {
+		return "", big.ZERO, false
+	}
+	sc := c.copy()
+	sc.TimeFrame += shift
+	return sc.mapDecimal(r, t)
+}
+
 func (c *Comparable) mapDecimal(r *runner.Runner, t *tax.Trade) (string, big.Decimal, bool) {
 	minFloatingPoints := 3
-	//if c.Trade != nil {
-	//	val, ok := c.mapTrade(t)
-	//	val = val.Mul(c.Trade.parseMultiplier())
-	//	mess := "Trade: " + c.Trade.Name + "@" + val.FormattedString(minFloatingPoints)
-	//	return mess, val, ok
-	//}
+	if c.Constant != nil {
+		val := big.NewDecimal(*c.Constant)
+		return val.FormattedString(minFloatingPoints), val, true
+	}
+	if c.Math != nil {
+		return c.mapArithmetic(r, t)
+	}
+	if c.BarsSince != nil {
+		return c.mapBarsSince(r, t)
+	}
+	if c.Trade != nil {
+		val, ok := c.mapTrade(r, t)
+		mess := "Trade: " + c.Trade.Name + "@" + val.FormattedString(minFloatingPoints)
+		return mess, val.Mul(c.Trade.parseMultiplier()), ok
+	}
 	if r == nil {
 		return "", big.ZERO, false
 	}
+	if c.Depth != nil {
+		val, ok := c.mapDepth(r)
+		mess := "Depth: " + c.Depth.Name + "@" + val.FormattedString(minFloatingPoints)
+		return mess, val.Mul(c.Depth.parseMultiplier()), ok
+	}
 	line, ok := r.GetLines(c.convertTimePeriod())
 	if !ok || line == nil {
 		return "", big.ZERO, ok
@@ -116,6 +239,21 @@ func (c *Comparable) mapDecimal(r *runner.Runner, t *tax.Trade) (string, big.Dec
 		mess := "Indicator: " + c.Indicator.Name + "@" + val.FormattedString(minFloatingPoints)
 		return mess, val.Mul(c.Indicator.parseMultiplier()), ok
 	}
+	if c.Futures != nil {
+		val, ok := c.mapFutures(r, line)
+		mess := "Futures: " + c.Futures.Name + "@" + val.FormattedString(minFloatingPoints)
+		return mess, val.Mul(c.Futures.parseMultiplier()), ok
+	}
+	if c.Basis != nil {
+		val, ok := c.mapBasis(r, line)
+		mess := "Basis: " + c.Basis.Name + "@" + val.FormattedString(minFloatingPoints)
+		return mess, val.Mul(c.Basis.parseMultiplier()), ok
+	}
+	if c.Spread != nil {
+		val, ok := c.mapSpread(line)
+		mess := "Spread: " + c.Spread.Name + "@" + val.FormattedString(minFloatingPoints)
+		return mess, val.Mul(c.Spread.parseMultiplier()), ok
+	}
 	if c.Fundamental != nil && r != nil {
 		val, ok := c.mapFundamental(r)
 		mess := "Fundamental: " + c.Fundamental.Name + "@" + val.FormattedString(minFloatingPoints)
@@ -224,24 +362,218 @@ func (c *Comparable) mapFundamental(r *runner.Runner) (big.Decimal, bool) {
 	}
 }
 
-//func (c *Comparable) mapTrade(td *tax.Trade) (big.Decimal, bool) {
-//	if td == nil {
-//		return big.ZERO, false
-//	}
-//	switch TradeLevel(c.Trade.Name) {
-//	case TradeVolume:
-//		return td.Quantity, true
-//	case TradePrice:
-//		return td.Price, true
-//	case TradeFixed:
-//		value, ok := c.Trade.Config["level"]
-//		if !ok {
-//			return big.ZERO, false
-//		}
-//		return big.NewDecimal(value), true
-//	case TradeUSDVolume:
-//		return td.Quantity.Mul(td.Price), true
-//	default:
-//		return big.ZERO, false
-//	}
-//}
+// mapFutures maps the futures comparable to the derivative of the runner aligned with the candle
+// of the same time frame. The funding rate and the open interest change are in percent.
+func (c *Comparable) mapFutures(r *runner.Runner, line *tax.Series) (big.Decimal, bool) {
+	if FuturesLevel(c.Futures.Name) == FuturesFixed {
+		value, ok := c.Futures.Config["level"]
+		if !ok {
+			return big.ZERO, false
+		}
+		return big.NewDecimal(value), true
+	}
+	series, ok := r.GetDerivatives(c.convertTimePeriod())
+	if !ok || series == nil {
+		return big.ZERO, false
+	}
+	index := len(line.Candles.Candles) - 1 - c.TimeFrame
+	cd := line.CandleByIndex(index)
+	if cd == nil {
+		return big.ZERO, false
+	}
+	d, isExact := series.DerivativeAt(cd.Period.Start)
+	if d == nil {
+		return big.ZERO, false
+	}
+	switch FuturesLevel(c.Futures.Name) {
+	case FuturesFundingRate:
+		return d.FundingRate.Mul(big.NewFromInt(100)), true
+	case FuturesNextFundingTime:
+		return big.NewFromInt(int(d.NextFundingTime / 1000)), d.NextFundingTime > 0
+	case FuturesOpenInterest:
+		return d.OpenInterest, d.OpenInterest.GT(big.ZERO)
+	case FuturesOpenInterestUSD:
+		return d.OpenInterest.Mul(d.MarkPrice), d.OpenInterest.GT(big.ZERO) && d.MarkPrice.GT(big.ZERO)
+	case FuturesOpenInterestChange:
+		window := 1
+		if value, ok := c.Futures.Config["window"]; ok && value >= 1 {
+			window = int(value)
+		}
+		prevCandle := line.CandleByIndex(index - window)
+		if prevCandle == nil {
+			return big.ZERO, false
+		}
+		prev, _ := series.DerivativeAt(prevCandle.Period.Start)
+		if prev == nil || prev.OpenInterest.EQ(big.ZERO) {
+			return big.ZERO, false
+		}
+		return d.OpenInterest.Sub(prev.OpenInterest).Div(prev.OpenInterest).Mul(big.NewFromInt(100)), true
+	case FuturesMarkPrice:
+		return d.MarkPrice, d.MarkPrice.GT(big.ZERO)
+	case FuturesIndexPrice:
+		return d.IndexPrice, d.IndexPrice.GT(big.ZERO)
+	// liquidations only count within the exact period of the candle.
+	case FuturesLiquidationBuy:
+		if !isExact {
+			return big.ZERO, true
+		}
+		return d.LiquidationBuy, true
+	case FuturesLiquidationSell:
+		if !isExact {
+			return big.ZERO, true
+		}
+		return d.LiquidationSell, true
+	case FuturesLiquidation:
+		if !isExact {
+			return big.ZERO, true
+		}
+		return d.LiquidationBuy.Add(d.LiquidationSell), true
+	default:
+		return big.ZERO, false
+	}
+}
+
+// mapBasis maps the basis comparable to the basis between the spot and the perpetual runner
+// of the same symbol, aligned with the candle of the same time frame. All levels but the basis
+// are in percent.
+func (c *Comparable) mapBasis(r *runner.Runner, line *tax.Series) (big.Decimal, bool) {
+	if BasisLevel(c.Basis.Name) == BasisFixed {
+		value, ok := c.Basis.Config["level"]
+		if !ok {
+			return big.ZERO, false
+		}
+		return big.NewDecimal(value), true
+	}
+	series, ok := r.GetBasis(c.convertTimePeriod())
+	if !ok || series == nil {
+		return big.ZERO, false
+	}
+	cd := line.CandleByIndex(len(line.Candles.Candles) - 1 - c.TimeFrame)
+	if cd == nil {
+		return big.ZERO, false
+	}
+	b, ok := series.BasisAt(cd.Period.Start)
+	if !ok {
+		return big.ZERO, false
+	}
+	switch BasisLevel(c.Basis.Name) {
+	case BasisBasis:
+		return b.Basis, true
+	case BasisPercentage:
+		return b.BasisPercentage, true
+	case BasisAnnualized:
+		// the basis of a perpetual is only annualized with its funding rate.
+		return b.AnnualizedBasis, b.IsAnnualized()
+	case BasisPremiumIndex:
+		return b.PremiumIndex, true
+	case BasisPremiumIndexAvg:
+		return b.PremiumIndexAverage, true
+	default:
+		return big.ZERO, false
+	}
+}
+
+// mapDepth maps the depth comparable to the current state of the runner's order book.
+// The depth within and the imbalance are computed on the levels within the given
+// percent of the mid price, 1% by default.
+func (c *Comparable) mapDepth(r *runner.Runner) (big.Decimal, bool) {
+	if DepthLevel(c.Depth.Name) == DepthFixed {
+		value, ok := c.Depth.Config["level"]
+		if !ok {
+			return big.ZERO, false
+		}
+		return big.NewDecimal(value), true
+	}
+	if r == nil {
+		return big.ZERO, false
+	}
+	book, ok := r.GetOrderBook()
+	if !ok {
+		return big.ZERO, false
+	}
+	percent := defaultDepthPercent
+	if value, ok := c.Depth.Config["percent"]; ok && value > 0 {
+		percent = value
+	}
+	l1 := book.L1()
+	switch DepthLevel(c.Depth.Name) {
+	case DepthBestBid:
+		return l1.BestBid.Price, l1.BestBid.Price.GT(big.ZERO)
+	case DepthBestAsk:
+		return l1.BestAsk.Price, l1.BestAsk.Price.GT(big.ZERO)
+	case DepthMidPrice:
+		mid := book.MidPrice()
+		return mid, mid.GT(big.ZERO)
+	case DepthSpread:
+		return l1.Spread(), l1.BestBid.Price.GT(big.ZERO) && l1.BestAsk.Price.GT(big.ZERO)
+	case DepthSpreadPercentageOfBid:
+		return l1.SpreadPercentageOfBid(), l1.BestBid.Price.GT(big.ZERO) && l1.BestAsk.Price.GT(big.ZERO)
+	case DepthImbalance:
+		return book.Imbalance(percent), true
+	case DepthBid:
+		bidDepth, _ := book.DepthWithin(percent)
+		return bidDepth, true
+	case DepthAsk:
+		_, askDepth := book.DepthWithin(percent)
+		return askDepth, true
+	default:
+		return big.ZERO, false
+	}
+}
+
+// mapTrade maps the trade comparable to the given trade, or the recent trades of the runner.
+// PRICE, VOLUME and USD_VOLUME map to the last trade, VOLUME and USD_VOLUME are summed over
+// the trades within the window if it is configured. TRADE_RATE and LARGE_PRINTS are computed
+// over the trades within the window, 60 seconds by default.
+func (c *Comparable) mapTrade(r *runner.Runner, td *tax.Trade) (big.Decimal, bool) {
+	if TradeLevel(c.Trade.Name) == TradeFixed {
+		value, ok := c.Trade.Config["level"]
+		if !ok {
+			return big.ZERO, false
+		}
+		return big.NewDecimal(value), true
+	}
+	var trades *tax.Trades
+	if r != nil {
+		trades = r.GetTrades()
+	}
+	if td == nil && trades != nil {
+		td = trades.Last()
+	}
+	if td == nil {
+		return big.ZERO, false
+	}
+	window, isWindowed := defaultTradeWindow, false
+	if value, ok := c.Trade.Config["window"]; ok && value > 0 {
+		window, isWindowed = time.Duration(value)*time.Second, true
+	}
+	switch TradeLevel(c.Trade.Name) {
+	case TradePrice:
+		return td.Price, true
+	case TradeVolume:
+		if isWindowed && trades != nil {
+			volume, _ := trades.Volume(window)
+			return volume, true
+		}
+		return td.Quantity, true
+	case TradeUSDVolume:
+		if isWindowed && trades != nil {
+			_, usdVolume := trades.Volume(window)
+			return usdVolume, true
+		}
+		return td.USDVolume(), true
+	case TradeRate:
+		if trades == nil {
+			return big.ZERO, false
+		}
+		return trades.Rate(window), true
+	case TradeLargePrints:
+		minValue, ok := c.Trade.Config["min_value"]
+		if !ok || trades == nil {
+			return big.ZERO, false
+		}
+		return big.NewFromInt(trades.LargePrints(window, big.NewDecimal(minValue))), true
+	default:
+		return big.ZERO, false
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"follow.markets/internal/pkg/runner"
 	tax "follow.markets/internal/pkg/techanex"
 	bn "github.com/adshao/go-binance/v2"
+	bnc "github.com/adshao/go-binance/v2/common"
+	"github.com/sdcoffey/big"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -80,3 +82,99 @@ func Test_MapDecimal(t *testing.T) {
 	assert.EqualValues(t, "1.0", val.FormattedString(1))
 
 }
+
+func Test_MapDepth(t *testing.T) {
+	r := runner.NewRunner("BTCUSDT", nil)
+	comparable := Comparable{Depth: &ComparableObject{Name: "SPREAD_PERCENTAGE_OF_BID"}}
+	assert.EqualValues(t, nil, comparable.validate())
+
+	// no order book on the runner
+	_, _, ok := comparable.mapDecimal(r, nil)
+	assert.EqualValues(t, false, ok)
+
+	book := tax.NewOrderBook("BTCUSDT", false)
+	err := book.Snapshot(1,
+		[]bnc.PriceLevel{{Price: "100", Quantity: "3"}, {Price: "99.5", Quantity: "1"}},
+		[]bnc.PriceLevel{{Price: "101", Quantity: "1"}, {Price: "105", Quantity: "4"}},
+	)
+	assert.EqualValues(t, nil, err)
+	r.SetOrderBook(book)
+
+	_, val, ok := comparable.mapDecimal(r, nil)
+	assert.EqualValues(t, true, ok)
+	assert.EqualValues(t, "1.0", val.FormattedString(1))
+
+	comparable = Comparable{Depth: &ComparableObject{Name: "IMBALANCE", Config: map[string]float64{"percent": 1}}}
+	_, val, ok = comparable.mapDecimal(r, nil)
+	assert.EqualValues(t, true, ok)
+	assert.EqualValues(t, "0.6", val.FormattedString(1))
+}
+
+func Test_MapFutures(t *testing.T) {
+	configs := runner.NewRunnerDefaultConfigs()
+	configs.Market = runner.Futures
+	r := runner.NewRunner("BTCUSDT", configs)
+
+	start := time.Unix(1499040000, 0)
+	for i, oi := range []int64{1000, 1100} {
+		kline := &bn.Kline{OpenTime: start.Add(time.Duration(i)*time.Minute).Unix() * 1000, Open: "1", High: "1", Low: "1", Close: "1", Volume: "1", TradeNum: 1}
+		assert.EqualValues(t, true, r.SyncCandle(tax.ConvertBinanceKline(kline, nil)))
+		r.SyncOpenInterest(&tax.OpenInterest{Time: start.Add(time.Duration(i) * time.Minute), OpenInterest: big.NewFromInt(int(oi))})
+	}
+	r.SyncMarkPrice(&tax.MarkPrice{Time: start.Add(time.Minute), MarkPrice: big.NewFromInt(100), IndexPrice: big.NewFromInt(99), FundingRate: big.NewDecimal(0.0005)})
+
+	comparable := Comparable{TimePeriod: 60, Futures: &ComparableObject{Name: "FUNDING_RATE"}}
+	assert.EqualValues(t, nil, comparable.validate())
+	_, val, ok := comparable.mapDecimal(r, nil)
+	assert.EqualValues(t, true, ok)
+	assert.EqualValues(t, "0.05", val.FormattedString(2))
+
+	comparable = Comparable{TimePeriod: 60, Futures: &ComparableObject{Name: "OPEN_INTEREST_CHANGE", Config: map[string]float64{"window": 1}}}
+	_, val, ok = comparable.mapDecimal(r, nil)
+	assert.EqualValues(t, true, ok)
+	assert.EqualValues(t, "10", val.FormattedString(0))
+
+	comparable = Comparable{TimePeriod: 60, TimeFrame: 1, Futures: &ComparableObject{Name: "OPEN_INTEREST"}}
+	_, val, ok = comparable.mapDecimal(r, nil)
+	assert.EqualValues(t, true, ok)
+	assert.EqualValues(t, "1000", val.FormattedString(0))
+}
+
+func Test_MapBasis(t *testing.T) {
+	r := runner.NewRunner("BTCUSDT", nil)
+	kline := &bn.Kline{OpenTime: 1499040000000, Open: "100", High: "100", Low: "100", Close: "100", Volume: "1", TradeNum: 1}
+	spot := tax.ConvertBinanceKline(kline, nil)
+	assert.EqualValues(t, true, r.SyncCandle(spot))
+
+	comparable := Comparable{TimePeriod: 60, Basis: &ComparableObject{Name: "BASIS"}}
+	assert.EqualValues(t, nil, comparable.validate())
+	_, _, ok := comparable.mapDecimal(r, nil)
+	assert.EqualValues(t, false, ok)
+
+	kline.Close = "102"
+	bs := tax.NewBasisSeries(0)
+	bs.Sync(spot, tax.ConvertBinanceKline(kline, nil), nil)
+	r.SetBasis(time.Minute, bs)
+	_, val, ok := comparable.mapDecimal(r, nil)
+	assert.EqualValues(t, true, ok)
+	assert.EqualValues(t, "2", val.FormattedString(0))
+
+	// the basis of a perpetual is annualized from its funding, it isn't without the derivative.
+	comparable = Comparable{TimePeriod: 60, Basis: &ComparableObject{Name: "ANNUALIZED_BASIS"}}
+	_, _, ok = comparable.mapDecimal(r, nil)
+	assert.EqualValues(t, false, ok)
+	d := tax.NewDerivative(spot.Period)
+	d.FundingRate = big.NewFromString("0.0001")
+	bs.Sync(spot, tax.ConvertBinanceKline(kline, nil), d)
+	_, val, ok = comparable.mapDecimal(r, nil)
+	assert.EqualValues(t, true, ok)
+	assert.EqualValues(t, "10.95", val.FormattedString(2))
+
+	// the basis of a dated futures is annualized over the time left to expiry.
+	bs = tax.NewDatedBasisSeries(0, spot.Period.End.Add(365*24*time.Hour))
+	bs.Sync(spot, tax.ConvertBinanceKline(kline, nil), nil)
+	r.SetBasis(time.Minute, bs)
+	_, val, ok = comparable.mapDecimal(r, nil)
+	assert.EqualValues(t, true, ok)
+	assert.EqualValues(t, "2", val.FormattedString(0))
+}
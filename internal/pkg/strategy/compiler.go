@@ -0,0 +1,326 @@
+package strategy
+
+import (
+	"math"
+	"strings"
+
+	tax "follow.markets/internal/pkg/techanex"
+	"follow.markets/pkg/util"
+)
+
+const (
+	// the time period of comparables without a time frame, in second.
+	defaultExpressionPeriod = 60
+)
+
+// indicatorAliases maps the short names of the indicators in an expression to their names.
+var indicatorAliases = map[string]tax.IndicatorName{
+	"MA":    tax.MA,
+	"SMA":   tax.MA,
+	"VMA":   tax.VMA,
+	"LHMA":  tax.LHMA,
+	"OCAMA": tax.OCAMA,
+	"EMA":   tax.EMA,
+	"BBU":   tax.BBU,
+	"BBL":   tax.BBL,
+	"ATR":   tax.ATR,
+	"RSI":   tax.RSI,
+	"STO":   tax.STO,
+	"MACD":  tax.MACD,
+	"HMACD": tax.HMACD,
+	"VWAP":  tax.VWAP,
+}
+
+// negations maps the comparison operators to their negations.
+var negations = map[Operator]Operator{
+	Less:      MoreEqual,
+	MoreEqual: Less,
+	More:      LessEqual,
+	LessEqual: More,
+	Equal:     NotEqual,
+	NotEqual:  Equal,
+}
+
+// CompileExpression compiles a signal expression to a rule tree. An expression is a combination
+// of conditions with AND, OR, NOT and parentheses, e.g.
+//
+//	EMA(9)@5m > EMA(26)@5m and (RSI(14)@15m < 30 or not CLOSE@1h[1] >= 100)
+//
+// The sides of a condition are arithmetic combinations of numbers and values with +, -, *, /,
+// abs(x), min(x, y...), max(x, y...) and pct_change(x, y), e.g. (CLOSE - EMA(50)) / ATR(10) > 2.
+// Besides the comparisons, two values can be compared with crosses_above and crosses_below,
+// a value or the difference of two values with rising(n) and falling(n), e.g. CLOSE rising(3).
+//
+// Expressions are looked for on the past bars with persist(n, expr), true on each of the last
+// n bars, at_least(k, n, expr), true on k of the last n bars, and sequence(n, expr, expr...),
+// true one after another within n bars, the last one on the last bar. bars_since(expr[, n]) is
+// the number of bars since the expression was last true, looked for in the last n bars,
+// 100 by default, e.g. bars_since(CLOSE crosses_above EMA(20)) <= 3.
+//
+// Other signals are referred to by name with signal(name), true when the signal's rule is, and
+// fired(name, n), true when the signal has fired within the last n bars, see SignalReference.
+//
+// A value is written as [namespace.]NAME[(args)][@frame][[offset]]. The namespace is one of
+// candle, indicator, fundamental, depth, trade, futures, basis or spread, it can be omitted for
+// candles, indicators and fundamentals. Positional arguments are indicator windows, other arguments
+// are written as key=value and go to the config, except the multiplier. The frame defaults
+// to 1m, the offset is the number of bars back from the last one. The errors are of type
+// *ParseError.
+func CompileExpression(expr string) (*RuleNode, error) {
+	node, err := parseExpression(expr)
+	if err != nil {
+		return nil, err
+	}
+	return compileNode(node, false)
+}
+
+func newOperator(o Operator) *Operator { return &o }
+
+// compileNode compiles the parsed node, negated if the given flag is set. The negation is
+// pushed down to the conditions with De Morgan's laws, the nodes which can't be negated,
+// crossovers, slopes, temporal nodes and signal references, are kept under a NOT node. The
+// children of the same operator as their parent are merged into it.
+func compileNode(node exprNode, negated bool) (*RuleNode, error) {
+	switch n := node.(type) {
+	case *exprLogical:
+		if n.opt == notExpression {
+			return compileNode(n.children[0], !negated)
+		}
+		opt := Operator(n.opt)
+		if negated {
+			opt = map[Operator]Operator{And: Or, Or: And}[opt]
+		}
+		rule := &RuleNode{Opt: opt}
+		for _, child := range n.children {
+			c, err := compileNode(child, negated)
+			if err != nil {
+				return nil, err
+			}
+			if !c.isLeaf() && c.Opt == opt {
+				rule.Nodes = append(rule.Nodes, c.Nodes...)
+				continue
+			}
+			rule.Nodes = append(rule.Nodes, c)
+		}
+		return rule, nil
+	case *exprCondition:
+		c, err := compileCondition(n)
+		if err != nil {
+			return nil, err
+		}
+		if !negated {
+			return &RuleNode{Condition: c}, nil
+		}
+		if opt, ok := negations[*c.Opt]; ok {
+			c.Opt = &opt
+			return &RuleNode{Condition: c}, nil
+		}
+		return &RuleNode{Opt: Not, Nodes: []*RuleNode{{Condition: c}}}, nil
+	case *exprTemporal:
+		rule := &RuleNode{Opt: n.opt, K: n.k, Bars: n.bars}
+		for _, child := range n.children {
+			c, err := compileNode(child, false)
+			if err != nil {
+				return nil, err
+			}
+			rule.Nodes = append(rule.Nodes, c)
+		}
+		if negated {
+			return &RuleNode{Opt: Not, Nodes: []*RuleNode{rule}}, nil
+		}
+		return rule, nil
+	case *exprReference:
+		rule := &RuleNode{Signal: &SignalReference{Name: n.name, Bars: n.bars}}
+		if negated {
+			return &RuleNode{Opt: Not, Nodes: []*RuleNode{rule}}, nil
+		}
+		return rule, nil
+	default:
+		return nil, node.position().errorf("unknown expression")
+	}
+}
+
+func compileCondition(n *exprCondition) (*Condition, error) {
+	if n.this.literal != nil && (n.that == nil || n.that.literal != nil) {
+		return nil, n.pos.errorf("a condition must compare at least one market value")
+	}
+	c := &Condition{Opt: newOperator(n.opt), Bars: n.bars}
+	var err error
+	if c.This, err = compileOperand(n.this, n.that); err != nil {
+		return nil, err
+	}
+	if n.that != nil {
+		if c.That, err = compileOperand(n.that, n.this); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.validate(); err != nil {
+		return nil, n.pos.errorf("%s", errorMessage(err))
+	}
+	return c, nil
+}
+
+// compileOperand compiles a side of a condition to a comparable. A number compared to a value
+// is compiled to a fixed level of the same kind and time frame as the value.
+func compileOperand(op, other *exprOperand) (*Comparable, error) {
+	// numbers within computed comparables, or compared to them, are constants.
+	if op.literal != nil && (other == nil || other.isComputed()) {
+		value := *op.literal
+		return &Comparable{Constant: &value}, nil
+	}
+	if op.since != nil {
+		rule, err := compileNode(op.since, false)
+		if err != nil {
+			return nil, err
+		}
+		c := &Comparable{BarsSince: &BarsSince{Rule: rule, Bars: op.lookback}}
+		if op.offset > 0 {
+			c.TimeFrame = op.offset
+		}
+		if err := c.validate(); err != nil {
+			return nil, op.pos.errorf("%s", errorMessage(err))
+		}
+		return c, nil
+	}
+	if op.arith != "" {
+		c := &Comparable{Math: &Arithmetic{Opt: op.arith}}
+		if op.offset > 0 {
+			c.TimeFrame = op.offset
+		}
+		for _, o := range op.operands {
+			oc, err := compileOperand(o, nil)
+			if err != nil {
+				return nil, err
+			}
+			c.Math.Operands = append(c.Math.Operands, oc)
+		}
+		if err := c.validate(); err != nil {
+			return nil, op.pos.errorf("%s", errorMessage(err))
+		}
+		return c, nil
+	}
+	if op.literal != nil {
+		ref, err := compileOperand(other, nil)
+		if err != nil {
+			return nil, err
+		}
+		fixed := &ComparableObject{Name: "FIXED", Config: map[string]float64{"level": *op.literal}}
+		c := &Comparable{TimePeriod: ref.TimePeriod}
+		switch {
+		case ref.Depth != nil:
+			c.Depth = fixed
+		case ref.Trade != nil:
+			c.Trade = fixed
+		case ref.Futures != nil:
+			c.Futures = fixed
+		case ref.Basis != nil:
+			c.Basis = fixed
+		case ref.Spread != nil:
+			c.Spread = fixed
+		case ref.Fundamental != nil:
+			c.Fundamental = fixed
+		default:
+			c.Candle = fixed
+		}
+		return c, nil
+	}
+	namespace, name := strings.ToLower(op.namespace), strings.ToUpper(op.name)
+	if namespace == "" {
+		namespace = resolveNamespace(name)
+		if namespace == "" {
+			return nil, op.pos.errorf("unknown value %q", op.name)
+		}
+	}
+	levels := map[string][]string{
+		"candle":      candleLevels,
+		"fundamental": fundamentals,
+		"depth":       depthLevels,
+		"trade":       tradeLevels,
+		"futures":     futuresLevels,
+		"basis":       basisLevels,
+		"spread":      spreadLevels,
+	}
+	obj := &ComparableObject{Name: name, Config: map[string]float64{}}
+	if namespace == "indicator" {
+		indicator, ok := indicatorAliases[name]
+		if !ok && !util.StringSliceContains(tax.AvailableIndicators(), op.name) {
+			return nil, op.pos.errorf("unknown indicator %q", op.name)
+		}
+		obj.Name = op.name
+		if ok {
+			obj.Name = indicator.ToString()
+		}
+	} else if list, ok := levels[namespace]; !ok {
+		return nil, op.pos.errorf("unknown namespace %q", op.namespace)
+	} else if !util.StringSliceContains(list, name) || name == "FIXED" {
+		return nil, op.pos.errorf("unknown %s value %q", namespace, op.name)
+	}
+	positional := 0
+	for _, arg := range op.args {
+		switch {
+		case arg.key == "" && namespace != "indicator":
+			return nil, arg.pos.errorf("%s values only take key=value arguments", namespace)
+		case arg.key == "" && positional > 0:
+			return nil, arg.pos.errorf("indicators take a single window")
+		case arg.key == "" && (arg.value < 1 || arg.value != math.Trunc(arg.value)):
+			return nil, arg.pos.errorf("the window of %s must be a positive integer", op.name)
+		case arg.key == "":
+			positional++
+			obj.Config["window"] = arg.value
+		case strings.ToLower(arg.key) == "multiplier":
+			multiplier := arg.value
+			obj.Multiplier = &multiplier
+		default:
+			obj.Config[strings.ToLower(arg.key)] = arg.value
+		}
+	}
+	c := &Comparable{TimePeriod: op.frame, TimeFrame: op.offset}
+	if op.frame < 0 {
+		c.TimePeriod = defaultExpressionPeriod
+		if namespace == "depth" || namespace == "trade" {
+			c.TimePeriod = 0
+		}
+	}
+	if op.offset < 0 {
+		c.TimeFrame = 0
+	}
+	switch namespace {
+	case "candle":
+		c.Candle = obj
+	case "indicator":
+		c.Indicator = obj
+	case "fundamental":
+		c.Fundamental = obj
+	case "depth":
+		c.Depth = obj
+	case "trade":
+		c.Trade = obj
+	case "futures":
+		c.Futures = obj
+	case "basis":
+		c.Basis = obj
+	case "spread":
+		c.Spread = obj
+	}
+	if err := c.validate(); err != nil {
+		return nil, op.pos.errorf("%s", errorMessage(err))
+	}
+	return c, nil
+}
+
+// resolveNamespace returns the namespace of a name written without one, candle levels
+// come first, then indicators and fundamentals.
+func resolveNamespace(name string) string {
+	switch {
+	case name == "FIXED":
+		return ""
+	case util.StringSliceContains(candleLevels, name):
+		return "candle"
+	case indicatorAliases[name] != "":
+		return "indicator"
+	case util.StringSliceContains(fundamentals, name):
+		return "fundamental"
+	default:
+		return ""
+	}
+}
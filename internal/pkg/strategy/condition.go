@@ -2,7 +2,7 @@ package strategy
 
 import (
 	"errors"
-	"strings"
+	"strconv"
 
 	"follow.markets/internal/pkg/runner"
 	tax "follow.markets/internal/pkg/techanex"
@@ -14,29 +14,46 @@ type Condition struct {
 	That *Comparable `json:"that"`
 	Opt  *Operator   `json:"opt"`
 	Msg  *string     `json:"message"`
+
+	// the number of bars of the slope operators, 1 by default.
+	Bars int `json:"bars,omitempty"`
 }
 
 type Conditions []*Condition
 
 func (c *Condition) validate() error {
+	if c.Opt == nil {
+		return atPath(errors.New("missing operator"), "opt")
+	}
 	if err := c.This.validate(); err != nil {
-		return err
+		return atPath(err, "this")
 	}
-	if err := c.That.validate(); err != nil {
-		return err
+	// the slope operators might be applied on this side only.
+	if c.That != nil || !c.Opt.isSlope() {
+		if err := c.That.validate(); err != nil {
+			return atPath(err, "that")
+		}
 	}
-	if c.Opt == nil {
-		return errors.New("missing operator")
+	if c.Bars < 0 {
+		return atPath(errors.New("invalid number of bars"), "bars")
 	}
 	return nil
 }
 
+func (c *Condition) getBars() int {
+	if c.Bars < 1 {
+		return 1
+	}
+	return c.Bars
+}
+
 func (c *Condition) copy() *Condition {
 	var nc Condition
 	nc.This = c.This.copy()
 	nc.That = c.That.copy()
 	nc.Opt = c.Opt.copy()
 	nc.Msg = nil
+	nc.Bars = c.Bars
 	return &nc
 }
 
@@ -49,14 +66,26 @@ func (cs Conditions) copy() Conditions {
 }
 
 func (c *Condition) evaluate(r *runner.Runner, t *tax.Trade) bool {
+	return c.evaluateAt(r, t, 0)
+}
+
+// evaluateAt evaluates the condition the given number of bars before the last one. The
+// message is only kept for the last bar.
+func (c *Condition) evaluateAt(r *runner.Runner, t *tax.Trade, shift int) bool {
 	if r == nil && t == nil {
 		return false
 	}
-	thisM, thisD, ok := c.This.mapDecimal(r, t)
+	if c.Opt.isCrossover() {
+		return c.evaluateCrossover(r, t, shift)
+	}
+	if c.Opt.isSlope() {
+		return c.evaluateSlope(r, t, shift)
+	}
+	thisM, thisD, ok := c.This.mapDecimalAt(r, t, shift)
 	if !ok {
 		return ok
 	}
-	thatM, thatD, ok := c.That.mapDecimal(r, t)
+	thatM, thatD, ok := c.That.mapDecimalAt(r, t, shift)
 	if !ok {
 		return ok
 	}
@@ -72,179 +101,85 @@ func (c *Condition) evaluate(r *runner.Runner, t *tax.Trade) bool {
 	case MoreEqual:
 		valid = thisD.GTE(thatD)
 	case Equal:
-		valid = thisD.GTE(thatD)
+		valid = thisD.EQ(thatD)
 	case NotEqual:
 		valid = !thisD.Sub(thatD).EQ(big.ZERO)
 	}
-	if valid {
+	if valid && shift == 0 {
 		c.Msg = &mess
 	}
 	return valid
 }
 
-type ConditionGroup struct {
-	Conditions Conditions `json:"conditions"`
-	Opt        *Operator  `json:"opt"`
-}
-
-func (g *ConditionGroup) copy() *ConditionGroup {
-	var ng ConditionGroup
-	ng.Conditions = g.Conditions.copy()
-	ng.Opt = g.Opt.copy()
-	return &ng
-}
-
-//type ConditionsGroups []*ConditionGroup
-
-//func (gs ConditionGroups) copy() ConditionGroups {
-//	var ngs ConditionGroups
-//	for _, g := range gs {
-//		ngs = append(ngs, g.copy())
-//	}
-//	return ngs
-//}
-
-func (g *ConditionGroup) validate() error {
-	if g.Opt == nil {
-		return errors.New("missing group operator")
+// evaluateCrossover returns true if this side crossed the other side on the shifted bar.
+func (c *Condition) evaluateCrossover(r *runner.Runner, t *tax.Trade, shift int) bool {
+	thisM, thisD, ok := c.This.mapDecimalAt(r, t, shift)
+	if !ok {
+		return ok
 	}
-	if strings.ToLower(string(*g.Opt)) != strings.ToLower(string(Or)) && strings.ToLower(string(*g.Opt)) != strings.ToLower(string(And)) {
-		return errors.New("invalid group condition")
+	thatM, thatD, ok := c.That.mapDecimalAt(r, t, shift)
+	if !ok {
+		return ok
 	}
-	for _, c := range g.Conditions {
-		if err := c.validate(); err != nil {
-			return err
-		}
+	_, prevThisD, ok := c.This.mapDecimalAt(r, t, shift+1)
+	if !ok {
+		return ok
 	}
-	return nil
-}
-
-func (g *ConditionGroup) evaluate(r *runner.Runner, t *tax.Trade) bool {
-	if r == nil {
-		return false
+	_, prevThatD, ok := c.That.mapDecimalAt(r, t, shift+1)
+	if !ok {
+		return ok
 	}
-	switch *g.Opt {
-	case And:
-		for _, c := range g.Conditions {
-			if !c.evaluate(r, t) {
-				return false
-			}
-		}
-		return true
-	case Or:
-		for _, c := range g.Conditions {
-			if c.evaluate(r, t) {
-				return true
-			}
-		}
-		return false
-	default:
-		return false
+	valid := false
+	switch Operator(*c.Opt) {
+	case CrossAbove:
+		valid = prevThisD.LTE(prevThatD) && thisD.GT(thatD)
+	case CrossBelow:
+		valid = prevThisD.GTE(prevThatD) && thisD.LT(thatD)
 	}
-}
-
-type ConditionGroups struct {
-	Groups []*ConditionGroup `json:"condition_groups"`
-	Opt    *Operator         `json:"opt"`
-}
-
-func (gs *ConditionGroups) copy() *ConditionGroups {
-	var ngs ConditionGroups
-	ngs.Opt = gs.Opt.copy()
-	for _, g := range gs.Groups {
-		ngs.Groups = append(ngs.Groups, g.copy())
+	if valid && shift == 0 {
+		mess := thisM + " " + c.Opt.toString() + " " + thatM
+		c.Msg = &mess
 	}
-	return &ngs
+	return valid
 }
 
-func (gs *ConditionGroups) validate() error {
-	if gs.Opt == nil {
-		return errors.New("missing group operator")
-	}
-	if strings.ToLower(string(*gs.Opt)) != strings.ToLower(string(Or)) && strings.ToLower(string(*gs.Opt)) != strings.ToLower(string(And)) {
-		return errors.New("invalid group condition")
-	}
-	for _, g := range gs.Groups {
-		if err := g.validate(); err != nil {
-			return err
+// evaluateSlope returns true if this side, or its difference to the other side if given,
+// strictly rose or fell on each of the bars before the shifted one.
+func (c *Condition) evaluateSlope(r *runner.Runner, t *tax.Trade, shift int) bool {
+	var thisM, thatM string
+	values := make([]big.Decimal, c.getBars()+1)
+	for i := range values {
+		mess, val, ok := c.This.mapDecimalAt(r, t, shift+i)
+		if !ok {
+			return ok
 		}
-	}
-	return nil
-}
-
-func (gs *ConditionGroups) evaluate(r *runner.Runner, t *tax.Trade) bool {
-	if r == nil {
-		return false
-	}
-	switch *gs.Opt {
-	case And:
-		for _, g := range gs.Groups {
-			if !g.evaluate(r, t) {
-				return false
-			}
+		if i == 0 {
+			thisM = mess
 		}
-		return true
-	case Or:
-		for _, g := range gs.Groups {
-			if g.evaluate(r, t) {
-				return true
+		if c.That != nil {
+			mess, thatVal, ok := c.That.mapDecimalAt(r, t, shift+i)
+			if !ok {
+				return ok
+			}
+			if i == 0 {
+				thatM = mess
 			}
+			val = val.Sub(thatVal)
 		}
-		return false
-	default:
-		return false
-	}
-}
-
-type Groups struct {
-	Groups []*ConditionGroups `json:"groups"`
-	Opt    *Operator          `json:"opt"`
-}
-
-func (ggs Groups) copy() Groups {
-	var nggs Groups
-	nggs.Opt = ggs.Opt.copy()
-	for _, g := range ggs.Groups {
-		nggs.Groups = append(nggs.Groups, g.copy())
-	}
-	return nggs
-}
-
-func (ggs *Groups) validate() error {
-	if ggs.Opt == nil {
-		return errors.New("missing group operator")
-	}
-	if strings.ToLower(string(*ggs.Opt)) != strings.ToLower(string(Or)) && strings.ToLower(string(*ggs.Opt)) != strings.ToLower(string(And)) {
-		return errors.New("invalid group condition")
+		values[i] = val
 	}
-	for _, g := range ggs.Groups {
-		if err := g.validate(); err != nil {
-			return err
+	for i := 0; i < len(values)-1; i++ {
+		if (Operator(*c.Opt) == Rising && !values[i].GT(values[i+1])) || (Operator(*c.Opt) == Falling && !values[i].LT(values[i+1])) {
+			return false
 		}
 	}
-	return nil
-}
-
-func (ggs *Groups) evaluate(r *runner.Runner, t *tax.Trade) bool {
-	if r == nil {
-		return false
-	}
-	switch *ggs.Opt {
-	case And:
-		for _, g := range ggs.Groups {
-			if !g.evaluate(r, t) {
-				return false
-			}
-		}
+	if shift > 0 {
 		return true
-	case Or:
-		for _, g := range ggs.Groups {
-			if g.evaluate(r, t) {
-				return true
-			}
-		}
-		return false
-	default:
-		return false
 	}
+	mess := thisM + " " + c.Opt.toString() + " for " + strconv.Itoa(c.getBars()) + " bar(s)"
+	if c.That != nil {
+		mess += " against " + thatM
+	}
+	c.Msg = &mess
+	return true
 }
@@ -0,0 +1,81 @@
+package strategy
+
+import (
+	"testing"
+	"time"
+
+	bn "github.com/adshao/go-binance/v2"
+	"github.com/stretchr/testify/assert"
+
+	"follow.markets/internal/pkg/runner"
+	tax "follow.markets/internal/pkg/techanex"
+)
+
+func newTestRunner(t *testing.T, closes ...string) *runner.Runner {
+	r := runner.NewRunner("BTCUSDT", nil)
+	start := time.Unix(1499040000, 0)
+	for i, c := range closes {
+		kline := &bn.Kline{OpenTime: start.Add(time.Duration(i)*time.Minute).Unix() * 1000, Open: "1", High: c, Low: "0", Close: c, Volume: "1", TradeNum: 1}
+		assert.EqualValues(t, true, r.SyncCandle(tax.ConvertBinanceKline(kline, nil)))
+	}
+	return r
+}
+
+func Test_EqualCondition(t *testing.T) {
+	opt := Equal
+	c := &Condition{
+		This: &Comparable{TimePeriod: 60, Candle: &ComparableObject{Name: "CLOSE"}},
+		That: &Comparable{TimePeriod: 60, Candle: &ComparableObject{Name: "FIXED", Config: map[string]float64{"level": 5}}},
+		Opt:  &opt,
+	}
+	// the closes above the level aren't equal to it.
+	for close, expected := range map[string]bool{"4": false, "5": true, "6": false} {
+		r := runner.NewRunner("BTCUSDT", nil)
+		kline := &bn.Kline{OpenTime: 1499040000000, Open: "1", High: close, Low: "0", Close: close, Volume: "1", TradeNum: 1}
+		assert.EqualValues(t, true, r.SyncCandle(tax.ConvertBinanceKline(kline, nil)))
+		assert.EqualValues(t, expected, c.evaluate(r, nil), close)
+	}
+}
+
+func Test_CrossoverCondition(t *testing.T) {
+	rule, err := CompileExpression("CLOSE crosses_above 5")
+	assert.EqualValues(t, nil, err)
+	c := rule.Condition
+	assert.EqualValues(t, CrossAbove, *c.Opt)
+
+	assert.EqualValues(t, true, c.evaluate(newTestRunner(t, "4", "5", "6"), nil))
+	assert.EqualValues(t, "Candle: CLOSE@6.000 crosses above Candle: FIXED@5.000", *c.Msg)
+	assert.EqualValues(t, false, c.evaluate(newTestRunner(t, "4", "6", "7"), nil))
+	assert.EqualValues(t, false, c.evaluate(newTestRunner(t, "7"), nil))
+
+	rule, _ = CompileExpression("CLOSE cross_below 5")
+	c = rule.Condition
+	assert.EqualValues(t, true, c.evaluate(newTestRunner(t, "6", "4"), nil))
+}
+
+func Test_SlopeCondition(t *testing.T) {
+	rule, err := CompileExpression("CLOSE rising(2)")
+	assert.EqualValues(t, nil, err)
+	c := rule.Condition
+	assert.EqualValues(t, nil, c.validate())
+	assert.EqualValues(t, true, c.evaluate(newTestRunner(t, "1", "2", "3"), nil))
+	assert.EqualValues(t, "Candle: CLOSE@3.000 rising for 2 bar(s)", *c.Msg)
+	assert.EqualValues(t, false, c.evaluate(newTestRunner(t, "2", "1", "3"), nil))
+	assert.EqualValues(t, false, c.evaluate(newTestRunner(t, "2", "3"), nil))
+
+	// the difference between close and high is constant.
+	opt := Falling
+	c = &Condition{
+		This: &Comparable{TimePeriod: 60, Candle: &ComparableObject{Name: "CLOSE"}},
+		That: &Comparable{TimePeriod: 60, Candle: &ComparableObject{Name: "HIGH"}},
+		Opt:  &opt,
+	}
+	assert.EqualValues(t, nil, c.validate())
+	assert.EqualValues(t, false, c.evaluate(newTestRunner(t, "3", "2"), nil))
+
+	// slopes can't be negated, they are kept under a NOT node.
+	rule, err = CompileExpression("not CLOSE rising")
+	assert.EqualValues(t, nil, err)
+	assert.EqualValues(t, Not, rule.Opt)
+	assert.EqualValues(t, true, rule.evaluate(newTestRunner(t, "2", "1"), nil))
+}
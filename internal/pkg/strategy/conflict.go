@@ -0,0 +1,59 @@
+package strategy
+
+import (
+	"sort"
+	"strings"
+)
+
+const (
+	// the policies resolving the signals firing on a ticker at once.
+	AllConflict      = "ALL"      // all the signals go through
+	FirstConflict    = "FIRST"    // the first signal goes through, in the order they were added
+	PriorityConflict = "PRIORITY" // the signal of the highest priority goes through
+	CancelConflict   = "CANCEL"   // the bullish and the bearish signals cancel each other
+)
+
+// IsConflictPolicy returns true if the given policy is known, ALL is the default policy.
+func IsConflictPolicy(policy string) bool {
+	switch strings.ToUpper(policy) {
+	case "", AllConflict, FirstConflict, PriorityConflict, CancelConflict:
+		return true
+	default:
+		return false
+	}
+}
+
+// ResolveConflicts returns the signals, out of the ones firing on a ticker at once, which go
+// through under the policy, highest priority first. The ties of the PRIORITY policy go to the
+// first signal, and the signals which are neither bullish nor bearish are never cancelled.
+func ResolveConflicts(policy string, fired Signals) Signals {
+	if len(fired) == 0 {
+		return Signals{}
+	}
+	out := append(Signals{}, fired...)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Priority > out[j].Priority })
+	switch strings.ToUpper(policy) {
+	case FirstConflict:
+		return fired[:1]
+	case PriorityConflict:
+		return out[:1]
+	case CancelConflict:
+		hasBullish, hasBearish := false, false
+		for _, s := range out {
+			hasBullish = hasBullish || s.IsBullish()
+			hasBearish = hasBearish || s.IsBearish()
+		}
+		if !hasBullish || !hasBearish {
+			return out
+		}
+		kept := Signals{}
+		for _, s := range out {
+			if !s.IsBullish() && !s.IsBearish() {
+				kept = append(kept, s)
+			}
+		}
+		return kept
+	default:
+		return out
+	}
+}
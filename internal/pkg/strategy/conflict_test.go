@@ -0,0 +1,32 @@
+package strategy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ResolveConflicts(t *testing.T) {
+	bull := &Signal{Name: "bull", SignalType: BullishSignal, Priority: 1}
+	bear := &Signal{Name: "bear", SignalType: BearishSignal, Priority: 2}
+	other := &Signal{Name: "other"}
+	names := func(ss Signals) []string {
+		out := []string{}
+		for _, s := range ss {
+			out = append(out, s.Name)
+		}
+		return out
+	}
+	fired := Signals{bull, bear, other}
+
+	assert.EqualValues(t, []string{"bear", "bull", "other"}, names(ResolveConflicts(AllConflict, fired)))
+	assert.EqualValues(t, []string{"bear", "bull", "other"}, names(ResolveConflicts("", fired)))
+	assert.EqualValues(t, []string{"bull"}, names(ResolveConflicts(FirstConflict, fired)))
+	assert.EqualValues(t, []string{"bear"}, names(ResolveConflicts(PriorityConflict, fired)))
+	assert.EqualValues(t, []string{"other"}, names(ResolveConflicts(CancelConflict, fired)))
+	assert.EqualValues(t, []string{"bull", "other"}, names(ResolveConflicts(CancelConflict, Signals{other, bull})))
+	assert.EqualValues(t, []string{}, names(ResolveConflicts(PriorityConflict, nil)))
+
+	assert.EqualValues(t, true, IsConflictPolicy("priority"))
+	assert.EqualValues(t, false, IsConflictPolicy("LAST"))
+}
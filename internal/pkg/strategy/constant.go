@@ -1,5 +1,7 @@
 package strategy
 
+import "time"
+
 type CandleLevel string
 
 const (
@@ -26,10 +28,63 @@ const (
 type TradeLevel string
 
 const (
-	TradeFixed     TradeLevel = "FIXED"
-	TradePrice     TradeLevel = "PRICE"
-	TradeVolume    TradeLevel = "VOLUME"
-	TradeUSDVolume TradeLevel = "USD_VOLUME"
+	TradeFixed       TradeLevel = "FIXED"
+	TradePrice       TradeLevel = "PRICE"
+	TradeVolume      TradeLevel = "VOLUME"
+	TradeUSDVolume   TradeLevel = "USD_VOLUME"
+	TradeRate        TradeLevel = "TRADE_RATE"
+	TradeLargePrints TradeLevel = "LARGE_PRINTS"
+)
+
+type DepthLevel string
+
+const (
+	DepthFixed                 DepthLevel = "FIXED"
+	DepthBestBid               DepthLevel = "BEST_BID"
+	DepthBestAsk               DepthLevel = "BEST_ASK"
+	DepthMidPrice              DepthLevel = "MID_PRICE"
+	DepthSpread                DepthLevel = "SPREAD"
+	DepthSpreadPercentageOfBid DepthLevel = "SPREAD_PERCENTAGE_OF_BID"
+	DepthImbalance             DepthLevel = "IMBALANCE"
+	DepthBid                   DepthLevel = "BID_DEPTH"
+	DepthAsk                   DepthLevel = "ASK_DEPTH"
+)
+
+type FuturesLevel string
+
+const (
+	FuturesFixed              FuturesLevel = "FIXED"
+	FuturesFundingRate        FuturesLevel = "FUNDING_RATE"
+	FuturesNextFundingTime    FuturesLevel = "NEXT_FUNDING_TIME"
+	FuturesOpenInterest       FuturesLevel = "OPEN_INTEREST"
+	FuturesOpenInterestUSD    FuturesLevel = "OPEN_INTEREST_USD"
+	FuturesOpenInterestChange FuturesLevel = "OPEN_INTEREST_CHANGE"
+	FuturesMarkPrice          FuturesLevel = "MARK_PRICE"
+	FuturesIndexPrice         FuturesLevel = "INDEX_PRICE"
+	FuturesLiquidationBuy     FuturesLevel = "LIQUIDATION_BUY"
+	FuturesLiquidationSell    FuturesLevel = "LIQUIDATION_SELL"
+	FuturesLiquidation        FuturesLevel = "LIQUIDATION"
+)
+
+type BasisLevel string
+
+const (
+	BasisFixed           BasisLevel = "FIXED"
+	BasisBasis           BasisLevel = "BASIS"
+	BasisPercentage      BasisLevel = "BASIS_PERCENTAGE"
+	BasisAnnualized      BasisLevel = "ANNUALIZED_BASIS"
+	BasisPremiumIndex    BasisLevel = "PREMIUM_INDEX"
+	BasisPremiumIndexAvg BasisLevel = "PREMIUM_INDEX_MA"
+)
+
+type SpreadLevel string
+
+const (
+	SpreadFixed  SpreadLevel = "FIXED"
+	SpreadSpread SpreadLevel = "SPREAD"
+	SpreadMean   SpreadLevel = "MEAN"
+	SpreadStd    SpreadLevel = "STD"
+	SpreadZScore SpreadLevel = "ZSCORE"
 )
 
 type Fundamental string
@@ -52,6 +107,9 @@ const (
 
 	BullishSignal = "BULLISH"
 	BearishSignal = "BEARISH"
+
+	OnCloseEvaluation  = "ON_CLOSE"
+	IntrabarEvaluation = "INTRABAR"
 )
 
 var (
@@ -62,7 +120,24 @@ var (
 	}
 
 	tradeLevels = []string{
-		"FIXED", "USD_VOLUME", "VOLUME", "PRICE",
+		"FIXED", "USD_VOLUME", "VOLUME", "PRICE", "TRADE_RATE", "LARGE_PRINTS",
+	}
+
+	depthLevels = []string{
+		"FIXED", "BEST_BID", "BEST_ASK", "MID_PRICE", "SPREAD", "SPREAD_PERCENTAGE_OF_BID", "IMBALANCE", "BID_DEPTH", "ASK_DEPTH",
+	}
+
+	futuresLevels = []string{
+		"FIXED", "FUNDING_RATE", "NEXT_FUNDING_TIME", "OPEN_INTEREST", "OPEN_INTEREST_USD", "OPEN_INTEREST_CHANGE",
+		"MARK_PRICE", "INDEX_PRICE", "LIQUIDATION_BUY", "LIQUIDATION_SELL", "LIQUIDATION",
+	}
+
+	basisLevels = []string{
+		"FIXED", "BASIS", "BASIS_PERCENTAGE", "ANNUALIZED_BASIS", "PREMIUM_INDEX", "PREMIUM_INDEX_MA",
+	}
+
+	spreadLevels = []string{
+		"FIXED", "SPREAD", "MEAN", "STD", "ZSCORE",
 	}
 
 	fundamentals = []string{
@@ -70,4 +145,8 @@ var (
 	}
 
 	AcceptablePeriods = []int64{60, 180, 300, 900, 1800, 3600, 7200, 14400, 86400}
+
+	// default configs for the depth and trade comparables.
+	defaultDepthPercent = 1.0
+	defaultTradeWindow  = time.Minute
 )
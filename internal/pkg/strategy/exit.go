@@ -0,0 +1,346 @@
+package strategy
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/sdcoffey/big"
+
+	"follow.markets/internal/pkg/runner"
+	tax "follow.markets/internal/pkg/techanex"
+)
+
+const (
+	// the reasons to exit a position.
+	StopLossExit     = "STOP_LOSS"
+	TakeProfitExit   = "TAKE_PROFIT"
+	TrailingStopExit = "TRAILING_STOP"
+	TimeStopExit     = "TIME_STOP"
+	RuleExit         = "EXIT_RULE"
+
+	// the default window and time period of the ATR of the exit levels.
+	defaultATRWindow = 10
+	defaultATRPeriod = 60 // in second
+)
+
+// Exit defines when an open position of a signal is closed, whichever comes first: the stop
+// loss, the trailing stop, the take profit, the time stop or the exit rule. The exit rule is
+// either given as a rule or as an expression, see CompileExpression.
+type Exit struct {
+	Rule       *RuleNode  `json:"rule,omitempty"`
+	Expression string     `json:"expression,omitempty"`
+	StopLoss   *ExitLevel `json:"stop_loss,omitempty"`
+	TakeProfit *ExitLevel `json:"take_profit,omitempty"`
+	Trailing   *ExitLevel `json:"trailing,omitempty"`
+	TimeStop   int64      `json:"time_stop,omitempty"` // in second
+}
+
+// ExitLevel is a price level of an exit, either a distance from the entry price, a percentage
+// or a multiple of the ATR on entry, or a price level such as an indicator. A trailing level
+// is a distance from the best price since entry, or a price level which only moves towards
+// the position's profit.
+type ExitLevel struct {
+	Percent   float64     `json:"percent,omitempty"`
+	ATR       float64     `json:"atr,omitempty"`
+	ATRWindow int         `json:"atr_window,omitempty"`
+	ATRPeriod int         `json:"atr_period,omitempty"` // in second
+	Price     *Comparable `json:"price,omitempty"`
+}
+
+// Position is an open position of a signal, it keeps the levels of the signal's exits.
+type Position struct {
+	Side       string
+	EntryPrice big.Decimal
+	EntryTime  time.Time
+
+	// the levels fixed on entry, zero if the exit isn't set or is a price level.
+	stopLoss   big.Decimal
+	takeProfit big.Decimal
+	trailing   big.Decimal // the trailing distance
+
+	// the best price since entry and the current trailing stop.
+	best      big.Decimal
+	trailStop big.Decimal
+}
+
+// MarshalJSON encodes the exit, without the rule compiled from its expression.
+func (e Exit) MarshalJSON() ([]byte, error) {
+	type exit Exit
+	out := exit(e)
+	if len(strings.TrimSpace(e.Expression)) > 0 {
+		out.Rule = nil
+	}
+	return json.Marshal(out)
+}
+
+func (e *Exit) copy() *Exit {
+	if e == nil {
+		return nil
+	}
+	ne := *e
+	ne.Rule = e.Rule.copy()
+	ne.StopLoss = e.StopLoss.copy()
+	ne.TakeProfit = e.TakeProfit.copy()
+	ne.Trailing = e.Trailing.copy()
+	return &ne
+}
+
+// compile compiles the expression of the exit to its rule.
+func (e *Exit) compile() error {
+	if len(strings.TrimSpace(e.Expression)) == 0 {
+		return nil
+	}
+	if !e.Rule.isEmpty() {
+		return errors.New("an exit must have either a rule or an expression")
+	}
+	rule, err := CompileExpression(e.Expression)
+	if err != nil {
+		return atExpression(err, "expression")
+	}
+	e.Rule = rule
+	return nil
+}
+
+func (e *Exit) validate() error {
+	if e.Rule.isEmpty() && e.StopLoss == nil && e.TakeProfit == nil && e.Trailing == nil && e.TimeStop == 0 {
+		return errors.New("missing exit")
+	}
+	if e.Rule != nil {
+		if err := e.Rule.validate(); err != nil {
+			if len(strings.TrimSpace(e.Expression)) > 0 {
+				return atExpression(err, "expression")
+			}
+			return atPath(err, "rule")
+		}
+	}
+	for i, l := range []*ExitLevel{e.StopLoss, e.TakeProfit, e.Trailing} {
+		if l == nil {
+			continue
+		}
+		if err := l.validate(); err != nil {
+			return atPath(err, []string{"stop_loss", "take_profit", "trailing"}[i])
+		}
+	}
+	if e.TimeStop < 0 {
+		return atPath(errors.New("invalid exit time stop"), "time_stop")
+	}
+	return nil
+}
+
+// comparables returns the comparables of the exit rule and the levels.
+func (e *Exit) comparables() []*Comparable {
+	var out []*Comparable
+	for _, c := range e.Rule.conditions() {
+		out = append(out, c.This.flatten()...)
+		out = append(out, c.That.flatten()...)
+	}
+	for _, l := range []*ExitLevel{e.StopLoss, e.TakeProfit, e.Trailing} {
+		if l != nil {
+			out = append(out, l.comparable().flatten()...)
+		}
+	}
+	return out
+}
+
+func (l *ExitLevel) copy() *ExitLevel {
+	if l == nil {
+		return nil
+	}
+	nl := *l
+	nl.Price = l.Price.copy()
+	return &nl
+}
+
+func (l *ExitLevel) validate() error {
+	n := 0
+	for _, set := range []bool{l.Percent != 0, l.ATR != 0, l.Price != nil} {
+		if set {
+			n++
+		}
+	}
+	if n != 1 {
+		return errors.New("an exit level must have one of percent, atr or price")
+	}
+	if l.Percent < 0 || l.ATR < 0 || l.ATRWindow < 0 || l.ATRPeriod < 0 {
+		return errors.New("invalid exit level")
+	}
+	if l.Price != nil {
+		return atPath(l.Price.validate(), "price")
+	}
+	return nil
+}
+
+// comparable returns the comparable of the price level or the ATR, nil for a percentage.
+func (l *ExitLevel) comparable() *Comparable {
+	if l.Price != nil {
+		return l.Price
+	}
+	if l.ATR == 0 {
+		return nil
+	}
+	window, period := l.ATRWindow, l.ATRPeriod
+	if window == 0 {
+		window = defaultATRWindow
+	}
+	if period == 0 {
+		period = defaultATRPeriod
+	}
+	return &Comparable{
+		TimePeriod: period,
+		Indicator:  &ComparableObject{Name: tax.ATR.ToString(), Config: map[string]float64{"window": float64(window)}},
+	}
+}
+
+// distance returns the distance of the level from the given price, false if the level is
+// a price level or the ATR isn't available.
+func (l *ExitLevel) distance(r *runner.Runner, price big.Decimal) (big.Decimal, bool) {
+	if l == nil || l.Price != nil {
+		return big.ZERO, false
+	}
+	if l.Percent > 0 {
+		return price.Mul(big.NewDecimal(l.Percent / 100)), true
+	}
+	_, atr, ok := l.comparable().mapDecimal(r, nil)
+	if !ok || atr.LTE(big.ZERO) {
+		return big.ZERO, false
+	}
+	return atr.Mul(big.NewDecimal(l.ATR)), true
+}
+
+// HasExit returns true if the signal defines its own exits.
+func (s Signal) HasExit() bool {
+	return s.Exit != nil
+}
+
+// NewPosition opens a position of the signal on the runner, the percentage and ATR levels
+// of the exits are fixed with the entry price and the ATR on entry.
+func (s Signal) NewPosition(r *runner.Runner, side string, price big.Decimal, t time.Time) *Position {
+	p := &Position{
+		Side:       strings.ToUpper(side),
+		EntryPrice: price,
+		EntryTime:  t,
+		stopLoss:   big.ZERO,
+		takeProfit: big.ZERO,
+		trailing:   big.ZERO,
+		best:       price,
+		trailStop:  big.ZERO,
+	}
+	if s.Exit == nil {
+		return p
+	}
+	if d, ok := s.Exit.StopLoss.distance(r, price); ok {
+		p.stopLoss = p.away(price, d, false)
+	}
+	if d, ok := s.Exit.TakeProfit.distance(r, price); ok {
+		p.takeProfit = p.away(price, d, true)
+	}
+	if s.Exit.Trailing != nil && s.Exit.Trailing.ATR > 0 {
+		p.trailing, _ = s.Exit.Trailing.distance(r, price)
+	}
+	return p
+}
+
+// ShouldExit returns true and the reason if the position should be closed at the given price
+// and time. It's meant to be called on every price update of the position.
+func (s Signal) ShouldExit(r *runner.Runner, p *Position, price big.Decimal, t time.Time) (string, bool) {
+	if s.Exit == nil || p == nil || price.LTE(big.ZERO) {
+		return "", false
+	}
+	if stop, ok := p.level(r, s.Exit.StopLoss, p.stopLoss); ok && !p.isBeyond(price, stop) {
+		return StopLossExit, true
+	}
+	if p.updateTrailing(r, s.Exit.Trailing, price) && !p.isBeyond(price, p.trailStop) {
+		return TrailingStopExit, true
+	}
+	if target, ok := p.level(r, s.Exit.TakeProfit, p.takeProfit); ok && !p.isBeyond(target, price) {
+		return TakeProfitExit, true
+	}
+	if s.Exit.TimeStop > 0 && t.Sub(p.EntryTime) >= time.Duration(s.Exit.TimeStop)*time.Second {
+		return TimeStopExit, true
+	}
+	if !s.Exit.Rule.isEmpty() && s.Exit.Rule.evaluate(r, nil) {
+		return RuleExit, true
+	}
+	return "", false
+}
+
+// IsLong returns true if the position is opened with a buy order.
+func (p *Position) IsLong() bool {
+	return p.Side != "SELL"
+}
+
+// isBeyond returns true if the price is strictly beyond the level in the position's profit.
+func (p *Position) isBeyond(price, level big.Decimal) bool {
+	if p.IsLong() {
+		return price.GT(level)
+	}
+	return price.LT(level)
+}
+
+// away returns the level at the distance from the price, towards the position's profit or loss.
+func (p *Position) away(price, distance big.Decimal, isProfit bool) big.Decimal {
+	if p.IsLong() == isProfit {
+		return price.Add(distance)
+	}
+	return price.Sub(distance)
+}
+
+// level returns the fixed level, or the current value of the price level.
+func (p *Position) level(r *runner.Runner, l *ExitLevel, fixed big.Decimal) (big.Decimal, bool) {
+	if l == nil {
+		return big.ZERO, false
+	}
+	if l.Price == nil {
+		return fixed, fixed.GT(big.ZERO)
+	}
+	_, val, ok := l.Price.mapDecimal(r, nil)
+	return val, ok && val.GT(big.ZERO)
+}
+
+// updateTrailing moves the trailing stop with the best price since entry, it returns false
+// if there is no trailing stop yet.
+func (p *Position) updateTrailing(r *runner.Runner, l *ExitLevel, price big.Decimal) bool {
+	if l == nil {
+		return false
+	}
+	if p.isBeyond(price, p.best) {
+		p.best = price
+	}
+	var stop big.Decimal
+	switch {
+	case l.Price != nil:
+		val, ok := p.level(r, l, big.ZERO)
+		if !ok {
+			return p.trailStop.GT(big.ZERO)
+		}
+		stop = val
+	case l.Percent > 0:
+		stop = p.away(p.best, p.best.Mul(big.NewDecimal(l.Percent/100)), false)
+	case p.trailing.GT(big.ZERO):
+		stop = p.away(p.best, p.trailing, false)
+	default:
+		return false
+	}
+	if p.trailStop.EQ(big.ZERO) || p.isBeyond(stop, p.trailStop) {
+		p.trailStop = stop
+	}
+	return true
+}
+
+// stopDistance returns the distance from the price to the stop loss of the signal, false if
+// the signal doesn't have one.
+func (s Signal) stopDistance(r *runner.Runner, price big.Decimal) (big.Decimal, bool) {
+	if s.Exit == nil || s.Exit.StopLoss == nil {
+		return big.ZERO, false
+	}
+	if s.Exit.StopLoss.Price == nil {
+		return s.Exit.StopLoss.distance(r, price)
+	}
+	_, level, ok := s.Exit.StopLoss.Price.mapDecimal(r, nil)
+	if !ok || level.EQ(price) {
+		return big.ZERO, false
+	}
+	return level.Sub(price).Abs(), true
+}
@@ -0,0 +1,115 @@
+package strategy
+
+import (
+	"testing"
+	"time"
+
+	bn "github.com/adshao/go-binance/v2"
+	ta "github.com/heyphat/techan"
+	"github.com/sdcoffey/big"
+	"github.com/stretchr/testify/assert"
+
+	"follow.markets/internal/pkg/runner"
+	tax "follow.markets/internal/pkg/techanex"
+)
+
+func Test_Exit(t *testing.T) {
+	raw := []byte(`{
+		"name": "exit",
+		"signal_type": "BULLISH",
+		"expression": "CLOSE > 1",
+		"exit": {
+			"expression": "CLOSE < 50",
+			"stop_loss": {"percent": 5},
+			"take_profit": {"percent": 10},
+			"trailing": {"percent": 3},
+			"time_stop": 3600
+		}
+	}`)
+	signal, err := NewSignalFromBytes(raw)
+	assert.EqualValues(t, nil, err)
+	assert.EqualValues(t, true, signal.HasExit())
+	assert.EqualValues(t, false, signal.Exit.Rule.isEmpty())
+
+	r := runner.NewRunner("BTCUSDT", nil)
+	kline := &bn.Kline{OpenTime: 1499040000000, Open: "100", High: "100", Low: "100", Close: "100", Volume: "1", TradeNum: 1}
+	assert.EqualValues(t, true, r.SyncCandle(tax.ConvertBinanceKline(kline, nil)))
+
+	entry := time.Unix(1499040000, 0)
+	p := signal.NewPosition(r, "BUY", big.NewDecimal(100), entry)
+	_, ok := signal.ShouldExit(r, p, big.NewDecimal(101), entry)
+	assert.EqualValues(t, false, ok)
+	reason, ok := signal.ShouldExit(r, p, big.NewDecimal(95), entry)
+	assert.EqualValues(t, true, ok)
+	assert.EqualValues(t, StopLossExit, reason)
+
+	// the trailing stop follows the best price.
+	p = signal.NewPosition(r, "BUY", big.NewDecimal(100), entry)
+	_, ok = signal.ShouldExit(r, p, big.NewDecimal(108), entry)
+	assert.EqualValues(t, false, ok)
+	reason, ok = signal.ShouldExit(r, p, big.NewDecimal(104.5), entry)
+	assert.EqualValues(t, true, ok)
+	assert.EqualValues(t, TrailingStopExit, reason)
+
+	p = signal.NewPosition(r, "BUY", big.NewDecimal(100), entry)
+	reason, ok = signal.ShouldExit(r, p, big.NewDecimal(110), entry)
+	assert.EqualValues(t, TakeProfitExit, reason)
+	reason, ok = signal.ShouldExit(r, signal.NewPosition(r, "BUY", big.NewDecimal(100), entry), big.NewDecimal(100), entry.Add(time.Hour))
+	assert.EqualValues(t, TimeStopExit, reason)
+
+	// the levels are mirrored for a short position.
+	p = signal.NewPosition(r, "SELL", big.NewDecimal(100), entry)
+	reason, ok = signal.ShouldExit(r, p, big.NewDecimal(105), entry)
+	assert.EqualValues(t, StopLossExit, reason)
+	p = signal.NewPosition(r, "SELL", big.NewDecimal(100), entry)
+	reason, ok = signal.ShouldExit(r, p, big.NewDecimal(90), entry)
+	assert.EqualValues(t, TakeProfitExit, reason)
+
+	// the exit rule, the last close is 100.
+	signal.Exit = &Exit{Rule: &RuleNode{Condition: signal.Rule.Condition}}
+	reason, ok = signal.ShouldExit(r, signal.NewPosition(r, "BUY", big.NewDecimal(100), entry), big.NewDecimal(100), entry)
+	assert.EqualValues(t, true, ok)
+	assert.EqualValues(t, RuleExit, reason)
+
+	// the exit rule closes the position of the trading record.
+	rule := NewExitRule(*signal).SetRunner(r)
+	record := ta.NewTradingRecord()
+	assert.EqualValues(t, false, rule.IsSatisfied(0, record))
+	record.Operate(ta.Order{Side: ta.BUY, Price: big.NewDecimal(100), Amount: big.ONE, Security: "BTCUSDT", ExecutionTime: entry})
+	assert.EqualValues(t, true, rule.IsSatisfied(0, record))
+
+	// the stop and the target are hit within the last candle, though not at its close.
+	r = runner.NewRunner("BTCUSDT", nil)
+	kline = &bn.Kline{OpenTime: 1499040000000, Open: "100", High: "110", Low: "90", Close: "100", Volume: "1", TradeNum: 1}
+	assert.EqualValues(t, true, r.SyncCandle(tax.ConvertBinanceKline(kline, nil)))
+	for _, c := range []struct {
+		side      ta.OrderSide
+		exit      *Exit
+		satisfied bool
+	}{
+		{ta.BUY, &Exit{StopLoss: &ExitLevel{Percent: 5}}, true},
+		{ta.BUY, &Exit{TakeProfit: &ExitLevel{Percent: 5}}, true},
+		{ta.BUY, &Exit{StopLoss: &ExitLevel{Percent: 20}, TakeProfit: &ExitLevel{Percent: 20}}, false},
+		{ta.SELL, &Exit{StopLoss: &ExitLevel{Percent: 5}}, true},
+		{ta.SELL, &Exit{TakeProfit: &ExitLevel{Percent: 5}}, true},
+		{ta.SELL, &Exit{StopLoss: &ExitLevel{Percent: 20}, TakeProfit: &ExitLevel{Percent: 20}}, false},
+	} {
+		signal.Exit = c.exit
+		rule := NewExitRule(*signal).SetRunner(r)
+		record := ta.NewTradingRecord()
+		record.Operate(ta.Order{Side: c.side, Price: big.NewDecimal(100), Amount: big.ONE, Security: "BTCUSDT", ExecutionTime: entry})
+		assert.EqualValues(t, c.satisfied, rule.IsSatisfied(0, record))
+	}
+
+	invalid := []*Exit{
+		{},
+		{StopLoss: &ExitLevel{}},
+		{StopLoss: &ExitLevel{Percent: 1, ATR: 2}},
+		{TakeProfit: &ExitLevel{Percent: -1}},
+		{TimeStop: -1},
+	}
+	for _, e := range invalid {
+		assert.NotEqual(t, nil, e.validate())
+	}
+	assert.EqualValues(t, nil, (&Exit{Trailing: &ExitLevel{ATR: 2, ATRWindow: 10, ATRPeriod: 300}}).validate())
+}
@@ -0,0 +1,200 @@
+package strategy
+
+import (
+	"strconv"
+
+	"follow.markets/internal/pkg/runner"
+	tax "follow.markets/internal/pkg/techanex"
+)
+
+// SignalExplanation is the result of the evaluation of a signal on a runner with the outcome
+// of every rule node and condition, it tells why a signal does or doesn't trigger.
+type SignalExplanation struct {
+	Name   string       `json:"name"`
+	Ticker string       `json:"ticker,omitempty"`
+	Result bool         `json:"result"`
+	Rule   *Explanation `json:"rule"`
+
+	// the outcome of the script of a scripted signal.
+	Script *ScriptExplanation `json:"script,omitempty"`
+
+	// the stage of a staged signal, the outcomes of its reset rule and of the transitions
+	// from its stage.
+	Stage       string                   `json:"stage,omitempty"`
+	Reset       *Explanation             `json:"reset,omitempty"`
+	Transitions []*TransitionExplanation `json:"transitions,omitempty"`
+}
+
+// TransitionExplanation is the outcome of the rule of a transition of a staged signal.
+type TransitionExplanation struct {
+	From   string       `json:"from"`
+	To     string       `json:"to"`
+	Result bool         `json:"result"`
+	Rule   *Explanation `json:"rule"`
+}
+
+// Explanation is the outcome of a rule node, either of its condition or of its children.
+type Explanation struct {
+	Opt       Operator              `json:"opt,omitempty"`
+	K         int                   `json:"k,omitempty"`
+	Bars      int                   `json:"bars,omitempty"`
+	Result    bool                  `json:"result"`
+	Condition *ConditionExplanation `json:"condition,omitempty"`
+	Signal    *SignalReference      `json:"signal,omitempty"`
+	Nodes     []*Explanation        `json:"nodes,omitempty"`
+}
+
+// ConditionExplanation holds the values both sides of a condition are resolved to on the
+// last bar, a value is missing if its side can't be mapped, e.g. the runner has no data for it.
+type ConditionExplanation struct {
+	This      string   `json:"this"`
+	ThisValue *float64 `json:"this_value"`
+	Opt       string   `json:"opt"`
+	That      string   `json:"that,omitempty"`
+	ThatValue *float64 `json:"that_value,omitempty"`
+	Result    bool     `json:"result"`
+}
+
+// Explain evaluates the signal against the current status of the runner like Evaluate does,
+// but evaluates all the rule nodes and keeps their outcomes. The signal itself isn't changed.
+// A staged signal is explained from its initial stage, see ExplainStage.
+func (s *Signal) Explain(r *runner.Runner, t *tax.Trade) *SignalExplanation {
+	ns := s.copy()
+	out := &SignalExplanation{Name: ns.Name}
+	if r != nil {
+		out.Ticker = r.GetUniqueName()
+	}
+	if ns.Stages != nil {
+		return ns.explainStage(r, nil, out)
+	}
+	if ns.Script != nil {
+		ok, err := ns.Script.run(r)
+		out.Result, out.Script = ok, &ScriptExplanation{}
+		if ns.Script.Msg != nil {
+			out.Script.Message = *ns.Script.Msg
+		}
+		if err != nil {
+			out.Script.Error = err.Error()
+		}
+		return out
+	}
+	if ns.Rule == nil {
+		return out
+	}
+	out.Rule = ns.Rule.explain(r, t)
+	out.Result = ns.Evaluate(r, t)
+	return out
+}
+
+// ExplainStage explains the staged signal from the given state on the runner, the initial stage
+// if it's nil. It explains the reset rule and the rules of the transitions from the stage, the
+// result is true if the signal fires. The signal itself isn't changed.
+func (s *Signal) ExplainStage(r *runner.Runner, state *StageState) *SignalExplanation {
+	if s.Stages == nil {
+		return s.Explain(r, nil)
+	}
+	ns := s.copy()
+	out := &SignalExplanation{Name: ns.Name}
+	if r != nil {
+		out.Ticker = r.GetUniqueName()
+	}
+	return ns.explainStage(r, state, out)
+}
+
+func (s *Signal) explainStage(r *runner.Runner, state *StageState, out *SignalExplanation) *SignalExplanation {
+	out.Stage = s.Stages.initial()
+	if state != nil {
+		out.Stage = state.Name
+	}
+	if s.Stages.Reset != nil && out.Stage != s.Stages.initial() {
+		out.Reset = s.Stages.Reset.explain(r, nil)
+	}
+	for _, tr := range s.Stages.Transitions {
+		if tr.from(s.Stages) != out.Stage {
+			continue
+		}
+		rule := tr.Rule.explain(r, nil)
+		out.Transitions = append(out.Transitions, &TransitionExplanation{From: out.Stage, To: tr.To, Result: rule.Result, Rule: rule})
+	}
+	_, _, out.Result = s.Advance(r, state)
+	return out
+}
+
+func (n *RuleNode) explain(r *runner.Runner, t *tax.Trade) *Explanation {
+	out := &Explanation{Result: n.evaluate(r, t)}
+	if n.Signal != nil {
+		out.Signal = n.Signal.copy()
+		return out
+	}
+	if n.isLeaf() {
+		out.Condition = n.Condition.explain(r, t)
+		return out
+	}
+	out.Opt, out.K, out.Bars = n.Opt, n.K, n.Bars
+	for _, c := range n.Nodes {
+		out.Nodes = append(out.Nodes, c.explain(r, t))
+	}
+	return out
+}
+
+func (c *Condition) explain(r *runner.Runner, t *tax.Trade) *ConditionExplanation {
+	out := &ConditionExplanation{Result: c.evaluate(r, t)}
+	if c.Opt != nil {
+		out.Opt = c.Opt.toString()
+		if c.Opt.isSlope() {
+			out.Opt += " for " + strconv.Itoa(c.getBars()) + " bar(s)"
+		}
+	}
+	out.This, out.ThisValue = c.This.explain(r, t)
+	if c.That != nil {
+		out.That, out.ThatValue = c.That.explain(r, t)
+	}
+	return out
+}
+
+// explain returns the message and the value of the comparable on the last bar, or its label
+// and no value if it can't be mapped.
+func (c *Comparable) explain(r *runner.Runner, t *tax.Trade) (string, *float64) {
+	if c == nil {
+		return "", nil
+	}
+	if r == nil && t == nil {
+		return c.label(), nil
+	}
+	mess, val, ok := c.mapDecimal(r, t)
+	if !ok {
+		return c.label(), nil
+	}
+	f := val.Float()
+	return mess, &f
+}
+
+// label returns the kind and the name of the comparable.
+func (c *Comparable) label() string {
+	switch {
+	case c.Constant != nil:
+		return strconv.FormatFloat(*c.Constant, 'f', -1, 64)
+	case c.Math != nil:
+		return string(c.Math.Opt)
+	case c.BarsSince != nil:
+		return "BarsSince"
+	case c.Candle != nil:
+		return "Candle: " + c.Candle.Name
+	case c.Indicator != nil:
+		return "Indicator: " + c.Indicator.Name
+	case c.Fundamental != nil:
+		return "Fundamental: " + c.Fundamental.Name
+	case c.Depth != nil:
+		return "Depth: " + c.Depth.Name
+	case c.Trade != nil:
+		return "Trade: " + c.Trade.Name
+	case c.Futures != nil:
+		return "Futures: " + c.Futures.Name
+	case c.Basis != nil:
+		return "Basis: " + c.Basis.Name
+	case c.Spread != nil:
+		return "Spread: " + c.Spread.Name
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,76 @@
+package strategy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Explain(t *testing.T) {
+	signal, err := NewSignalFromBytes([]byte(`{"name":"explain","expression":"CLOSE > 5 and (CLOSE rising(2) or VOLUME > 10)"}`))
+	assert.EqualValues(t, nil, err)
+
+	exp := signal.Explain(newTestRunner(t, "3", "2", "6"), nil)
+	assert.EqualValues(t, "explain", exp.Name)
+	assert.EqualValues(t, "BTCUSDT", exp.Ticker)
+	assert.EqualValues(t, false, exp.Result)
+	assert.EqualValues(t, And, exp.Rule.Opt)
+	assert.EqualValues(t, 2, len(exp.Rule.Nodes))
+
+	first := exp.Rule.Nodes[0].Condition
+	assert.EqualValues(t, true, first.Result)
+	assert.EqualValues(t, ">", first.Opt)
+	assert.EqualValues(t, 6, *first.ThisValue)
+	assert.EqualValues(t, 5, *first.ThatValue)
+
+	// all the children are explained, even if the outcome is already known.
+	second := exp.Rule.Nodes[1]
+	assert.EqualValues(t, Or, second.Opt)
+	assert.EqualValues(t, false, second.Result)
+	assert.EqualValues(t, "rising for 2 bar(s)", second.Nodes[0].Condition.Opt)
+	assert.EqualValues(t, false, second.Nodes[0].Condition.Result)
+	assert.EqualValues(t, "", second.Nodes[0].Condition.That)
+	assert.EqualValues(t, 1, *second.Nodes[1].Condition.ThisValue)
+
+	// the values of the sides which can't be mapped are missing.
+	exp = signal.Explain(nil, nil)
+	assert.EqualValues(t, false, exp.Result)
+	assert.EqualValues(t, "Candle: CLOSE", exp.Rule.Nodes[0].Condition.This)
+	assert.EqualValues(t, (*float64)(nil), exp.Rule.Nodes[0].Condition.ThisValue)
+
+	// the signal itself isn't changed.
+	assert.EqualValues(t, true, signal.Explain(newTestRunner(t, "1", "2", "6"), nil).Result)
+	assert.EqualValues(t, (*string)(nil), signal.Rule.Nodes[0].Condition.Msg)
+}
+
+func Test_Explain_Stages(t *testing.T) {
+	signal, err := NewSignalFromBytes([]byte(`{"name": "staged", "stages": {"transitions": [
+		{"to": "ARMED", "expression": "CLOSE > 5"},
+		{"from": "ARMED", "to": "TRIGGER", "expression": "CLOSE < 5"}
+	], "reset_expression": "CLOSE > 10"}}`))
+	assert.EqualValues(t, nil, err)
+	r := newTestRunner(t, "3", "2", "6")
+
+	// the transitions from the initial stage are explained.
+	exp := signal.Explain(r, nil)
+	assert.EqualValues(t, IdleStage, exp.Stage)
+	assert.EqualValues(t, false, exp.Result)
+	assert.True(t, exp.Reset == nil)
+	assert.EqualValues(t, 1, len(exp.Transitions))
+	assert.EqualValues(t, "ARMED", exp.Transitions[0].To)
+	assert.EqualValues(t, true, exp.Transitions[0].Result)
+	assert.EqualValues(t, 6, *exp.Transitions[0].Rule.Condition.ThisValue)
+
+	// so are the ones from the current stage, with the reset rule.
+	exp = signal.ExplainStage(r, &StageState{Name: "ARMED"})
+	assert.EqualValues(t, "ARMED", exp.Stage)
+	assert.EqualValues(t, false, exp.Result)
+	assert.EqualValues(t, false, exp.Reset.Result)
+	assert.EqualValues(t, 1, len(exp.Transitions))
+	assert.EqualValues(t, "TRIGGER", exp.Transitions[0].To)
+	assert.EqualValues(t, false, exp.Transitions[0].Result)
+
+	exp = signal.ExplainStage(newTestRunner(t, "6", "4"), &StageState{Name: "ARMED"})
+	assert.EqualValues(t, true, exp.Transitions[0].Result)
+	assert.EqualValues(t, true, exp.Result)
+}
@@ -0,0 +1,744 @@
+package strategy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"follow.markets/pkg/util"
+)
+
+// ParseError is an error of a signal expression at the given line and column, both start at 1.
+type ParseError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+type position struct {
+	line   int
+	column int
+}
+
+func (p position) errorf(format string, args ...interface{}) *ParseError {
+	return &ParseError{Line: p.line, Column: p.column, Message: fmt.Sprintf(format, args...)}
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenOperator
+	tokenLParen
+	tokenRParen
+	tokenLBracket
+	tokenRBracket
+	tokenComma
+	tokenDot
+	tokenAt
+	tokenMinus
+	tokenPlus
+	tokenStar
+	tokenSlash
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value float64
+	pos   position
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of expression"
+	}
+	return strconv.Quote(t.text)
+}
+
+// comparisons maps the comparison symbols of the expression to the operators.
+var comparisons = map[string]Operator{
+	"<":  Less,
+	">":  More,
+	"<=": LessEqual,
+	">=": MoreEqual,
+	"=":  Equal,
+	"==": Equal,
+	"!=": NotEqual,
+	"<>": NotEqual,
+}
+
+// lex splits the expression into tokens.
+func lex(expr string) ([]token, error) {
+	var tokens []token
+	runes := []rune(expr)
+	pos := position{line: 1, column: 1}
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		start := pos
+		advance := func(n int) string {
+			text := string(runes[i : i+n])
+			i += n
+			pos.column += n
+			return text
+		}
+		switch {
+		case r == '\n':
+			i++
+			pos.line++
+			pos.column = 1
+		case unicode.IsSpace(r):
+			advance(1)
+		case unicode.IsLetter(r) || r == '_':
+			n := 1
+			for i+n < len(runes) && (unicode.IsLetter(runes[i+n]) || unicode.IsDigit(runes[i+n]) || runes[i+n] == '_') {
+				n++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: advance(n), pos: start})
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			n := 1
+			for i+n < len(runes) && (unicode.IsDigit(runes[i+n]) || runes[i+n] == '.') {
+				n++
+			}
+			text := advance(n)
+			value, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, start.errorf("invalid number %q", text)
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: text, value: value, pos: start})
+		case strings.ContainsRune("<>=!", r):
+			n := 1
+			if i+1 < len(runes) {
+				if _, ok := comparisons[string(runes[i:i+2])]; ok {
+					n = 2
+				}
+			}
+			text := advance(n)
+			if _, ok := comparisons[text]; !ok {
+				return nil, start.errorf("unexpected character %q", text)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: text, pos: start})
+		default:
+			kinds := map[rune]tokenKind{
+				'(': tokenLParen, ')': tokenRParen, '[': tokenLBracket, ']': tokenRBracket,
+				',': tokenComma, '.': tokenDot, '@': tokenAt, '-': tokenMinus,
+				'+': tokenPlus, '*': tokenStar, '/': tokenSlash,
+			}
+			kind, ok := kinds[r]
+			if !ok {
+				return nil, start.errorf("unexpected character %q", string(r))
+			}
+			tokens = append(tokens, token{kind: kind, text: advance(1), pos: start})
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: pos}), nil
+}
+
+// exprNode is a node of a parsed expression, either a logical node or a condition.
+type exprNode interface {
+	position() position
+}
+
+// exprLogical combines its children with either AND or OR, or negates its only child with NOT.
+type exprLogical struct {
+	pos      position
+	opt      string
+	children []exprNode
+}
+
+func (n *exprLogical) position() position { return n.pos }
+
+// exprCondition compares two operands, slopes might have no second operand.
+type exprCondition struct {
+	pos  position
+	this *exprOperand
+	that *exprOperand
+	opt  Operator
+	bars int
+}
+
+func (n *exprCondition) position() position { return n.pos }
+
+// exprTemporal evaluates its children on the past bars with PERSIST, AT_LEAST or SEQUENCE.
+type exprTemporal struct {
+	pos      position
+	opt      Operator
+	k        int
+	bars     int
+	children []exprNode
+}
+
+func (n *exprTemporal) position() position { return n.pos }
+
+// exprReference refers to another signal, currently true or fired within the number of bars.
+type exprReference struct {
+	pos  position
+	name string
+	bars int
+}
+
+func (n *exprReference) position() position { return n.pos }
+
+type exprArgument struct {
+	pos   position
+	key   string
+	value float64
+}
+
+// exprOperand is a side of a condition, either a number, a named value written as
+// [namespace.]NAME[(args)][@frame][[offset]], an arithmetic combination of operands or
+// the number of bars since an expression was last true.
+type exprOperand struct {
+	pos       position
+	literal   *float64
+	namespace string
+	name      string
+	args      []exprArgument
+	frame     int
+	offset    int
+	arith     ArithmeticOperator
+	operands  []*exprOperand
+	since     exprNode
+	lookback  int
+}
+
+// isComputed returns true if the operand is computed from other values.
+func (op *exprOperand) isComputed() bool { return op.arith != "" || op.since != nil }
+
+const (
+	notExpression       = "NOT"
+	barsSinceExpression = "BARS_SINCE"
+)
+
+type parser struct {
+	tokens []token
+	index  int
+}
+
+// parseExpression parses the given expression into a tree of logical nodes and conditions.
+// The precedence from the lowest is OR, AND and NOT, parentheses group sub expressions.
+func parseExpression(expr string) (exprNode, error) {
+	tokens, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokenEOF {
+		return nil, p.peek().pos.errorf("empty expression")
+	}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tk := p.peek(); tk.kind != tokenEOF {
+		return nil, tk.pos.errorf("unexpected %s", tk)
+	}
+	return node, nil
+}
+
+func (p *parser) peek() token { return p.tokens[p.index] }
+
+func (p *parser) next() token {
+	tk := p.tokens[p.index]
+	if tk.kind != tokenEOF {
+		p.index++
+	}
+	return tk
+}
+
+func (p *parser) expect(kind tokenKind, what string) (token, error) {
+	tk := p.next()
+	if tk.kind != kind {
+		return tk, tk.pos.errorf("expected %s, found %s", what, tk)
+	}
+	return tk, nil
+}
+
+// isKeyword returns true if the next token is the given case insensitive keyword.
+func (p *parser) isKeyword(keyword string) bool {
+	tk := p.peek()
+	return tk.kind == tokenIdent && strings.EqualFold(tk.text, keyword)
+}
+
+func (p *parser) parseOr() (exprNode, error) {
+	return p.parseLogical(string(Or), p.parseAnd)
+}
+
+func (p *parser) parseAnd() (exprNode, error) {
+	return p.parseLogical(string(And), p.parseNot)
+}
+
+func (p *parser) parseLogical(opt string, parseChild func() (exprNode, error)) (exprNode, error) {
+	first, err := parseChild()
+	if err != nil {
+		return nil, err
+	}
+	node := &exprLogical{pos: first.position(), opt: opt, children: []exprNode{first}}
+	for p.isKeyword(opt) {
+		p.next()
+		child, err := parseChild()
+		if err != nil {
+			return nil, err
+		}
+		node.children = append(node.children, child)
+	}
+	if len(node.children) == 1 {
+		return first, nil
+	}
+	return node, nil
+}
+
+func (p *parser) parseNot() (exprNode, error) {
+	if !p.isKeyword(notExpression) {
+		return p.parsePrimary()
+	}
+	tk := p.next()
+	child, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	return &exprLogical{pos: tk.pos, opt: notExpression, children: []exprNode{child}}, nil
+}
+
+// parsePrimary parses a condition or a sub expression in parentheses. Since a parenthesis
+// might also open an arithmetic operand, both are tried and the error of the one which
+// parsed further is returned if neither succeeds.
+func (p *parser) parsePrimary() (exprNode, error) {
+	if tk := p.peek(); tk.kind == tokenIdent && temporalFunctions[strings.ToUpper(tk.text)] != "" && p.tokens[p.index+1].kind == tokenLParen {
+		return p.parseTemporal()
+	}
+	if tk := p.peek(); tk.kind == tokenIdent && referenceFunctions[strings.ToUpper(tk.text)] && p.tokens[p.index+1].kind == tokenLParen {
+		return p.parseReference()
+	}
+	if p.peek().kind != tokenLParen {
+		return p.parseCondition()
+	}
+	start := p.index
+	node, condErr := p.parseCondition()
+	if condErr == nil {
+		return node, nil
+	}
+	p.index = start
+	p.next()
+	node, err := p.parseOr()
+	if err == nil {
+		_, err = p.expect(tokenRParen, "\")\"")
+	}
+	if err == nil {
+		return node, nil
+	}
+	ce, e := condErr.(*ParseError), err.(*ParseError)
+	if ce.Line > e.Line || (ce.Line == e.Line && ce.Column > e.Column) {
+		return nil, condErr
+	}
+	return nil, err
+}
+
+// temporalFunctions maps the temporal functions of the expression to the operators.
+var temporalFunctions = map[string]Operator{
+	"PERSIST":  Persist,
+	"AT_LEAST": AtLeast,
+	"SEQUENCE": Sequence,
+}
+
+// parseTemporal parses persist(n, expr), at_least(k, n, expr) or sequence(n, expr, expr...).
+func (p *parser) parseTemporal() (exprNode, error) {
+	tk := p.next()
+	p.next()
+	node := &exprTemporal{pos: tk.pos, opt: temporalFunctions[strings.ToUpper(tk.text)]}
+	if node.opt == AtLeast {
+		k, err := p.parseCount("a number of times")
+		if err != nil {
+			return nil, err
+		}
+		node.k = k
+		if _, err := p.expect(tokenComma, "\",\""); err != nil {
+			return nil, err
+		}
+	}
+	bars, err := p.parseCount("a number of bars")
+	if err != nil {
+		return nil, err
+	}
+	node.bars = bars
+	for {
+		if _, err := p.expect(tokenComma, "\",\""); err != nil {
+			return nil, err
+		}
+		child, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		node.children = append(node.children, child)
+		if node.opt != Sequence || p.peek().kind != tokenComma {
+			break
+		}
+	}
+	if _, err := p.expect(tokenRParen, "\")\""); err != nil {
+		return nil, err
+	}
+	if node.opt == Sequence && len(node.children) < 2 {
+		return nil, tk.pos.errorf("sequence takes two expressions or more")
+	}
+	if node.opt == AtLeast && node.k > node.bars {
+		return nil, tk.pos.errorf("at_least takes at most as many times as bars")
+	}
+	return node, nil
+}
+
+// referenceFunctions are the functions of the expression referring to other signals.
+var referenceFunctions = map[string]bool{
+	"SIGNAL": true,
+	"FIRED":  true,
+}
+
+// parseReference parses signal(name), true when the signal is, or fired(name, n), true when
+// the signal has fired within the last n bars.
+func (p *parser) parseReference() (exprNode, error) {
+	tk := p.next()
+	p.next()
+	name, err := p.expect(tokenIdent, "a signal name")
+	if err != nil {
+		return nil, err
+	}
+	node := &exprReference{pos: tk.pos, name: name.text}
+	if strings.ToUpper(tk.text) == "FIRED" {
+		if _, err := p.expect(tokenComma, "\",\""); err != nil {
+			return nil, err
+		}
+		if node.bars, err = p.parseCount("a number of bars"); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.expect(tokenRParen, "\")\""); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// parseCount parses a positive whole number.
+func (p *parser) parseCount(what string) (int, error) {
+	num, err := p.expect(tokenNumber, what)
+	if err != nil {
+		return 0, err
+	}
+	if num.value < 1 || num.value != float64(int(num.value)) {
+		return 0, num.pos.errorf("%s must be a positive whole number", what)
+	}
+	return int(num.value), nil
+}
+
+// crossovers maps the crossover keywords of the expression to the operators.
+var crossovers = map[string]Operator{
+	"CROSSES_ABOVE": CrossAbove,
+	"CROSS_ABOVE":   CrossAbove,
+	"CROSSES_BELOW": CrossBelow,
+	"CROSS_BELOW":   CrossBelow,
+}
+
+// slopes maps the slope keywords of the expression to the operators.
+var slopes = map[string]Operator{
+	"RISING":  Rising,
+	"FALLING": Falling,
+}
+
+// parseCondition parses a comparison, e.g. CLOSE > 10, a crossover, e.g.
+// EMA(9) crosses_above EMA(26), or a slope with an optional number of bars, e.g. CLOSE rising(3).
+func (p *parser) parseCondition() (exprNode, error) {
+	this, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	tk := p.next()
+	cond := &exprCondition{pos: this.pos, this: this}
+	switch word := strings.ToUpper(tk.text); {
+	case tk.kind == tokenOperator:
+		cond.opt = comparisons[tk.text]
+	case tk.kind == tokenIdent && crossovers[word] != "":
+		cond.opt = crossovers[word]
+	case tk.kind == tokenIdent && slopes[word] != "":
+		cond.opt = slopes[word]
+		if p.peek().kind == tokenLParen {
+			p.next()
+			bars, err := p.parseCount("a number of bars")
+			if err != nil {
+				return nil, err
+			}
+			cond.bars = bars
+			if _, err := p.expect(tokenRParen, "\")\""); err != nil {
+				return nil, err
+			}
+		}
+		return cond, nil
+	default:
+		return nil, tk.pos.errorf("expected a comparison operator, found %s", tk)
+	}
+	if cond.that, err = p.parseOperand(); err != nil {
+		return nil, err
+	}
+	return cond, nil
+}
+
+func (p *parser) parseNumber() (float64, position, error) {
+	sign, pos := 1.0, p.peek().pos
+	if p.peek().kind == tokenMinus {
+		p.next()
+		sign = -1.0
+	}
+	tk, err := p.expect(tokenNumber, "a number")
+	if err != nil {
+		return 0, pos, err
+	}
+	return sign * tk.value, pos, nil
+}
+
+// arithmeticFunctions maps the functions of the expression to the arithmetic operators.
+var arithmeticFunctions = map[string]ArithmeticOperator{
+	"ABS":        Absolute,
+	"MIN":        Minimum,
+	"MAX":        Maximum,
+	"PCT_CHANGE": PctChange,
+}
+
+// parseOperand parses a side of a condition, an arithmetic combination of numbers and values
+// where * and / bind tighter than + and -.
+func (p *parser) parseOperand() (*exprOperand, error) {
+	return p.parseArithmetic(p.parseTerm, map[tokenKind]ArithmeticOperator{tokenPlus: Add, tokenMinus: Subtract})
+}
+
+func (p *parser) parseTerm() (*exprOperand, error) {
+	return p.parseArithmetic(p.parseUnary, map[tokenKind]ArithmeticOperator{tokenStar: Multiply, tokenSlash: Divide})
+}
+
+func (p *parser) parseArithmetic(parseChild func() (*exprOperand, error), operators map[tokenKind]ArithmeticOperator) (*exprOperand, error) {
+	left, err := parseChild()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		opt, ok := operators[p.peek().kind]
+		if !ok {
+			return left, nil
+		}
+		p.next()
+		right, err := parseChild()
+		if err != nil {
+			return nil, err
+		}
+		// a chain of the same operator applies from left to right, it's kept as one node.
+		if left.arith == opt && left.offset < 0 {
+			left.operands = append(left.operands, right)
+			continue
+		}
+		left = &exprOperand{pos: left.pos, arith: opt, operands: []*exprOperand{left, right}, frame: -1, offset: -1}
+	}
+}
+
+func (p *parser) parseUnary() (*exprOperand, error) {
+	tk := p.peek()
+	if tk.kind != tokenMinus || p.tokens[p.index+1].kind == tokenNumber {
+		return p.parseAtom()
+	}
+	p.next()
+	op, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	minusOne := -1.0
+	return &exprOperand{pos: tk.pos, arith: Multiply, operands: []*exprOperand{{pos: tk.pos, literal: &minusOne}, op}, frame: -1, offset: -1}, nil
+}
+
+// parseAtom parses a number, a value, a function of operands, bars_since(expr[, n]) or an
+// operand in parentheses. Functions and parentheses might be followed by an offset which
+// applies to all their values.
+func (p *parser) parseAtom() (*exprOperand, error) {
+	tk := p.peek()
+	switch {
+	case tk.kind == tokenNumber || tk.kind == tokenMinus:
+		value, pos, err := p.parseNumber()
+		if err != nil {
+			return nil, err
+		}
+		return &exprOperand{pos: pos, literal: &value}, nil
+	case tk.kind == tokenLParen:
+		p.next()
+		op, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokenRParen, "\")\""); err != nil {
+			return nil, err
+		}
+		return op, p.parseOptionalOffset(op)
+	case tk.kind == tokenIdent && arithmeticFunctions[strings.ToUpper(tk.text)] != "" && p.tokens[p.index+1].kind == tokenLParen:
+		p.next()
+		p.next()
+		op := &exprOperand{pos: tk.pos, arith: arithmeticFunctions[strings.ToUpper(tk.text)], frame: -1, offset: -1}
+		for {
+			arg, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			op.operands = append(op.operands, arg)
+			next := p.next()
+			if next.kind == tokenRParen {
+				break
+			}
+			if next.kind != tokenComma {
+				return nil, next.pos.errorf("expected \",\" or \")\", found %s", next)
+			}
+		}
+		return op, p.parseOptionalOffset(op)
+	case tk.kind == tokenIdent && strings.EqualFold(tk.text, barsSinceExpression) && p.tokens[p.index+1].kind == tokenLParen:
+		p.next()
+		p.next()
+		op := &exprOperand{pos: tk.pos, frame: -1, offset: -1}
+		since, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		op.since = since
+		if p.peek().kind == tokenComma {
+			p.next()
+			if op.lookback, err = p.parseCount("a number of bars"); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := p.expect(tokenRParen, "\")\""); err != nil {
+			return nil, err
+		}
+		return op, p.parseOptionalOffset(op)
+	case tk.kind == tokenIdent:
+		return p.parseValue()
+	default:
+		return nil, tk.pos.errorf("expected a value, found %s", tk)
+	}
+}
+
+func (p *parser) parseOptionalOffset(op *exprOperand) error {
+	if p.peek().kind != tokenLBracket {
+		return nil
+	}
+	return p.parseOffset(op)
+}
+
+// parseOffset parses the number of bars back from the last one, written as [n].
+func (p *parser) parseOffset(op *exprOperand) error {
+	tk := p.next()
+	if op.offset >= 0 || op.literal != nil {
+		return tk.pos.errorf("duplicated offset")
+	}
+	num, err := p.expect(tokenNumber, "an offset")
+	if err != nil {
+		return err
+	}
+	if num.value != float64(int(num.value)) {
+		return num.pos.errorf("offset must be a whole number of bars")
+	}
+	op.offset = int(num.value)
+	_, err = p.expect(tokenRBracket, "\"]\"")
+	return err
+}
+
+// parseValue parses a named value written as [namespace.]NAME[(args)][@frame][[offset]].
+func (p *parser) parseValue() (*exprOperand, error) {
+	tk := p.next()
+	op := &exprOperand{pos: tk.pos, name: tk.text, frame: -1, offset: -1}
+	if p.peek().kind == tokenDot {
+		p.next()
+		name, err := p.expect(tokenIdent, "a name")
+		if err != nil {
+			return nil, err
+		}
+		op.namespace, op.name = tk.text, name.text
+	}
+	if p.peek().kind == tokenLParen {
+		args, err := p.parseArguments()
+		if err != nil {
+			return nil, err
+		}
+		op.args = args
+	}
+	for {
+		switch tk := p.peek(); tk.kind {
+		case tokenAt:
+			p.next()
+			if op.frame >= 0 {
+				return nil, tk.pos.errorf("duplicated time frame")
+			}
+			frame, err := p.parseFrame()
+			if err != nil {
+				return nil, err
+			}
+			op.frame = frame
+		case tokenLBracket:
+			if err := p.parseOffset(op); err != nil {
+				return nil, err
+			}
+		default:
+			return op, nil
+		}
+	}
+}
+
+// parseArguments parses a list of arguments, either positional numbers or key=number pairs.
+func (p *parser) parseArguments() ([]exprArgument, error) {
+	p.next()
+	var args []exprArgument
+	if p.peek().kind == tokenRParen {
+		p.next()
+		return args, nil
+	}
+	for {
+		arg := exprArgument{pos: p.peek().pos}
+		if tk := p.peek(); tk.kind == tokenIdent {
+			p.next()
+			if eq := p.next(); eq.kind != tokenOperator || eq.text != "=" {
+				return nil, eq.pos.errorf("expected \"=\" after argument %q, found %s", tk.text, eq)
+			}
+			arg.key = tk.text
+		}
+		value, _, err := p.parseNumber()
+		if err != nil {
+			return nil, err
+		}
+		arg.value = value
+		args = append(args, arg)
+		tk := p.next()
+		if tk.kind == tokenRParen {
+			return args, nil
+		}
+		if tk.kind != tokenComma {
+			return nil, tk.pos.errorf("expected \",\" or \")\", found %s", tk)
+		}
+	}
+}
+
+// frameUnits maps the units of a time frame to seconds.
+var frameUnits = map[string]int{"m": 60, "h": 3600, "d": 86400}
+
+// parseFrame parses a time frame written as a number followed by its unit, e.g. 5m, 1h or 1d,
+// and returns it in seconds.
+func (p *parser) parseFrame() (int, error) {
+	num, err := p.expect(tokenNumber, "a time frame")
+	if err != nil {
+		return 0, err
+	}
+	unit := p.next()
+	seconds, ok := frameUnits[strings.ToLower(unit.text)]
+	if unit.kind != tokenIdent || !ok || unit.pos.line != num.pos.line || unit.pos.column != num.pos.column+len(num.text) {
+		return 0, num.pos.errorf("invalid time frame, expected a number followed by m, h or d")
+	}
+	period := int(num.value * float64(seconds))
+	if !util.Int64SliceContains(AcceptablePeriods, int64(period)) {
+		return 0, num.pos.errorf("unsupported time frame %s%s", num.text, unit.text)
+	}
+	return period, nil
+}
@@ -0,0 +1,93 @@
+package strategy
+
+import (
+	"errors"
+	"testing"
+
+	bn "github.com/adshao/go-binance/v2"
+	"github.com/stretchr/testify/assert"
+
+	"follow.markets/internal/pkg/runner"
+	tax "follow.markets/internal/pkg/techanex"
+)
+
+func Test_CompileExpression(t *testing.T) {
+	rule, err := CompileExpression("EMA(9)@5m > EMA(26)@5m and RSI(14)@15m < 30")
+	assert.EqualValues(t, nil, err)
+	assert.EqualValues(t, nil, rule.validate())
+	assert.EqualValues(t, And, rule.Opt)
+	assert.EqualValues(t, 2, len(rule.Nodes))
+	c := rule.Nodes[0].Condition
+	assert.EqualValues(t, 300, c.This.TimePeriod)
+	assert.EqualValues(t, tax.EMA.ToString(), c.This.Indicator.Name)
+	assert.EqualValues(t, 26, c.That.Indicator.Config["window"])
+	c = rule.Nodes[1].Condition
+	assert.EqualValues(t, Less, *c.Opt)
+	assert.EqualValues(t, "FIXED", c.That.Candle.Name)
+	assert.EqualValues(t, 30, c.That.Candle.Config["level"])
+	assert.EqualValues(t, 900, c.That.TimePeriod)
+
+	// the negation is pushed down to the conditions.
+	rule, err = CompileExpression("not (CLOSE[1] >= 10 or depth.IMBALANCE(percent=2) < -0.5)")
+	assert.EqualValues(t, nil, err)
+	assert.EqualValues(t, And, rule.Opt)
+	c = rule.Nodes[0].Condition
+	assert.EqualValues(t, Less, *c.Opt)
+	assert.EqualValues(t, 1, c.This.TimeFrame)
+	c = rule.Nodes[1].Condition
+	assert.EqualValues(t, MoreEqual, *c.Opt)
+	assert.EqualValues(t, 0, c.This.TimePeriod)
+	assert.EqualValues(t, 2, c.This.Depth.Config["percent"])
+	assert.EqualValues(t, -0.5, c.That.Depth.Config["level"])
+
+	// nested operators of the same kind are merged.
+	rule, err = CompileExpression("CLOSE > 1 and (OPEN > 1 or (HIGH > 1 or (LOW > 1 and VOLUME > 1)))")
+	assert.EqualValues(t, nil, err)
+	assert.EqualValues(t, nil, rule.validate())
+	assert.EqualValues(t, 3, len(rule.Nodes[1].Nodes))
+	assert.EqualValues(t, And, rule.Nodes[1].Nodes[2].Opt)
+}
+
+func Test_ParseError(t *testing.T) {
+	cases := []struct {
+		expr   string
+		line   int
+		column int
+	}{
+		{"CLOSE >", 1, 8},
+		{"CLOSE > 1 and\n  EMA(9)@7m < 2", 2, 10},
+		{"CLOSE > 1 or FOO > 2", 1, 14},
+		{"(CLOSE > 1", 1, 11},
+		{"CLOSE ~ 1", 1, 7},
+		{"1 < 2", 1, 1},
+		{"trade.LARGE_PRINTS > 1", 1, 1},
+		{"EMA(0) > 1", 1, 5},
+		{"EMA(-5) > 1", 1, 5},
+		{"EMA(2.5) > 1", 1, 5},
+	}
+	for _, c := range cases {
+		_, err := CompileExpression(c.expr)
+		var perr *ParseError
+		assert.EqualValues(t, true, errors.As(err, &perr), c.expr)
+		if perr != nil {
+			assert.EqualValues(t, c.line, perr.Line, c.expr)
+			assert.EqualValues(t, c.column, perr.Column, c.expr)
+		}
+	}
+}
+
+func Test_SignalExpression(t *testing.T) {
+	raw := []byte(`{"name": "expression", "signal_type": "BULLISH", "expression": "CLOSE > OPEN and VOLUME >= 100"}`)
+	signal, err := NewSignalFromBytes(raw)
+	assert.EqualValues(t, nil, err)
+	assert.EqualValues(t, 60, signal.TimePeriod.Seconds())
+
+	r := runner.NewRunner("BTCUSDT", nil)
+	kline := &bn.Kline{OpenTime: 1499040000000, Open: "1.0", High: "1.2", Low: "0.9", Close: "1.1", Volume: "150", TradeNum: 10}
+	assert.EqualValues(t, true, r.SyncCandle(tax.ConvertBinanceKline(kline, nil)))
+	assert.EqualValues(t, true, signal.Evaluate(r, nil))
+
+	raw = []byte(`{"name": "expression", "expression": "CLOSE >", "rule": {"opt": "AND", "groups": []}}`)
+	_, err = NewSignalFromBytes(raw)
+	assert.EqualValues(t, "expression: line 1, column 8: expected a value, found end of expression", err.Error())
+}
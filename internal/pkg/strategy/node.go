@@ -0,0 +1,217 @@
+package strategy
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"follow.markets/internal/pkg/runner"
+	tax "follow.markets/internal/pkg/techanex"
+)
+
+// RuleNode is a node of a signal rule. A leaf node holds a condition, the other nodes combine
+// their children with AND, OR, NOT, which negates its only child, or K_OF_N, which is
+// satisfied when at least K of its children are. The temporal nodes, PERSIST, AT_LEAST and
+// SEQUENCE, look through the given number of past bars, see evaluateTemporal. A leaf node
+// can also refer to another signal, see SignalReference.
+type RuleNode struct {
+	Opt       Operator         `json:"opt,omitempty"`
+	K         int              `json:"k,omitempty"`
+	Bars      int              `json:"bars,omitempty"`
+	Nodes     []*RuleNode      `json:"nodes,omitempty"`
+	Condition *Condition       `json:"condition,omitempty"`
+	Signal    *SignalReference `json:"signal,omitempty"`
+
+	// the JSON paths of the children of a decoded node, see ValidationError.
+	decoded bool
+	paths   []string
+}
+
+// UnmarshalJSON decodes a rule node. The legacy rules, with the three levels of groups,
+// condition groups and conditions, are decoded into nodes of the same operators.
+func (n *RuleNode) UnmarshalJSON(data []byte) error {
+	type node RuleNode
+	var aux struct {
+		node
+		Groups          []*RuleNode  `json:"groups"`
+		ConditionGroups []*RuleNode  `json:"condition_groups"`
+		Conditions      []*Condition `json:"conditions"`
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	*n = RuleNode(aux.node)
+	n.Opt = Operator(strings.ToUpper(string(n.Opt)))
+	n.decoded = true
+	for i := range n.Nodes {
+		n.paths = append(n.paths, indexPath("nodes", i))
+	}
+	for i, g := range aux.Groups {
+		n.Nodes = append(n.Nodes, g)
+		n.paths = append(n.paths, indexPath("groups", i))
+	}
+	for i, g := range aux.ConditionGroups {
+		n.Nodes = append(n.Nodes, g)
+		n.paths = append(n.paths, indexPath("condition_groups", i))
+	}
+	// the legacy conditions are the conditions of their nodes, at the paths of the nodes.
+	for i, c := range aux.Conditions {
+		n.Nodes = append(n.Nodes, &RuleNode{Condition: c})
+		n.paths = append(n.paths, indexPath("conditions", i))
+	}
+	return nil
+}
+
+// childPath returns the JSON path of the child of the given index, relative to the node.
+func (n *RuleNode) childPath(i int) string {
+	if i < len(n.paths) {
+		return n.paths[i]
+	}
+	return ""
+}
+
+// fieldPath returns the JSON path of the field of a decoded node, relative to the node.
+func (n *RuleNode) fieldPath(name string) string {
+	if n.decoded {
+		return name
+	}
+	return ""
+}
+
+// isLeaf returns true if the node holds a condition or a signal reference.
+func (n *RuleNode) isLeaf() bool { return n.Condition != nil || n.Signal != nil }
+
+// isEmpty returns true if the node has neither a condition, a signal reference nor children.
+func (n *RuleNode) isEmpty() bool { return n == nil || (!n.isLeaf() && len(n.Nodes) == 0) }
+
+func (n *RuleNode) copy() *RuleNode {
+	if n == nil {
+		return nil
+	}
+	var nn RuleNode
+	nn.Opt = n.Opt
+	nn.K = n.K
+	nn.Bars = n.Bars
+	nn.decoded = n.decoded
+	nn.paths = n.paths
+	if n.Condition != nil {
+		nn.Condition = n.Condition.copy()
+	}
+	nn.Signal = n.Signal.copy()
+	for _, c := range n.Nodes {
+		nn.Nodes = append(nn.Nodes, c.copy())
+	}
+	return &nn
+}
+
+func (n *RuleNode) validate() error {
+	if n == nil {
+		return errors.New("rule node must not be nil")
+	}
+	if n.isLeaf() {
+		if len(n.Nodes) > 0 {
+			return errors.New("a condition node must not have children")
+		}
+		if n.Signal != nil {
+			if n.Condition != nil {
+				return errors.New("a node must have either a condition or a signal reference")
+			}
+			return atPath(n.Signal.validate(), n.fieldPath("signal"))
+		}
+		return atPath(n.Condition.validate(), n.fieldPath("condition"))
+	}
+	switch n.Opt {
+	case And, Or:
+		if len(n.Nodes) == 0 {
+			return errors.New("missing rule nodes")
+		}
+	case Not:
+		if len(n.Nodes) != 1 {
+			return errors.New("NOT takes one rule node")
+		}
+	case KOfN:
+		if n.K < 1 || n.K > len(n.Nodes) {
+			return atPath(errors.New("K of K_OF_N must be between 1 and the number of rule nodes"), n.fieldPath("k"))
+		}
+	case Persist, AtLeast, Sequence:
+		if err := n.validateTemporal(); err != nil {
+			return err
+		}
+	case "":
+		return atPath(errors.New("missing group operator"), n.fieldPath("opt"))
+	default:
+		return atPath(errors.New("invalid group condition"), n.fieldPath("opt"))
+	}
+	for i, c := range n.Nodes {
+		if err := c.validate(); err != nil {
+			return atPath(err, n.childPath(i))
+		}
+	}
+	return nil
+}
+
+func (n *RuleNode) evaluate(r *runner.Runner, t *tax.Trade) bool {
+	return n.evaluateAt(r, t, 0)
+}
+
+// evaluateAt evaluates the node the given number of bars before the last one.
+func (n *RuleNode) evaluateAt(r *runner.Runner, t *tax.Trade, shift int) bool {
+	if r == nil && t == nil {
+		return false
+	}
+	if n.Signal != nil {
+		return n.Signal.evaluateAt(r, t, shift)
+	}
+	if n.isLeaf() {
+		return n.Condition.evaluateAt(r, t, shift)
+	}
+	if n.Opt.isTemporal() {
+		return n.evaluateTemporal(r, t, shift)
+	}
+	switch n.Opt {
+	case And:
+		for _, c := range n.Nodes {
+			if !c.evaluateAt(r, t, shift) {
+				return false
+			}
+		}
+		return len(n.Nodes) > 0
+	case Or:
+		for _, c := range n.Nodes {
+			if c.evaluateAt(r, t, shift) {
+				return true
+			}
+		}
+		return false
+	case Not:
+		return len(n.Nodes) == 1 && !n.Nodes[0].evaluateAt(r, t, shift)
+	case KOfN:
+		count := 0
+		for i, c := range n.Nodes {
+			if c.evaluateAt(r, t, shift) {
+				count++
+			}
+			if count >= n.K || count+len(n.Nodes)-1-i < n.K {
+				break
+			}
+		}
+		return n.K > 0 && count >= n.K
+	default:
+		return false
+	}
+}
+
+// conditions returns all the conditions of the node and its children.
+func (n *RuleNode) conditions() Conditions {
+	if n == nil {
+		return nil
+	}
+	if n.Condition != nil {
+		return Conditions{n.Condition}
+	}
+	var out Conditions
+	for _, c := range n.Nodes {
+		out = append(out, c.conditions()...)
+	}
+	return out
+}
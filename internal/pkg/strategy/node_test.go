@@ -0,0 +1,62 @@
+package strategy
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LegacyRule(t *testing.T) {
+	// the signals with the groups at the top level.
+	raw, err := ioutil.ReadFile("../../../configs/signals/signal.json")
+	assert.EqualValues(t, nil, err)
+	signal, err := NewSignalFromBytes(raw)
+	assert.EqualValues(t, nil, err)
+	assert.EqualValues(t, And, signal.Rule.Opt)
+	assert.EqualValues(t, Or, signal.Rule.Nodes[0].Opt)
+	assert.EqualValues(t, true, signal.Rule.Nodes[0].Nodes[0].Nodes[0].isLeaf())
+
+	// the signals with the groups under the rule.
+	raw, err = ioutil.ReadFile("./signals/signal.json")
+	assert.EqualValues(t, nil, err)
+	signal, err = NewSignalFromBytes(raw)
+	assert.EqualValues(t, nil, err)
+	assert.EqualValues(t, 2, len(signal.Rule.Nodes[0].Nodes))
+	assert.EqualValues(t, true, signal.Evaluate(newTestRunner(t, "1", "2"), nil))
+}
+
+func Test_RuleNode(t *testing.T) {
+	raw := []byte(`{
+		"opt": "k_of_n",
+		"k": 2,
+		"nodes": [
+			{"condition": {"opt": "MORE", "this": {"time_period": 60, "candle": {"name": "CLOSE"}}, "that": {"constant": 1}}},
+			{"opt": "NOT", "nodes": [
+				{"condition": {"opt": "MORE", "this": {"time_period": 60, "candle": {"name": "CLOSE"}}, "that": {"constant": 5}}}
+			]},
+			{"opt": "OR", "nodes": [
+				{"condition": {"opt": "LESS", "this": {"time_period": 60, "candle": {"name": "CLOSE"}}, "that": {"constant": 0}}}
+			]}
+		]
+	}`)
+	var rule RuleNode
+	assert.EqualValues(t, nil, json.Unmarshal(raw, &rule))
+	assert.EqualValues(t, nil, rule.validate())
+	assert.EqualValues(t, KOfN, rule.Opt)
+	assert.EqualValues(t, 3, len(rule.conditions()))
+
+	assert.EqualValues(t, true, rule.evaluate(newTestRunner(t, "3"), nil))
+	assert.EqualValues(t, false, rule.evaluate(newTestRunner(t, "6"), nil))
+
+	rule.K = 4
+	assert.EqualValues(t, "k: K of K_OF_N must be between 1 and the number of rule nodes", rule.validate().Error())
+	rule.K = 2
+	rule.Nodes[1].Nodes = append(rule.Nodes[1].Nodes, rule.Nodes[0])
+	assert.EqualValues(t, "nodes[1]: NOT takes one rule node", rule.validate().Error())
+
+	nr := rule.copy()
+	nr.Nodes[0].Condition.This.Candle.Name = "OPEN"
+	assert.EqualValues(t, "CLOSE", rule.Nodes[0].Condition.This.Candle.Name)
+}
@@ -10,8 +10,22 @@ const (
 	MoreEqual Operator = "MORE_EQUAL"
 	NotEqual  Operator = "NOT_EQUAL"
 
-	Or  Operator = "OR"
-	And Operator = "AND"
+	// crossovers compare the current and the previous values of both sides, slopes compare
+	// the values of the last bars.
+	CrossAbove Operator = "CROSS_ABOVE"
+	CrossBelow Operator = "CROSS_BELOW"
+	Rising     Operator = "RISING"
+	Falling    Operator = "FALLING"
+
+	Or   Operator = "OR"
+	And  Operator = "AND"
+	Not  Operator = "NOT"
+	KOfN Operator = "K_OF_N"
+
+	// temporal operators evaluate their children on the past bars.
+	Persist  Operator = "PERSIST"
+	AtLeast  Operator = "AT_LEAST"
+	Sequence Operator = "SEQUENCE"
 )
 
 func (o *Operator) toString() string {
@@ -28,11 +42,29 @@ func (o *Operator) toString() string {
 		return ">="
 	case NotEqual:
 		return "<>"
+	case CrossAbove:
+		return "crosses above"
+	case CrossBelow:
+		return "crosses below"
+	case Rising:
+		return "rising"
+	case Falling:
+		return "falling"
 	default:
 		return "UNKNOWN"
 	}
 }
 
+// isCrossover returns true if the operator compares the current and previous values of both sides.
+func (o *Operator) isCrossover() bool {
+	return *o == CrossAbove || *o == CrossBelow
+}
+
+// isSlope returns true if the operator compares the values of the last bars.
+func (o *Operator) isSlope() bool {
+	return *o == Rising || *o == Falling
+}
+
 func (o *Operator) copy() *Operator {
 	var no Operator
 	no = *o
@@ -0,0 +1,232 @@
+package strategy
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	ta "github.com/heyphat/techan"
+	"github.com/sdcoffey/big"
+
+	"follow.markets/internal/pkg/runner"
+	tax "follow.markets/internal/pkg/techanex"
+)
+
+const (
+	// the methods to compute the spread between the runner and the second leg.
+	RatioSpread      = "RATIO"
+	DifferenceSpread = "DIFFERENCE"
+
+	// the default number of bars of the spread's mean, standard deviation and z-score.
+	defaultSpreadWindow = 20
+)
+
+// Pair makes the signal a pairs signal, its spread conditions are evaluated on the spread
+// between the runner it's evaluated on, the first leg, and the runner of the given unique name,
+// the second leg. The spread is either the price ratio of the legs or the difference of the
+// first leg and the second leg times the hedge ratio, both on the close prices of the candles
+// of the same period. A triggered pairs signal opens the first leg on the signal's side and the
+// second leg on the other side, the short leg must be on futures to be traded.
+type Pair struct {
+	Leg        string  `json:"leg"`
+	Method     string  `json:"method,omitempty"`      // RATIO by default
+	HedgeRatio float64 `json:"hedge_ratio,omitempty"` // 1 by default
+	Window     int     `json:"window,omitempty"`      // in bars
+}
+
+// pairLeg binds the spread comparables of a signal to the runner of its second leg.
+type pairLeg struct {
+	pair   *Pair
+	runner *runner.Runner
+}
+
+func (p *Pair) copy() *Pair {
+	if p == nil {
+		return nil
+	}
+	np := *p
+	return &np
+}
+
+func (p *Pair) validate() error {
+	if len(strings.TrimSpace(p.Leg)) == 0 {
+		return errors.New("missing pair leg")
+	}
+	if m := strings.ToUpper(p.Method); m != "" && m != RatioSpread && m != DifferenceSpread {
+		return errors.New("unknown pair spread method")
+	}
+	if p.HedgeRatio < 0 {
+		return errors.New("invalid pair hedge ratio")
+	}
+	if p.Window < 0 || p.Window == 1 {
+		return errors.New("a pair window must have at least 2 bars")
+	}
+	return nil
+}
+
+func (p *Pair) hedgeRatio() big.Decimal {
+	if p.HedgeRatio == 0 {
+		return big.ONE
+	}
+	return big.NewDecimal(p.HedgeRatio)
+}
+
+func (p *Pair) window() int {
+	if p.Window == 0 {
+		return defaultSpreadWindow
+	}
+	return p.Window
+}
+
+// spread returns the spread between the prices of the first and the second leg.
+func (p *Pair) spread(first, second big.Decimal) (big.Decimal, bool) {
+	hedged := second.Mul(p.hedgeRatio())
+	if strings.ToUpper(p.Method) == DifferenceSpread {
+		return first.Sub(hedged), true
+	}
+	if hedged.EQ(big.ZERO) {
+		return big.ZERO, false
+	}
+	return first.Div(hedged), true
+}
+
+// LegQuantity returns the quantity of the second leg hedging the given quantity of the first
+// leg, the same value for a ratio and the hedge ratio times the quantity for a difference.
+func (p *Pair) LegQuantity(qty, price, legPrice big.Decimal) (big.Decimal, bool) {
+	if strings.ToUpper(p.Method) == DifferenceSpread {
+		return qty.Mul(p.hedgeRatio()), qty.GT(big.ZERO)
+	}
+	if legPrice.LTE(big.ZERO) {
+		return big.ZERO, false
+	}
+	legQty := qty.Mul(price).Mul(p.hedgeRatio()).Div(legPrice)
+	return legQty, legQty.GT(big.ZERO)
+}
+
+// String returns a text description of the pair.
+func (p *Pair) String() string {
+	if p == nil {
+		return ""
+	}
+	method := strings.ToUpper(p.Method)
+	if method == "" {
+		method = RatioSpread
+	}
+	return fmt.Sprintf("%s %s x%s over %d bars", p.Leg, method, p.hedgeRatio().FormattedString(2), p.window())
+}
+
+// IsPair returns true if the signal is evaluated on the spread with a second leg.
+func (s Signal) IsPair() bool {
+	return s.Pair != nil
+}
+
+// SetLeg binds the spread conditions of the signal to the runner of its second leg, they
+// aren't satisfied until it's set. It's meant to be called on a copy of the signal before
+// each evaluation.
+func (s *Signal) SetLeg(leg *runner.Runner) {
+	if s.Pair == nil {
+		return
+	}
+	for _, c := range s.comparables() {
+		if c != nil && c.Spread != nil {
+			c.leg = &pairLeg{pair: s.Pair, runner: leg}
+		}
+	}
+}
+
+// needsPair returns true if the signal has conditions on the spread.
+func (s Signal) needsPair() bool {
+	for _, c := range s.comparables() {
+		if c != nil && c.Spread != nil && SpreadLevel(c.Spread.Name) != SpreadFixed {
+			return true
+		}
+	}
+	return false
+}
+
+// mapSpread maps the spread comparable to the spread with the second leg at the candle of the
+// same time frame. The mean, the standard deviation and the z-score are computed over the
+// window of the pair, or the window of the config if it's given.
+func (c *Comparable) mapSpread(line *tax.Series) (big.Decimal, bool) {
+	if SpreadLevel(c.Spread.Name) == SpreadFixed {
+		value, ok := c.Spread.Config["level"]
+		if !ok {
+			return big.ZERO, false
+		}
+		return big.NewDecimal(value), true
+	}
+	if c.leg == nil || c.leg.runner == nil {
+		return big.ZERO, false
+	}
+	legLine, ok := c.leg.runner.GetLines(c.convertTimePeriod())
+	if !ok || legLine == nil {
+		return big.ZERO, false
+	}
+	index := len(line.Candles.Candles) - 1 - c.TimeFrame
+	if SpreadLevel(c.Spread.Name) == SpreadSpread {
+		return c.leg.spreadAt(line, legLine, index)
+	}
+	window := c.leg.pair.window()
+	if value, ok := c.Spread.Config["window"]; ok && value >= 2 {
+		window = int(value)
+	}
+	values := make([]float64, 0, window)
+	for i := index - window + 1; i <= index; i++ {
+		val, ok := c.leg.spreadAt(line, legLine, i)
+		if !ok {
+			return big.ZERO, false
+		}
+		values = append(values, val.Float())
+	}
+	var mean, variance float64
+	for _, v := range values {
+		mean += v / float64(len(values))
+	}
+	for _, v := range values {
+		variance += (v - mean) * (v - mean) / float64(len(values))
+	}
+	std := math.Sqrt(variance)
+	switch SpreadLevel(c.Spread.Name) {
+	case SpreadMean:
+		return big.NewDecimal(mean), true
+	case SpreadStd:
+		return big.NewDecimal(std), true
+	case SpreadZScore:
+		if std == 0 {
+			return big.ZERO, false
+		}
+		return big.NewDecimal((values[len(values)-1] - mean) / std), true
+	default:
+		return big.ZERO, false
+	}
+}
+
+// spreadAt returns the spread at the candle of the given index of the first leg's line, with
+// the candle of the second leg starting at the same time.
+func (l *pairLeg) spreadAt(line, legLine *tax.Series, index int) (big.Decimal, bool) {
+	cd := line.CandleByIndex(index)
+	if cd == nil {
+		return big.ZERO, false
+	}
+	legCd := candleAt(legLine, cd.Period.Start)
+	if legCd == nil {
+		return big.ZERO, false
+	}
+	return l.pair.spread(cd.ClosePrice, legCd.ClosePrice)
+}
+
+// candleAt returns the candle of the line starting at the given time, nil if there is none.
+func candleAt(line *tax.Series, start time.Time) *ta.Candle {
+	for i := len(line.Candles.Candles) - 1; i >= 0; i-- {
+		cd := line.Candles.Candles[i]
+		if cd.Period.Start.Equal(start) {
+			return cd
+		}
+		if cd.Period.Start.Before(start) {
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package strategy
+
+import (
+	"testing"
+
+	"github.com/sdcoffey/big"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Pair(t *testing.T) {
+	first := newTestRunner(t, "10", "10", "10", "12")
+	second := newTestRunner(t, "5", "5", "5", "5")
+
+	signal, err := NewSignalFromBytes([]byte(`{"name": "pair", "expression": "spread.ZSCORE(window=4) > 1.5"}`))
+	assert.EqualValues(t, "spread conditions need a pair", err.Error())
+	assert.True(t, signal == nil)
+
+	signal, err = NewSignalFromBytes([]byte(`{"name": "pair", "expression": "spread.ZSCORE(window=4) > 1.5", "pair": {"leg": "ETHUSDT"}}`))
+	assert.EqualValues(t, nil, err)
+	assert.EqualValues(t, true, signal.IsPair())
+
+	// the spread isn't available until the second leg is set.
+	assert.EqualValues(t, false, signal.Evaluate(first, nil))
+	ns := signal.copy()
+	ns.SetLeg(second)
+	assert.EqualValues(t, true, ns.Evaluate(first, nil))
+	assert.EqualValues(t, false, signal.Evaluate(first, nil))
+
+	c := &Comparable{TimePeriod: 60, Spread: &ComparableObject{Name: "SPREAD"}, leg: &pairLeg{pair: signal.Pair, runner: second}}
+	_, val, ok := c.mapDecimal(first, nil)
+	assert.EqualValues(t, true, ok)
+	assert.EqualValues(t, "2.4", val.FormattedString(1))
+
+	c.Spread = &ComparableObject{Name: "ZSCORE", Config: map[string]float64{"window": 4}}
+	_, val, ok = c.mapDecimal(first, nil)
+	assert.EqualValues(t, true, ok)
+	assert.EqualValues(t, "1.732", val.FormattedString(3))
+
+	// the window can't go beyond the history.
+	c.Spread.Config["window"] = 5
+	_, _, ok = c.mapDecimal(first, nil)
+	assert.EqualValues(t, false, ok)
+
+	c.leg.pair = &Pair{Leg: "ETHUSDT", Method: DifferenceSpread, HedgeRatio: 2}
+	c.Spread = &ComparableObject{Name: "SPREAD"}
+	_, val, ok = c.mapDecimal(first, nil)
+	assert.EqualValues(t, true, ok)
+	assert.EqualValues(t, "2", val.FormattedString(0))
+
+	qty, ok := c.leg.pair.LegQuantity(big.ONE, big.ONE, big.ONE)
+	assert.EqualValues(t, true, ok)
+	assert.EqualValues(t, "2", qty.FormattedString(0))
+}
@@ -0,0 +1,168 @@
+package strategy
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"follow.markets/internal/pkg/runner"
+	tax "follow.markets/internal/pkg/techanex"
+)
+
+// SignalRegistry resolves the signals referenced by rule nodes and knows when they last fired
+// on a runner, as the start of the bar they fired on, see Signal.LastBar.
+type SignalRegistry interface {
+	GetSignal(name string) (*Signal, bool)
+	LastFired(name string, r *runner.Runner) (time.Time, bool)
+}
+
+// SignalReference refers to another signal by name. Without bars, it's satisfied when the
+// rule of the signal is currently true on the runner, the signal must then have a rule, or an
+// expression, as the outcome of a staged or scripted signal depends on more than the runner.
+// With bars, it's satisfied when the signal, of any kind, has fired on the runner within the
+// last bars of its time period. A reference is never satisfied until the signal holding it is
+// bound to a registry, the backtests don't have one.
+type SignalReference struct {
+	Name string `json:"name"`
+	Bars int    `json:"bars,omitempty"`
+
+	registry SignalRegistry
+}
+
+func (sr *SignalReference) copy() *SignalReference {
+	if sr == nil {
+		return nil
+	}
+	nsr := *sr
+	return &nsr
+}
+
+func (sr *SignalReference) validate() error {
+	if len(strings.TrimSpace(sr.Name)) == 0 {
+		return atPath(errors.New("missing referenced signal name"), "name")
+	}
+	if sr.Bars < 0 {
+		return atPath(errors.New("invalid referenced signal bars"), "bars")
+	}
+	return nil
+}
+
+// evaluateAt evaluates the reference the given number of bars before the last one. The firing
+// history is looked up from the start of that bar, a bar being the time period of the
+// referenced signal, or the smallest frame of the runner if it's unknown.
+func (sr *SignalReference) evaluateAt(r *runner.Runner, t *tax.Trade, shift int) bool {
+	if sr.registry == nil {
+		return false
+	}
+	s, ok := sr.registry.GetSignal(sr.Name)
+	if !ok {
+		return false
+	}
+	if sr.Bars == 0 {
+		return s.Rule != nil && s.Rule.evaluateAt(r, t, shift)
+	}
+	if r == nil {
+		return false
+	}
+	fired, ok := sr.registry.LastFired(sr.Name, r)
+	if !ok {
+		return false
+	}
+	period := s.period(r)
+	bar, ok := barAt(r, period, shift)
+	if !ok {
+		return false
+	}
+	return fired.After(bar.Add(-time.Duration(sr.Bars)*period)) && !fired.After(bar)
+}
+
+// bind binds the references of the node and its children to the registry.
+func (n *RuleNode) bind(registry SignalRegistry) {
+	if n == nil {
+		return
+	}
+	if n.Signal != nil {
+		n.Signal.registry = registry
+	}
+	for _, c := range n.Nodes {
+		c.bind(registry)
+	}
+}
+
+// references returns the signals referenced by the node and its children.
+func (n *RuleNode) references() []*SignalReference {
+	if n == nil {
+		return nil
+	}
+	var out []*SignalReference
+	if n.Signal != nil {
+		out = append(out, n.Signal)
+	}
+	for _, c := range n.Nodes {
+		out = append(out, c.references()...)
+	}
+	return out
+}
+
+// Bind binds the references to other signals, in the rule, the stages and the exit rule, to the
+// registry.
+func (s *Signal) Bind(registry SignalRegistry) {
+	s.Rule.bind(registry)
+	for _, n := range s.Stages.rules() {
+		n.bind(registry)
+	}
+	if s.Exit != nil {
+		s.Exit.Rule.bind(registry)
+	}
+}
+
+// References returns the names of the signals referenced in the rule, the stages and the exit
+// rule.
+func (s Signal) References() []string {
+	var out []string
+	for _, ref := range s.references() {
+		out = append(out, ref.Name)
+	}
+	return out
+}
+
+func (s Signal) references() []*SignalReference {
+	out := s.Rule.references()
+	for _, n := range s.Stages.rules() {
+		out = append(out, n.references()...)
+	}
+	if s.Exit != nil {
+		out = append(out, s.Exit.Rule.references()...)
+	}
+	return out
+}
+
+// CheckReferences returns an error if the signal references itself, directly or through the
+// signals of the registry, or refers to the current outcome of a staged or scripted signal.
+func (s Signal) CheckReferences(registry SignalRegistry) error {
+	for _, ref := range s.references() {
+		if rs, ok := registry.GetSignal(ref.Name); ok && ref.Bars == 0 && rs.Rule == nil {
+			return errors.New("signal " + ref.Name + " is staged or scripted, refer to it with fired instead")
+		}
+	}
+	visited := make(map[string]bool)
+	var visit func(path []string, refs []string) error
+	visit = func(path []string, refs []string) error {
+		for _, ref := range refs {
+			if ref == s.Name {
+				return errors.New("signal reference cycle: " + strings.Join(append(path, ref), " -> "))
+			}
+			if visited[ref] {
+				continue
+			}
+			visited[ref] = true
+			if rs, ok := registry.GetSignal(ref); ok {
+				if err := visit(append(path, ref), rs.References()); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return visit([]string{s.Name}, s.References())
+}
@@ -0,0 +1,101 @@
+package strategy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"follow.markets/internal/pkg/runner"
+)
+
+type testRegistry struct {
+	signals map[string]*Signal
+	fired   map[string]time.Time
+}
+
+func (tr *testRegistry) GetSignal(name string) (*Signal, bool) {
+	s, ok := tr.signals[name]
+	return s, ok
+}
+
+func (tr *testRegistry) LastFired(name string, r *runner.Runner) (time.Time, bool) {
+	t, ok := tr.fired[name+"-"+r.GetUniqueName()]
+	return t, ok
+}
+
+func newReferenceSignal(t *testing.T, name, expr string) *Signal {
+	s, err := NewSignalFromBytes([]byte(`{"name": "` + name + `", "expression": "` + expr + `"}`))
+	assert.EqualValues(t, nil, err)
+	return s
+}
+
+func Test_SignalReference(t *testing.T) {
+	registry := &testRegistry{signals: map[string]*Signal{}, fired: map[string]time.Time{}}
+	regime := newReferenceSignal(t, "regime", "CLOSE > 5")
+	trigger := newReferenceSignal(t, "trigger", "signal(regime) and fired(breakout, 3) and CLOSE < 10")
+	assert.EqualValues(t, []string{"regime", "breakout"}, trigger.References())
+	registry.signals["regime"] = regime
+	registry.signals["breakout"] = newReferenceSignal(t, "breakout", "CLOSE > 100")
+
+	r := newTestRunner(t, "1", "6", "7", "8")
+	// the references aren't satisfied without a registry.
+	assert.EqualValues(t, false, trigger.Evaluate(r, nil))
+
+	trigger.Bind(registry)
+	assert.EqualValues(t, nil, trigger.CheckReferences(registry))
+	assert.EqualValues(t, false, trigger.Evaluate(r, nil))
+	// the firings are looked up by the bars they fired on, the last one starts 3 minutes in.
+	start := time.Unix(1499040000, 0)
+	last, ok := trigger.LastBar(r)
+	assert.EqualValues(t, true, ok)
+	assert.EqualValues(t, start.Add(3*time.Minute), last)
+	registry.fired["breakout-"+r.GetUniqueName()] = start.Add(time.Minute)
+	assert.EqualValues(t, true, trigger.Evaluate(r, nil))
+	registry.fired["breakout-"+r.GetUniqueName()] = start
+	assert.EqualValues(t, false, trigger.Evaluate(r, nil))
+	registry.fired["breakout-"+r.GetUniqueName()] = start.Add(4 * time.Minute)
+	assert.EqualValues(t, false, trigger.Evaluate(r, nil))
+	registry.fired["breakout-"+r.GetUniqueName()] = start.Add(time.Minute)
+
+	// the referenced signal is evaluated on the past bars in temporal nodes.
+	persist := newReferenceSignal(t, "persist", "persist(3, signal(regime))")
+	persist.Bind(registry)
+	assert.EqualValues(t, true, persist.Evaluate(r, nil))
+	assert.EqualValues(t, false, persist.Evaluate(newTestRunner(t, "7", "1", "7", "8"), nil))
+
+	negated := newReferenceSignal(t, "negated", "not signal(regime)")
+	negated.Bind(registry)
+	assert.EqualValues(t, Not, negated.Rule.Opt)
+	assert.EqualValues(t, false, negated.Evaluate(r, nil))
+
+	// cycles are detected through the registered signals.
+	registry.signals["trigger"] = trigger
+	err := newReferenceSignal(t, "regime", "signal(trigger)").CheckReferences(registry)
+	assert.EqualValues(t, "signal reference cycle: regime -> trigger -> regime", err.Error())
+	err = newReferenceSignal(t, "breakout", "fired(trigger, 2)").CheckReferences(registry)
+	assert.EqualValues(t, "signal reference cycle: breakout -> trigger -> breakout", err.Error())
+	assert.NotEqual(t, nil, newReferenceSignal(t, "self", "signal(self)").CheckReferences(registry))
+
+	explanation := trigger.Explain(r, nil)
+	assert.EqualValues(t, "regime", explanation.Rule.Nodes[0].Signal.Name)
+	assert.EqualValues(t, true, explanation.Rule.Nodes[0].Result)
+
+	// the staged and scripted signals are only referred to by their firings.
+	registry.signals["staged"], _ = NewSignalFromBytes([]byte(`{"name": "staged", "stages": {"transitions": [{"to": "DONE", "expression": "CLOSE > 1"}]}}`))
+	registry.signals["scripted"], _ = NewSignalFromBytes([]byte(`{"name": "scripted", "script": {"source": "def evaluate(runner):\n  return True\n"}}`))
+	err = newReferenceSignal(t, "current", "signal(staged)").CheckReferences(registry)
+	assert.EqualValues(t, "signal staged is staged or scripted, refer to it with fired instead", err.Error())
+	assert.NotEqual(t, nil, newReferenceSignal(t, "current", "CLOSE > 1 and signal(scripted)").CheckReferences(registry))
+	history := newReferenceSignal(t, "history", "fired(staged, 1) and fired(scripted, 2)")
+	assert.EqualValues(t, nil, history.CheckReferences(registry))
+	history.Bind(registry)
+	registry.fired["staged-"+r.GetUniqueName()] = last
+	registry.fired["scripted-"+r.GetUniqueName()] = last.Add(-time.Minute)
+	assert.EqualValues(t, true, history.Evaluate(r, nil))
+
+	for _, expr := range []string{"signal()", "fired(regime)", "fired(regime, 0)", "signal(1)"} {
+		_, err := CompileExpression(expr)
+		assert.NotEqual(t, nil, err, expr)
+	}
+}
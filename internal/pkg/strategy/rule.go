@@ -11,6 +11,8 @@ type GenericRule struct {
 	Signal Signal
 
 	runner *runner.Runner
+	// the stage of a staged signal.
+	state *StageState
 }
 
 func NewRule(signal Signal) *GenericRule {
@@ -25,13 +27,76 @@ func (gr *GenericRule) SetRunner(r *runner.Runner) *GenericRule {
 	return gr
 }
 
-func (gr GenericRule) IsSatisfied(index int, record *ta.TradingRecord) bool {
+// IsSatisfied returns true if the signal is satisfied on the runner, a staged signal is
+// advanced and is satisfied when it fires.
+func (gr *GenericRule) IsSatisfied(index int, record *ta.TradingRecord) bool {
 	if gr.runner == nil {
 		return false
 	}
+	if gr.Signal.IsStaged() {
+		var fired bool
+		gr.state, _, fired = gr.Signal.Advance(gr.runner, gr.state)
+		return fired
+	}
 	return gr.Signal.Evaluate(gr.runner, nil)
 }
 
+// ExitRule is satisfied when the open position of the trading record meets one of the exits
+// of the signal within the last candle. The prices within the candle are checked in a fixed
+// order, as it can't be told in which order they were traded: the low of a long position, or
+// the high of a short one, first, so the stops are hit before the targets, then the other
+// extreme and the close.
+type ExitRule struct {
+	Signal Signal
+
+	runner   *runner.Runner
+	position *Position
+}
+
+func NewExitRule(signal Signal) *ExitRule {
+	return &ExitRule{Signal: signal}
+}
+
+func (er *ExitRule) SetRunner(r *runner.Runner) *ExitRule {
+	if er == nil {
+		return nil
+	}
+	er.runner = r
+	return er
+}
+
+func (er *ExitRule) IsSatisfied(index int, record *ta.TradingRecord) bool {
+	if er.runner == nil || !record.CurrentPosition().IsOpen() {
+		return false
+	}
+	line, ok := er.runner.GetLines(er.runner.SmallestFrame())
+	if !ok || line == nil {
+		return false
+	}
+	candle := line.Candles.LastCandle()
+	if candle == nil {
+		return false
+	}
+	entrance := record.CurrentPosition().EntranceOrder()
+	if er.position == nil || !er.position.EntryTime.Equal(entrance.ExecutionTime) {
+		side := "BUY"
+		if entrance.Side == ta.SELL {
+			side = "SELL"
+		}
+		er.position = er.Signal.NewPosition(er.runner, side, entrance.Price, entrance.ExecutionTime)
+	}
+	prices := []big.Decimal{candle.MinPrice, candle.MaxPrice, candle.ClosePrice}
+	if !er.position.IsLong() {
+		prices[0], prices[1] = candle.MaxPrice, candle.MinPrice
+	}
+	for _, price := range prices {
+		if _, ok := er.Signal.ShouldExit(er.runner, er.position, price, candle.Period.Start); ok {
+			return true
+		}
+	}
+	return false
+}
+
 type StopLossRule struct {
 	LossTolerance big.Decimal
 
@@ -4,28 +4,46 @@ import (
 	"io/ioutil"
 	"testing"
 
+	bn "github.com/adshao/go-binance/v2"
 	"github.com/sdcoffey/big"
 	"github.com/stretchr/testify/assert"
 
+	"follow.markets/internal/pkg/runner"
 	tax "follow.markets/internal/pkg/techanex"
 )
 
 func Test_Rule(t *testing.T) {
-	return
-	path := "./signal_trade.json"
+	path := "./signals/signal_trade.json"
 	raw, err := ioutil.ReadFile(path)
 	assert.EqualValues(t, nil, err)
 
 	signal, err := NewSignalFromBytes(raw)
 	assert.EqualValues(t, nil, err)
-
-	td := tax.NewTrade()
-	td.Price = big.NewFromInt(2000)
-	td.Quantity = big.NewFromInt(1)
-
-	rule := NewRule(*signal) //.SetTrade(td)
-
+	assert.EqualValues(t, true, signal.NeedsTrades())
+	assert.EqualValues(t, false, signal.NeedsDepth())
+
+	r := runner.NewRunner("BTCUSDT", nil)
+	kline := &bn.Kline{
+		OpenTime: 1499040000000,
+		Open:     "0.0",
+		High:     "0.8",
+		Low:      "0.01",
+		Close:    "0.2",
+		Volume:   "148976.1",
+		TradeNum: 308,
+	}
+	ok := r.SyncCandle(tax.ConvertBinanceKline(kline, nil))
+	assert.EqualValues(t, true, ok)
+
+	rule := NewRule(*signal).SetRunner(r)
+	assert.EqualValues(t, false, rule.IsSatisfied(0, nil))
+
+	for i := 0; i < 2; i++ {
+		td := tax.NewTrade()
+		td.Price = big.NewFromInt(2000)
+		td.Quantity = big.NewFromInt(1)
+		td.TradeTime = 1499040000000 + int64(i)*1000
+		r.SyncTrade(td)
+	}
 	assert.EqualValues(t, true, rule.IsSatisfied(0, nil))
-
-	//risk := NewRiskRewardRule(0.5, 0.6, runner.NewRunner("BTCUSDT", nil))
 }
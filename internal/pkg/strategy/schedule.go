@@ -0,0 +1,220 @@
+package strategy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"follow.markets/internal/pkg/runner"
+	"follow.markets/pkg/util"
+)
+
+const (
+	// FundingEvent is the funding timestamps of the futures, read from the runner's derivatives
+	// and every 8 hours from midnight UTC if the runner doesn't have them.
+	FundingEvent = "FUNDING"
+
+	defaultFundingInterval = 8 * 60 * 60 // in second
+)
+
+// weekdays are the names of the days of a schedule, in the order of time.Weekday.
+var weekdays = []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}
+
+// Schedule defines when a signal is live. The hours of day and the days of week are in the
+// timezone of the schedule, the market's local timezone if it's not given, and a signal without
+// hours or days is live all day or all week. The signal isn't live within the blackout windows,
+// before the start time and after the expiry time.
+type Schedule struct {
+	Timezone  string      `json:"timezone,omitempty"`
+	Hours     []int       `json:"hours,omitempty"` // from 0 to 23
+	Days      []string    `json:"days,omitempty"`  // MON, TUE...
+	Blackouts []*Blackout `json:"blackouts,omitempty"`
+	Start     *time.Time  `json:"start,omitempty"`
+	Expiry    *time.Time  `json:"expiry,omitempty"`
+}
+
+// Blackout is a window around scheduled times, either the times of an event, or every given
+// number of seconds from the Unix epoch shifted by the offset. The window spans the seconds
+// before and after each scheduled time.
+type Blackout struct {
+	Event  string `json:"event,omitempty"`
+	Every  int64  `json:"every,omitempty"`  // in second
+	Offset int64  `json:"offset,omitempty"` // in second
+	Before int64  `json:"before"`           // in second
+	After  int64  `json:"after"`            // in second
+}
+
+func (s *Schedule) copy() *Schedule {
+	if s == nil {
+		return nil
+	}
+	ns := *s
+	ns.Hours = append([]int{}, s.Hours...)
+	ns.Days = append([]string{}, s.Days...)
+	ns.Blackouts = make([]*Blackout, 0, len(s.Blackouts))
+	for _, b := range s.Blackouts {
+		nb := *b
+		ns.Blackouts = append(ns.Blackouts, &nb)
+	}
+	return &ns
+}
+
+func (s *Schedule) validate() error {
+	if _, err := time.LoadLocation(s.Timezone); err != nil {
+		return errors.New("invalid schedule timezone")
+	}
+	for _, h := range s.Hours {
+		if h < 0 || h > 23 {
+			return errors.New("schedule hours must be between 0 and 23")
+		}
+	}
+	for _, d := range s.Days {
+		if weekday(d) < 0 {
+			return errors.New("invalid schedule day")
+		}
+	}
+	for _, b := range s.Blackouts {
+		if b == nil {
+			return errors.New("missing schedule blackout")
+		}
+		if err := b.validate(); err != nil {
+			return err
+		}
+	}
+	if s.Start != nil && s.Expiry != nil && !s.Start.Before(*s.Expiry) {
+		return errors.New("schedule start must be before its expiry")
+	}
+	return nil
+}
+
+func (b *Blackout) validate() error {
+	if b.Event != "" && strings.ToUpper(b.Event) != FundingEvent {
+		return errors.New("unknown blackout event")
+	}
+	if b.Event == "" && b.Every <= 0 {
+		return errors.New("a blackout must have an event or an interval")
+	}
+	if b.Every < 0 || b.Before < 0 || b.After < 0 {
+		return errors.New("invalid blackout window")
+	}
+	return nil
+}
+
+// IsActive returns true if the schedule is live at the given time on the runner, the
+// location is used if the schedule doesn't have a timezone.
+func (s *Schedule) IsActive(r *runner.Runner, t time.Time, loc *time.Location) bool {
+	if s == nil {
+		return true
+	}
+	if (s.Start != nil && t.Before(*s.Start)) || (s.Expiry != nil && !t.Before(*s.Expiry)) {
+		return false
+	}
+	if tz, err := time.LoadLocation(s.Timezone); err == nil && s.Timezone != "" {
+		loc = tz
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	local := t.In(loc)
+	if len(s.Hours) > 0 && !util.IntSliceContains(s.Hours, local.Hour()) {
+		return false
+	}
+	if len(s.Days) > 0 {
+		live := false
+		for _, d := range s.Days {
+			live = live || weekday(d) == int(local.Weekday())
+		}
+		if !live {
+			return false
+		}
+	}
+	for _, b := range s.Blackouts {
+		if b.covers(r, t) {
+			return false
+		}
+	}
+	return true
+}
+
+// covers returns true if the given time is within the blackout window of the previous
+// or the next scheduled time.
+func (b *Blackout) covers(r *runner.Runner, t time.Time) bool {
+	prev, next := b.around(r, t)
+	return !t.Before(prev) && t.Sub(prev) <= time.Duration(b.After)*time.Second ||
+		!t.After(next) && next.Sub(t) <= time.Duration(b.Before)*time.Second
+}
+
+// around returns the scheduled times right before, inclusive, and right after the given time.
+func (b *Blackout) around(r *runner.Runner, t time.Time) (time.Time, time.Time) {
+	every := b.Every
+	if every <= 0 {
+		every = defaultFundingInterval
+	}
+	if strings.ToUpper(b.Event) == FundingEvent && r != nil {
+		if series, ok := r.GetDerivatives(r.SmallestFrame()); ok && series != nil {
+			if d := series.LastDerivative(); d != nil && d.NextFundingTime > 0 {
+				next := time.UnixMilli(d.NextFundingTime)
+				if next.After(t) && next.Sub(t) <= time.Duration(every)*time.Second {
+					return next.Add(-time.Duration(every) * time.Second), next
+				}
+			}
+		}
+	}
+	shifted := t.Unix() - b.Offset
+	prev := shifted - ((shifted%every)+every)%every + b.Offset
+	return time.Unix(prev, 0), time.Unix(prev+every, 0)
+}
+
+// String returns a text description of the schedule.
+func (s *Schedule) String() string {
+	if s == nil {
+		return ""
+	}
+	var out []string
+	if len(s.Days) > 0 {
+		out = append(out, "on "+strings.ToUpper(strings.Join(s.Days, ",")))
+	}
+	if len(s.Hours) > 0 {
+		hours := make([]string, 0, len(s.Hours))
+		for _, h := range s.Hours {
+			hours = append(hours, fmt.Sprintf("%02d", h))
+		}
+		out = append(out, "at "+strings.Join(hours, ",")+"h")
+	}
+	if s.Timezone != "" {
+		out = append(out, s.Timezone)
+	}
+	for _, b := range s.Blackouts {
+		out = append(out, "off "+b.String())
+	}
+	if s.Start != nil {
+		out = append(out, "from "+s.Start.Format(time.RFC3339))
+	}
+	if s.Expiry != nil {
+		out = append(out, "until "+s.Expiry.Format(time.RFC3339))
+	}
+	return strings.Join(out, " ")
+}
+
+// String returns a text description of the blackout.
+func (b *Blackout) String() string {
+	event := strings.ToUpper(b.Event)
+	if event == "" {
+		event = "every " + (time.Duration(b.Every) * time.Second).String()
+		if b.Offset != 0 {
+			event += " +" + (time.Duration(b.Offset) * time.Second).String()
+		}
+	}
+	return fmt.Sprintf("%s before and %s after %s", time.Duration(b.Before)*time.Second, time.Duration(b.After)*time.Second, event)
+}
+
+// weekday returns the index of the day in the week, -1 if it's unknown.
+func weekday(day string) int {
+	for i, d := range weekdays {
+		if d == strings.ToUpper(day) {
+			return i
+		}
+	}
+	return -1
+}
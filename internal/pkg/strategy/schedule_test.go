@@ -0,0 +1,60 @@
+package strategy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"follow.markets/internal/pkg/runner"
+)
+
+func Test_Schedule(t *testing.T) {
+	seoul, err := time.LoadLocation("Asia/Seoul")
+	assert.EqualValues(t, nil, err)
+	// Monday, 2022-01-03 10:30 in Seoul.
+	now := time.Date(2022, 1, 3, 10, 30, 0, 0, seoul)
+	r := runner.NewRunner("BTCUSDT", nil)
+
+	var s *Schedule
+	assert.EqualValues(t, true, s.IsActive(r, now, nil))
+
+	s = &Schedule{Hours: []int{9, 10, 11}, Days: []string{"mon", "TUE"}}
+	assert.EqualValues(t, nil, s.validate())
+	assert.EqualValues(t, true, s.IsActive(r, now, seoul))
+	assert.EqualValues(t, false, s.IsActive(r, now, time.UTC))
+	assert.EqualValues(t, false, s.IsActive(r, now.Add(24*time.Hour*2), seoul))
+
+	// the timezone of the schedule comes before the given one.
+	s.Timezone = "Asia/Seoul"
+	assert.EqualValues(t, true, s.IsActive(r, now, time.UTC))
+
+	expiry := now.Add(time.Hour)
+	s.Expiry = &expiry
+	assert.EqualValues(t, true, s.IsActive(r, now, nil))
+	assert.EqualValues(t, false, s.IsActive(r, expiry, nil))
+	start := now.Add(time.Minute)
+	s.Start = &start
+	assert.EqualValues(t, false, s.IsActive(r, now, nil))
+	s.Start = &expiry
+	assert.NotEqual(t, nil, s.validate())
+
+	// the funding times are every 8 hours from midnight UTC without the runner's derivatives.
+	s = &Schedule{Blackouts: []*Blackout{{Event: "funding", Before: 300, After: 60}}}
+	assert.EqualValues(t, nil, s.validate())
+	funding := time.Date(2022, 1, 3, 8, 0, 0, 0, time.UTC)
+	assert.EqualValues(t, true, s.IsActive(r, funding.Add(-6*time.Minute), nil))
+	assert.EqualValues(t, false, s.IsActive(r, funding.Add(-5*time.Minute), nil))
+	assert.EqualValues(t, false, s.IsActive(r, funding.Add(time.Minute), nil))
+	assert.EqualValues(t, true, s.IsActive(r, funding.Add(2*time.Minute), nil))
+
+	s = &Schedule{Blackouts: []*Blackout{{Every: 3600, Offset: 1800, Before: 60}}}
+	assert.EqualValues(t, false, s.IsActive(r, funding.Add(29*time.Minute+30*time.Second), nil))
+	assert.EqualValues(t, true, s.IsActive(r, funding.Add(31*time.Minute), nil))
+	assert.EqualValues(t, "off 1m0s before and 0s after every 1h0m0s +30m0s", s.String())
+
+	assert.NotEqual(t, nil, (&Schedule{Hours: []int{24}}).validate())
+	assert.NotEqual(t, nil, (&Schedule{Days: []string{"MONDAY"}}).validate())
+	assert.NotEqual(t, nil, (&Schedule{Timezone: "Mars/Olympus"}).validate())
+	assert.NotEqual(t, nil, (&Schedule{Blackouts: []*Blackout{{Before: 60}}}).validate())
+}
@@ -0,0 +1,246 @@
+package strategy
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// schemaDialect is the JSON Schema dialect of the signal schema.
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema is a JSON Schema, only the keywords the signal schema uses.
+type JSONSchema struct {
+	Dialect              string                 `json:"$schema,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"`
+	AnyOf                []*JSONSchema          `json:"anyOf,omitempty"`
+	Type                 interface{}            `json:"type,omitempty"` // a type or a list of types
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	AdditionalProperties interface{}            `json:"additionalProperties,omitempty"` // false or a schema
+	Items                *JSONSchema            `json:"items,omitempty"`
+	Defs                 map[string]*JSONSchema `json:"$defs,omitempty"`
+}
+
+var (
+	// the legacy fields of the strategy types, which are decoded into their current fields.
+	legacyFields = map[reflect.Type]map[string]reflect.Type{
+		reflect.TypeOf(Signal{}): {
+			"groups": reflect.TypeOf([]*RuleNode{}),
+		},
+		reflect.TypeOf(RuleNode{}): {
+			"groups":           reflect.TypeOf([]*RuleNode{}),
+			"condition_groups": reflect.TypeOf([]*RuleNode{}),
+			"conditions":       reflect.TypeOf([]*Condition{}),
+		},
+	}
+
+	jsonUnmarshaler = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+	signalSchema     *JSONSchema
+	signalSchemaOnce sync.Once
+)
+
+// SignalSchema returns the JSON Schema of the signals, generated from the strategy types. The
+// objects don't take any field they don't declare, as the signals are decoded strictly.
+func SignalSchema() *JSONSchema {
+	signalSchemaOnce.Do(func() {
+		defs := map[string]*JSONSchema{}
+		root := newSchema(reflect.TypeOf(Signal{}), defs)
+		signalSchema = &JSONSchema{Dialect: schemaDialect, Title: "Signal", Ref: root.Ref, Defs: defs}
+	})
+	return signalSchema
+}
+
+// newSchema returns the schema of the type, the named structs are defined once in the defs and
+// referred to, which allows the recursive types.
+func newSchema(t reflect.Type, defs map[string]*JSONSchema) *JSONSchema {
+	// the types decoding themselves, other than the ones of the legacy fields, e.g. time.Time.
+	if t.Kind() != reflect.Ptr && legacyFields[t] == nil {
+		switch {
+		case reflect.PtrTo(t).Implements(jsonUnmarshaler):
+			return &JSONSchema{}
+		case reflect.PtrTo(t).Implements(textUnmarshaler):
+			return &JSONSchema{Type: "string"}
+		}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return nullable(newSchema(t.Elem(), defs))
+	case reflect.Slice, reflect.Array:
+		return nullable(&JSONSchema{Type: "array", Items: newSchema(t.Elem(), defs)})
+	case reflect.Map:
+		return nullable(&JSONSchema{Type: "object", AdditionalProperties: newSchema(t.Elem(), defs)})
+	case reflect.Interface:
+		return &JSONSchema{}
+	case reflect.String:
+		return &JSONSchema{Type: "string"}
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &JSONSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: "number"}
+	case reflect.Struct:
+		if len(t.Name()) == 0 {
+			return structSchema(t, defs)
+		}
+		if _, ok := defs[t.Name()]; !ok {
+			// the definition is reserved before its fields, which might refer to it.
+			defs[t.Name()] = &JSONSchema{}
+			*defs[t.Name()] = *structSchema(t, defs)
+		}
+		return &JSONSchema{Ref: "#/$defs/" + t.Name()}
+	default:
+		return &JSONSchema{}
+	}
+}
+
+func structSchema(t reflect.Type, defs map[string]*JSONSchema) *JSONSchema {
+	out := &JSONSchema{Type: "object", Properties: map[string]*JSONSchema{}, AdditionalProperties: false}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if len(f.PkgPath) > 0 {
+			continue // unexported
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if len(name) == 0 {
+			name = f.Name
+		}
+		out.Properties[name] = newSchema(f.Type, defs)
+	}
+	for name, lt := range legacyFields[t] {
+		out.Properties[name] = newSchema(lt, defs)
+	}
+	return out
+}
+
+// nullable returns the schema taking null too, as the missing pointers, slices and maps are
+// encoded as null.
+func nullable(s *JSONSchema) *JSONSchema {
+	if len(s.Ref) > 0 {
+		return &JSONSchema{AnyOf: []*JSONSchema{s, {Type: "null"}}}
+	}
+	if t, ok := s.Type.(string); ok {
+		s.Type = []string{t, "null"}
+	}
+	return s
+}
+
+// checkSignalJSON checks the JSON of a signal against the signal schema, it returns a validation
+// error for the first unknown field or value of the wrong type. The field names are matched
+// case insensitively as the JSON decoding does.
+func checkSignalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return err
+	}
+	schema := SignalSchema()
+	return schema.check(v, schema.Defs, "")
+}
+
+func (s *JSONSchema) check(v interface{}, defs map[string]*JSONSchema, path string) error {
+	if v == nil {
+		return nil // null decodes to the zero value
+	}
+	if len(s.Ref) > 0 {
+		return defs[strings.TrimPrefix(s.Ref, "#/$defs/")].check(v, defs, path)
+	}
+	for _, as := range s.AnyOf {
+		if as.Type != "null" {
+			return as.check(v, defs, path)
+		}
+	}
+	switch s.baseType() {
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return atPath(errors.New("must be an object"), path)
+		}
+		keys := make([]string, 0, len(obj))
+		for key := range obj {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			val := obj[key]
+			ps, ok := s.property(key)
+			if !ok {
+				as, isSchema := s.AdditionalProperties.(*JSONSchema)
+				if !isSchema {
+					return atPath(errors.New("unknown field"), joinPath(path, key))
+				}
+				ps = as
+			}
+			if err := ps.check(val, defs, joinPath(path, key)); err != nil {
+				return err
+			}
+		}
+	case "array":
+		arr, ok := v.([]interface{})
+		if !ok {
+			return atPath(errors.New("must be an array"), path)
+		}
+		for i, val := range arr {
+			if err := s.Items.check(val, defs, path+indexPath("", i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		if _, ok := v.(string); !ok {
+			return atPath(errors.New("must be a string"), path)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return atPath(errors.New("must be a boolean"), path)
+		}
+	case "integer":
+		n, ok := v.(json.Number)
+		if _, err := strconv.ParseInt(n.String(), 10, 64); !ok || err != nil {
+			return atPath(errors.New("must be an integer"), path)
+		}
+	case "number":
+		if _, ok := v.(json.Number); !ok {
+			return atPath(errors.New("must be a number"), path)
+		}
+	}
+	return nil
+}
+
+// baseType returns the type of the schema other than null, empty if it takes any value.
+func (s *JSONSchema) baseType() string {
+	switch t := s.Type.(type) {
+	case string:
+		return t
+	case []string:
+		return t[0]
+	default:
+		return ""
+	}
+}
+
+// property returns the schema of the property of the given name, matched case insensitively.
+func (s *JSONSchema) property(name string) (*JSONSchema, bool) {
+	if ps, ok := s.Properties[name]; ok {
+		return ps, true
+	}
+	for key, ps := range s.Properties {
+		if strings.EqualFold(key, name) {
+			return ps, true
+		}
+	}
+	return nil, false
+}
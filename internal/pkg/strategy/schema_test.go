@@ -0,0 +1,83 @@
+package strategy
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SignalSchema(t *testing.T) {
+	schema := SignalSchema()
+	assert.EqualValues(t, "#/$defs/Signal", schema.Ref)
+	assert.EqualValues(t, false, schema.Defs["RuleNode"].AdditionalProperties)
+	_, ok := schema.Defs["RuleNode"].Properties["condition_groups"]
+	assert.EqualValues(t, true, ok)
+
+	// the published schema is the generated one.
+	bts, err := json.MarshalIndent(schema, "", "  ")
+	assert.EqualValues(t, nil, err)
+	published, err := ioutil.ReadFile("../../../docs/signal.schema.json")
+	assert.EqualValues(t, nil, err)
+	assert.EqualValues(t, string(bts)+"\n", string(published))
+}
+
+func Test_StrictDecoding(t *testing.T) {
+	condition := `{"opt": "MORE", "this": {"time_period": 60, "candle": {"name": "CLOSE"}}, "that": {"time_period": 60, "indicator": {"name": "ExponentialMovingAverage", "config": {"window": 9}}}}`
+	signal := func(cond string) []byte {
+		return []byte(`{"name": "strict", "rule": {"opt": "AND", "groups": [{"opt": "OR", "condition_groups": [
+			{"opt": "AND", "conditions": [` + condition + `]},
+			{"opt": "AND", "conditions": [` + condition + `, ` + condition + `, ` + cond + `]}
+		]}]}}`)
+	}
+	s, err := NewSignalFromBytes(signal(condition))
+	assert.EqualValues(t, nil, err)
+
+	// the signals are the same after a round trip.
+	bts, err := json.Marshal(s)
+	assert.EqualValues(t, nil, err)
+	_, err = NewSignalFromBytes(bts)
+	assert.EqualValues(t, nil, err)
+
+	// so are the expression, staged and scripted signals.
+	for _, bts := range [][]byte{
+		[]byte(`{"name": "strict", "expression": "CLOSE > 1", "exit": {"expression": "CLOSE < 1"}}`),
+		[]byte(`{"name": "strict", "stages": {"transitions": [{"to": "DONE", "expression": "CLOSE > 1"}], "reset_expression": "CLOSE < 1"}}`),
+		[]byte(`{"name": "strict", "script": {"source": "def evaluate(runner):\n  return True\n"}}`),
+	} {
+		s, err := NewSignalFromBytes(bts)
+		assert.EqualValues(t, nil, err)
+		bts, err = json.Marshal(s)
+		assert.EqualValues(t, nil, err)
+		c, err := NewSignalFromBytes(bts)
+		assert.EqualValues(t, nil, err)
+		assert.EqualValues(t, s.Expression, c.Expression)
+		assert.EqualValues(t, s.Stages != nil, c.Stages != nil)
+		assert.EqualValues(t, s.Script != nil, c.Script != nil)
+	}
+
+	_, err = NewSignalFromBytes(signal(strings.Replace(condition, `"candle"`, `"candles"`, 1)))
+	assert.EqualValues(t, "rule.groups[0].condition_groups[1].conditions[2].this.candles: unknown field", err.Error())
+
+	_, err = NewSignalFromBytes(signal(strings.Replace(condition, `"time_period": 60`, `"time_period": "60"`, 1)))
+	assert.EqualValues(t, "rule.groups[0].condition_groups[1].conditions[2].this.time_period: must be an integer", err.Error())
+
+	_, err = NewSignalFromBytes(signal(strings.Replace(condition, `"ExponentialMovingAverage"`, `"ExponentialAverage"`, 1)))
+	assert.EqualValues(t, "rule.groups[0].condition_groups[1].conditions[2].that.indicator.name: invalid indicator name or config", err.Error())
+	ve, ok := err.(*ValidationError)
+	assert.EqualValues(t, true, ok)
+	assert.EqualValues(t, "invalid indicator name or config", ve.Err.Error())
+
+	_, err = NewSignalFromBytes([]byte(`{"name": "strict", "expression": "CLOSE > 1", "notify": "ALL"}`))
+	assert.EqualValues(t, "notify: unknown field", err.Error())
+
+	// the legacy groups are at the top level.
+	_, err = NewSignalFromBytes([]byte(`{"name": "strict", "groups": [{"opt": "AND", "conditions": [` + strings.Replace(condition, `"CLOSE"`, `"CLOSED"`, 1) + `]}]}`))
+	assert.EqualValues(t, "groups[0].conditions[0].this.candle.name: invalid candle level", err.Error())
+
+	// the paths of the compiled rules stop at their expressions.
+	_, err = NewSignalFromBytes([]byte(`{"name": "strict", "stages": {"transitions": [{"to": "DONE", "expression": "CLOSE >"}]}}`))
+	assert.EqualValues(t, "stages.transitions[0].expression: line 1, column 8: expected a value, found end of expression", err.Error())
+}
@@ -0,0 +1,321 @@
+package strategy
+
+import (
+	"errors"
+	"fmt"
+	"runtime/metrics"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+
+	"follow.markets/internal/pkg/runner"
+	"follow.markets/pkg/util"
+)
+
+const (
+	// the default and the maximum limits of a script run.
+	defaultScriptSteps   = 100000
+	maxScriptSteps       = 10000000
+	defaultScriptTimeout = 100  // in millisecond
+	maxScriptTimeout     = 1000 // in millisecond
+	defaultScriptMemory  = 64   // in megabyte
+	maxScriptMemory      = 512  // in megabyte
+
+	// the number of steps between two checks of the memory of a run.
+	scriptMemoryCheckSteps = 10
+	// the metric of the bytes allocated on the heap since the start of the process.
+	heapAllocsMetric = "/gc/heap/allocs:bytes"
+
+	// the function a script must define.
+	scriptFunction = "evaluate"
+)
+
+// Script is a signal written in Starlark instead of a rule. The script defines a function
+// evaluate(runner) returning whether the signal triggers, or a tuple of the result and a
+// message. The runner is read only:
+//
+//   - runner.name and runner.market are the unique name and the market of the runner.
+//   - runner.candles(period, count) returns the last count candles of the period in second,
+//     oldest first, with start, open, high, low, close, volume and trades.
+//   - runner.indicator(name, window, period, offset) returns the indicator of the candle offset
+//     bars before the last one, None if it's not available.
+//
+// The period defaults to the time period of the script, 60 seconds if it's not given. A run is
+// cancelled when it exceeds its steps, its timeout or its memory, and the script doesn't
+// trigger. The memory of a run is the memory allocated on the heap while it runs, checked every
+// 10 steps. As Starlark doesn't meter the allocations of a thread, the allocations of the rest
+// of the process during the run count too, which might only cancel a run earlier. A single step,
+// e.g. a string repetition, is bounded by Starlark itself to allocations below 1GB.
+type Script struct {
+	Source     string  `json:"source"`
+	TimePeriod int     `json:"time_period,omitempty"` // in second
+	MaxSteps   uint64  `json:"max_steps,omitempty"`
+	Timeout    int64   `json:"timeout,omitempty"`    // in millisecond
+	MaxMemory  int64   `json:"max_memory,omitempty"` // in megabyte
+	Msg        *string `json:"message,omitempty"`
+
+	program *starlark.Program
+}
+
+// ScriptExplanation is the outcome of the last run of a script.
+type ScriptExplanation struct {
+	Message string `json:"message,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+func (sc *Script) copy() *Script {
+	if sc == nil {
+		return nil
+	}
+	nsc := *sc
+	nsc.Msg = nil
+	return &nsc
+}
+
+// compile compiles the source of the script, the compiled program is shared by its copies.
+func (sc *Script) compile() error {
+	_, prog, err := starlark.SourceProgram("script", sc.Source, func(string) bool { return false })
+	if err != nil {
+		return err
+	}
+	sc.program = prog
+	return nil
+}
+
+func (sc *Script) validate() error {
+	if len(strings.TrimSpace(sc.Source)) == 0 {
+		return errors.New("missing script source")
+	}
+	if sc.MaxSteps > maxScriptSteps {
+		return errors.New("script max steps must be at most " + strconv.Itoa(maxScriptSteps))
+	}
+	if sc.Timeout < 0 || sc.Timeout > maxScriptTimeout {
+		return errors.New("script timeout must be between 0 and " + strconv.Itoa(maxScriptTimeout) + " milliseconds")
+	}
+	if sc.MaxMemory < 0 || sc.MaxMemory > maxScriptMemory {
+		return errors.New("script max memory must be between 0 and " + strconv.Itoa(maxScriptMemory) + " megabytes")
+	}
+	if sc.TimePeriod != 0 && !util.Int64SliceContains(AcceptablePeriods, int64(sc.TimePeriod)) {
+		return errors.New("unknown time period")
+	}
+	if sc.program == nil {
+		if err := sc.compile(); err != nil {
+			return err
+		}
+	}
+	thread, stop := sc.newThread()
+	defer stop()
+	globals, err := sc.init(thread)
+	if err != nil {
+		return err
+	}
+	if _, ok := globals[scriptFunction].(starlark.Callable); !ok {
+		return errors.New("a script must define " + scriptFunction + "(runner)")
+	}
+	return nil
+}
+
+// init executes the top level statements of the script, a panic is returned as an error.
+func (sc *Script) init(thread *starlark.Thread) (globals starlark.StringDict, err error) {
+	defer recoverScript(&err)
+	return sc.program.Init(thread, nil)
+}
+
+// recoverScript recovers from a panic of a script run to the given error, a script can't take
+// the process down.
+func recoverScript(err *error) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("script panicked: %v", r)
+	}
+}
+
+// period returns the time period of the script.
+func (sc *Script) period() time.Duration {
+	if sc.TimePeriod == 0 {
+		return time.Minute
+	}
+	return time.Duration(sc.TimePeriod) * time.Second
+}
+
+// newThread returns a thread limited to the steps, the memory and the timeout of the script, the
+// returned function must be called when the run is done.
+func (sc *Script) newThread() (*starlark.Thread, func()) {
+	thread := &starlark.Thread{Name: "script"}
+	steps := sc.MaxSteps
+	if steps == 0 {
+		steps = defaultScriptSteps
+	}
+	memory := sc.MaxMemory
+	if memory == 0 {
+		memory = defaultScriptMemory
+	}
+	// the thread stops every few steps to check its memory, until it reaches its max steps.
+	start, limit := heapAllocs(), uint64(memory)<<20
+	thread.OnMaxSteps = func(thread *starlark.Thread) {
+		switch {
+		case thread.ExecutionSteps() >= steps:
+			thread.Cancel("too many steps")
+		case heapAllocs()-start > limit:
+			thread.Cancel("too much memory")
+		default:
+			thread.SetMaxExecutionSteps(minSteps(thread.ExecutionSteps()+scriptMemoryCheckSteps, steps))
+		}
+	}
+	thread.SetMaxExecutionSteps(minSteps(scriptMemoryCheckSteps, steps))
+	timeout := sc.Timeout
+	if timeout == 0 {
+		timeout = defaultScriptTimeout
+	}
+	timer := time.AfterFunc(time.Duration(timeout)*time.Millisecond, func() { thread.Cancel("timeout") })
+	return thread, func() { timer.Stop() }
+}
+
+// heapAllocs returns the bytes allocated on the heap since the start of the process.
+func heapAllocs() uint64 {
+	sample := []metrics.Sample{{Name: heapAllocsMetric}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
+
+func minSteps(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// evaluate runs the script on the runner, the message of the script is kept on it.
+func (sc *Script) evaluate(r *runner.Runner) bool {
+	ok, _ := sc.run(r)
+	return ok
+}
+
+// run runs the script on the runner, it returns an error if the script fails or doesn't return
+// a boolean and an optional message.
+func (sc *Script) run(r *runner.Runner) (ok bool, err error) {
+	defer recoverScript(&err)
+	sc.Msg = nil
+	if r == nil {
+		return false, errors.New("missing runner")
+	}
+	if sc.program == nil {
+		return false, errors.New("script isn't compiled")
+	}
+	thread, stop := sc.newThread()
+	defer stop()
+	globals, err := sc.program.Init(thread, nil)
+	if err != nil {
+		return false, err
+	}
+	fn, isFn := globals[scriptFunction].(starlark.Callable)
+	if !isFn {
+		return false, errors.New("a script must define " + scriptFunction + "(runner)")
+	}
+	res, err := starlark.Call(thread, fn, starlark.Tuple{newScriptRunner(r, sc.period())}, nil)
+	if err != nil {
+		return false, err
+	}
+	if t, ok := res.(starlark.Tuple); ok && len(t) == 2 {
+		mess, ok := starlark.AsString(t[1])
+		if !ok {
+			return false, errors.New("a script message must be a string")
+		}
+		sc.Msg, res = &mess, t[0]
+	}
+	b, ok := res.(starlark.Bool)
+	if !ok {
+		return false, errors.New("a script must return a boolean")
+	}
+	return bool(b), nil
+}
+
+// newScriptRunner returns the read only view of the runner given to scripts.
+func newScriptRunner(r *runner.Runner, period time.Duration) starlark.Value {
+	return starlarkstruct.FromStringDict(starlark.String("runner"), starlark.StringDict{
+		"name":      starlark.String(r.GetUniqueName()),
+		"market":    starlark.String(string(r.GetMarketType())),
+		"candles":   starlark.NewBuiltin("candles", recoverBuiltin(scriptCandles(r, period))),
+		"indicator": starlark.NewBuiltin("indicator", recoverBuiltin(scriptIndicator(r, period))),
+	})
+}
+
+type scriptBuiltin func(*starlark.Thread, *starlark.Builtin, starlark.Tuple, []starlark.Tuple) (starlark.Value, error)
+
+// recoverBuiltin returns the builtin failing the script instead of panicking.
+func recoverBuiltin(fn scriptBuiltin) scriptBuiltin {
+	return func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (val starlark.Value, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				val, err = nil, fmt.Errorf("%s: %v", b.Name(), r)
+			}
+		}()
+		return fn(thread, b, args, kwargs)
+	}
+}
+
+func scriptCandles(r *runner.Runner, period time.Duration) scriptBuiltin {
+	return func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		seconds, count := int(period/time.Second), 1
+		if err := starlark.UnpackArgs(b.Name(), args, kwargs, "period?", &seconds, "count?", &count); err != nil {
+			return nil, err
+		}
+		if count < 1 {
+			return nil, fmt.Errorf("%s: count must be positive", b.Name())
+		}
+		line, ok := r.GetLines(time.Duration(seconds) * time.Second)
+		if !ok || line == nil {
+			return starlark.NewList(nil), nil
+		}
+		candles := line.Candles.Candles
+		if count < len(candles) {
+			candles = candles[len(candles)-count:]
+		}
+		out := make([]starlark.Value, 0, len(candles))
+		for _, cd := range candles {
+			out = append(out, starlarkstruct.FromStringDict(starlark.String("candle"), starlark.StringDict{
+				"start":  starlark.MakeInt64(cd.Period.Start.Unix()),
+				"open":   starlark.Float(cd.OpenPrice.Float()),
+				"high":   starlark.Float(cd.MaxPrice.Float()),
+				"low":    starlark.Float(cd.MinPrice.Float()),
+				"close":  starlark.Float(cd.ClosePrice.Float()),
+				"volume": starlark.Float(cd.Volume.Float()),
+				"trades": starlark.MakeUint(cd.TradeCount),
+			}))
+		}
+		return starlark.NewList(out), nil
+	}
+}
+
+func scriptIndicator(r *runner.Runner, period time.Duration) scriptBuiltin {
+	return func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var name string
+		window, seconds, offset := 0, int(period/time.Second), 0
+		if err := starlark.UnpackArgs(b.Name(), args, kwargs, "name", &name, "window?", &window, "period?", &seconds, "offset?", &offset); err != nil {
+			return nil, err
+		}
+		if window < 0 || offset < 0 {
+			return nil, fmt.Errorf("%s: window and offset must not be negative", b.Name())
+		}
+		config := map[string]float64{}
+		if window > 0 {
+			config["window"] = float64(window)
+		}
+		c := &Comparable{TimePeriod: seconds, TimeFrame: offset, Indicator: &ComparableObject{Name: name, Config: config}}
+		_, val, ok := c.mapDecimal(r, nil)
+		if !ok {
+			return starlark.None, nil
+		}
+		return starlark.Float(val.Float()), nil
+	}
+}
+
+// IsScripted returns true if the signal is defined as a script.
+func (s Signal) IsScripted() bool {
+	return s.Script != nil
+}
@@ -0,0 +1,70 @@
+package strategy
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newScriptSignal(t *testing.T, source string) (*Signal, error) {
+	script, err := json.Marshal(map[string]interface{}{"source": source})
+	assert.EqualValues(t, nil, err)
+	return NewSignalFromBytes([]byte(`{"name": "script", "script": ` + string(script) + `}`))
+}
+
+func Test_Script(t *testing.T) {
+	signal, err := newScriptSignal(t, `
+def evaluate(runner):
+    closes = [c.close for c in runner.candles(count=3)]
+    if closes[-1] > max(closes[:-1]):
+        return True, "%s breaks out at %s" % (runner.name, closes[-1])
+    return False
+`)
+	assert.EqualValues(t, nil, err)
+	assert.EqualValues(t, true, signal.IsScripted())
+	assert.EqualValues(t, time.Minute, signal.TimePeriod)
+
+	assert.EqualValues(t, true, signal.Evaluate(newTestRunner(t, "4", "5", "6"), nil))
+	assert.EqualValues(t, "BTCUSDT breaks out at 6.0", *signal.Script.Msg)
+	assert.EqualValues(t, false, signal.Evaluate(newTestRunner(t, "4", "6", "5"), nil))
+	assert.True(t, signal.Script.Msg == nil)
+
+	_, err = newScriptSignal(t, "def check(runner):\n    return True\n")
+	assert.EqualValues(t, "script: a script must define evaluate(runner)", err.Error())
+
+	_, err = newScriptSignal(t, "load('os', 'system')\n")
+	assert.NotNil(t, err)
+
+	// a script can't run away.
+	signal, err = newScriptSignal(t, "def evaluate(runner):\n    for i in range(100000000):\n        pass\n    return True\n")
+	assert.EqualValues(t, nil, err)
+	ok, err := signal.Script.run(newTestRunner(t, "1"))
+	assert.EqualValues(t, false, ok)
+	assert.True(t, strings.Contains(err.Error(), "too many steps"))
+
+	// nor take too much memory.
+	signal, err = newScriptSignal(t, "def evaluate(runner):\n    s = 'x'\n    for i in range(40):\n        s = s + s\n    return True\n")
+	assert.EqualValues(t, nil, err)
+	ok, err = signal.Script.run(newTestRunner(t, "1"))
+	assert.EqualValues(t, false, ok)
+	assert.True(t, strings.Contains(err.Error(), "too much memory"))
+
+	// nor crash on bad arguments.
+	signal, err = newScriptSignal(t, "def evaluate(runner):\n    return len(runner.candles(count=-1)) > 0\n")
+	assert.EqualValues(t, nil, err)
+	ok, err = signal.Script.run(newTestRunner(t, "4", "5", "6"))
+	assert.EqualValues(t, false, ok)
+	assert.True(t, strings.Contains(err.Error(), "count must be positive"))
+
+	_, err = NewSignalFromBytes([]byte(`{"name": "script", "script": {"source": "def evaluate(runner):\n    return True\n", "max_memory": 1024}}`))
+	assert.EqualValues(t, "script: script max memory must be between 0 and 512 megabytes", err.Error())
+
+	signal, err = newScriptSignal(t, "def evaluate(runner):\n    return runner.indicator('EMA', 20)\n")
+	assert.EqualValues(t, nil, err)
+	explanation := signal.Explain(newTestRunner(t, "1"), nil)
+	assert.EqualValues(t, false, explanation.Result)
+	assert.EqualValues(t, "a script must return a boolean", explanation.Script.Error)
+}
@@ -2,6 +2,7 @@ package strategy
 
 import (
 	"encoding/json"
+	"errors"
 	"sort"
 	"strings"
 	"time"
@@ -20,42 +21,190 @@ type Signal struct {
 	TrackType  string `json:"track_type"`
 	SignalType string `json:"signal_type"`
 
-	// The conditions of the signal
+	// The priority of the signal over the other signals firing on the same ticker at once, see
+	// ResolveConflicts.
+	Priority int `json:"priority,omitempty"`
+
+	// The conditions of the signal, either given as a rule or as an expression which
+	// is compiled to the rule, see CompileExpression.
 	TimePeriod time.Duration `json:"primary_period"`
-	Rule       Groups        `json:"rule"`
+	Rule       *RuleNode     `json:"rule"`
+	Expression string        `json:"expression,omitempty"`
+
+	// The stages of a staged signal, which is defined by the transitions between its stages
+	// instead of a rule.
+	Stages *Stages `json:"stages,omitempty"`
+
+	// The script of a scripted signal, which is written in Starlark instead of a rule.
+	Script *Script `json:"script,omitempty"`
+
+	// The tickers the signal is evaluated on, in addition to the ticker patterns it's added with.
+	Universe *Universe `json:"universe,omitempty"`
+
+	// The second leg of a pairs signal, whose spread conditions are evaluated on the spread
+	// between the runner and the second leg.
+	Pair *Pair `json:"pair,omitempty"`
+
+	// The hours, days and dates the signal is live, it's always live without a schedule.
+	Schedule *Schedule `json:"schedule,omitempty"`
+
+	// The evaluation mode of the signal, it's evaluated on candle close by default.
+	Evaluation struct {
+		Mode        string `json:"mode"`
+		MinInterval int64  `json:"min_interval"` // in second
+	} `json:"evaluation"`
+
+	// The exits of the positions opened on the signal, the trader's and the tester's risk
+	// settings are used without them.
+	Exit *Exit `json:"exit,omitempty"`
+
+	// The sizing of the positions opened on the signal, the trader's and the tester's sizing
+	// is used without it.
+	Sizing *Sizing `json:"sizing,omitempty"`
 
 	// The trading information for the signal
 	Trade struct {
 		Price         *Comparable `json:"price"`
 		MaxWaitToFill *int64      `json:"max_wait_to_fill"` // in second
 	} `json:"trade"`
+
+	// The test cases of the signal, see SignalTest. They aren't kept by the copies.
+	Tests []*SignalTest `json:"tests,omitempty"`
 }
 
 type Signals []*Signal
 
+// MarshalJSON encodes the signal, without the rule compiled from its expression, so that the
+// signal decodes back.
+func (s Signal) MarshalJSON() ([]byte, error) {
+	type signal Signal
+	out := signal(s)
+	if len(strings.TrimSpace(s.Expression)) > 0 {
+		out.Rule = nil
+	}
+	return json.Marshal(out)
+}
+
 // A new signal entity will be created mostly from a post request body.
 // This method will convert bytes data from a json to a singal and
 // validate all the conditions.
 func NewSignalFromBytes(bytes []byte) (*Signal, error) {
+	// the signals are decoded strictly, a field the schema doesn't have is an error.
+	if err := checkSignalJSON(bytes); err != nil {
+		return nil, err
+	}
 	signal := Signal{}
 	err := json.Unmarshal(bytes, &signal)
 	if err != nil {
 		return nil, err
 	}
-	//if signal.Rule == nil {
-	//	return nil, errors.New("not a valid signal")
-	//}
-	if err := signal.Rule.validate(); err != nil {
+	// the oldest signals have their groups at the top level, all groups must be satisfied.
+	var legacy struct {
+		Groups []*RuleNode `json:"groups"`
+	}
+	if err := json.Unmarshal(bytes, &legacy); err != nil {
 		return nil, err
 	}
-	periods := signal.GetPeriods()
-	signal.TimePeriod = periods[0]
+	// the path of the rule in the signal, the legacy groups are at the top level.
+	rulePath := "rule"
+	if signal.Rule.isEmpty() && len(legacy.Groups) > 0 {
+		signal.Rule = &RuleNode{Opt: And, Nodes: legacy.Groups}
+		for i := range legacy.Groups {
+			signal.Rule.paths = append(signal.Rule.paths, indexPath("groups", i))
+		}
+		rulePath = ""
+	}
+	if len(strings.TrimSpace(signal.Expression)) > 0 {
+		if !signal.Rule.isEmpty() {
+			return nil, errors.New("a signal must have either a rule or an expression")
+		}
+		rule, err := CompileExpression(signal.Expression)
+		if err != nil {
+			return nil, atExpression(err, "expression")
+		}
+		signal.Rule = rule
+	}
+	if signal.Script != nil {
+		if signal.Rule != nil || signal.Stages != nil {
+			return nil, errors.New("a scripted signal must not have a rule or stages")
+		}
+		if err := signal.Script.validate(); err != nil {
+			return nil, atPath(err, "script")
+		}
+	} else if signal.Stages != nil {
+		if signal.Rule != nil {
+			return nil, errors.New("a staged signal must not have a rule")
+		}
+		if err := signal.Stages.compile(); err != nil {
+			return nil, atPath(err, "stages")
+		}
+		if err := signal.Stages.validate(); err != nil {
+			return nil, atPath(err, "stages")
+		}
+	} else if signal.Rule == nil {
+		return nil, atPath(errors.New("missing signal rule"), "rule")
+	} else if err := signal.Rule.validate(); err != nil {
+		if len(strings.TrimSpace(signal.Expression)) > 0 {
+			return nil, atExpression(err, "expression")
+		}
+		return nil, atPath(err, rulePath)
+	}
+	if err := signal.validateEvaluation(); err != nil {
+		return nil, atPath(err, "evaluation")
+	}
+	if signal.Universe != nil {
+		if err := signal.Universe.validate(); err != nil {
+			return nil, atPath(err, "universe")
+		}
+	}
+	if signal.Exit != nil {
+		if err := signal.Exit.compile(); err != nil {
+			return nil, atPath(err, "exit")
+		}
+		if err := signal.Exit.validate(); err != nil {
+			return nil, atPath(err, "exit")
+		}
+	}
+	if signal.Sizing != nil {
+		if err := signal.Sizing.Validate(); err != nil {
+			return nil, atPath(err, "sizing")
+		}
+		if strings.ToUpper(signal.Sizing.Model) == FixedRiskSizing && (signal.Exit == nil || signal.Exit.StopLoss == nil) {
+			return nil, errors.New("fixed risk sizing needs a stop loss")
+		}
+	}
+	if signal.Schedule != nil {
+		if err := signal.Schedule.validate(); err != nil {
+			return nil, atPath(err, "schedule")
+		}
+	}
+	if signal.Pair != nil {
+		if err := signal.Pair.validate(); err != nil {
+			return nil, atPath(err, "pair")
+		}
+	} else if signal.needsPair() {
+		return nil, errors.New("spread conditions need a pair")
+	}
+	for i, st := range signal.Tests {
+		if st == nil {
+			return nil, atPath(errors.New("missing signal test"), indexPath("tests", i))
+		}
+		if err := st.validate(); err != nil {
+			return nil, atPath(err, indexPath("tests", i))
+		}
+	}
+	if periods := signal.GetPeriods(); len(periods) > 0 {
+		signal.TimePeriod = periods[0]
+	}
 	return &signal, err
 }
 
 // Evaluate evaluates signal against the current status of the runner.
 func (s *Signal) Evaluate(r *runner.Runner, t *tax.Trade) bool {
-	if r == nil && t == nil {
+	if s.Script != nil {
+		return s.Script.evaluate(r)
+	}
+	if (r == nil && t == nil) || s.Rule == nil {
 		return false
 	}
 	return s.Rule.evaluate(r, t)
@@ -66,11 +215,21 @@ func (s *Signal) copy() *Signal {
 	var ns Signal
 	ns.Name = s.Name
 	ns.Rule = s.Rule.copy()
+	ns.Expression = s.Expression
+	ns.Stages = s.Stages.copy()
+	ns.Script = s.Script.copy()
 	ns.OwnerID = s.OwnerID
 	ns.SignalType = s.SignalType
+	ns.Priority = s.Priority
 	ns.TrackType = s.TrackType
 	ns.NotifyType = s.NotifyType
 	ns.TimePeriod = s.TimePeriod
+	ns.Evaluation = s.Evaluation
+	ns.Universe = s.Universe.copy()
+	ns.Schedule = s.Schedule.copy()
+	ns.Pair = s.Pair.copy()
+	ns.Exit = s.Exit.copy()
+	ns.Sizing = s.Sizing.copy()
 	ns.Trade.Price = s.Trade.Price.copy()
 	ns.Trade.MaxWaitToFill = s.Trade.MaxWaitToFill
 	return &ns
@@ -89,26 +248,64 @@ func (ss Signals) Copy() Signals {
 func (s Signal) Description() string {
 	var out []string
 	var thisFrame, thatFrame string
-	for _, cg := range s.Rule.Groups {
-		for _, g := range cg.Groups {
-			for _, c := range g.Conditions {
-				if c.Msg != nil {
-					out = append(out, *c.Msg)
-					thisFrame = (time.Duration(c.This.TimePeriod) * time.Second).String()
-					thatFrame = (time.Duration(c.That.TimePeriod) * time.Second).String()
-				}
+	for _, c := range s.conditions() {
+		if c.Msg != nil {
+			out = append(out, *c.Msg)
+			thisFrame = c.This.timePeriod().String()
+			if c.That != nil {
+				thatFrame = c.That.timePeriod().String()
 			}
 		}
 	}
+	if s.Script != nil {
+		thisFrame = s.Script.period().String()
+		if s.Script.Msg != nil {
+			out = append(out, *s.Script.Msg)
+		}
+	}
+	if s.Schedule != nil {
+		out = append([]string{"live " + s.Schedule.String()}, out...)
+	}
+	if s.Pair != nil {
+		out = append([]string{"pair with " + s.Pair.String()}, out...)
+	}
 	out = append([]string{s.Name + ": " + thisFrame + ": " + thatFrame}, out...)
 	return strings.Join(out, "\n")
 }
 
+// IsActive returns true if the signal is live on the runner at the given time according to
+// its schedule, the location is the timezone of schedules without one.
+func (s Signal) IsActive(r *runner.Runner, t time.Time, loc *time.Location) bool {
+	return s.Schedule.IsActive(r, t, loc)
+}
+
 // IsOnetime returns true if the signal is valid for only one time check.
 func (s Signal) IsOnetime() bool {
 	return strings.ToLower(s.TrackType) == strings.ToLower(OnetimeTrack)
 }
 
+// IsIntrabar returns true if the signal is evaluated on every update of the last candles,
+// false if it's only evaluated on candle close.
+func (s Signal) IsIntrabar() bool {
+	return strings.ToUpper(s.Evaluation.Mode) == IntrabarEvaluation
+}
+
+// GetMinInterval returns the minimum duration between two intrabar evaluations of the signal.
+func (s Signal) GetMinInterval() time.Duration {
+	return time.Duration(s.Evaluation.MinInterval) * time.Second
+}
+
+func (s Signal) validateEvaluation() error {
+	mode := strings.ToUpper(s.Evaluation.Mode)
+	if mode != "" && mode != OnCloseEvaluation && mode != IntrabarEvaluation {
+		return atPath(errors.New("invalid evaluation mode"), "mode")
+	}
+	if s.Evaluation.MinInterval < 0 {
+		return atPath(errors.New("invalid evaluation min interval"), "min_interval")
+	}
+	return nil
+}
+
 // OpenTradingSide returns generic string for trading, either BUY or SELL.
 func (s Signal) OpenTradingSide() string {
 	if s.IsBullish() {
@@ -181,20 +378,17 @@ func (s Signal) BacktestSide(side string) string {
 
 func (s Signal) GetPeriods() []time.Duration {
 	var periods []time.Duration
-	for _, gs := range s.Rule.Groups {
-		for _, g := range gs.Groups {
-			if g == nil {
-				continue
-			}
-			for _, c := range g.Conditions {
-				if !util.DurationSliceContains(periods, time.Duration(c.This.TimePeriod)*time.Second) {
-					periods = append(periods, time.Duration(c.This.TimePeriod)*time.Second)
-				}
-				if !util.DurationSliceContains(periods, time.Duration(c.That.TimePeriod)*time.Second) {
-					periods = append(periods, time.Duration(c.That.TimePeriod)*time.Second)
-				}
-			}
+	for _, c := range s.comparables() {
+		// streamed comparables, depth and trade, might not have a time period.
+		if c == nil || c.TimePeriod <= 0 {
+			continue
 		}
+		if !util.DurationSliceContains(periods, time.Duration(c.TimePeriod)*time.Second) {
+			periods = append(periods, time.Duration(c.TimePeriod)*time.Second)
+		}
+	}
+	if s.Script != nil && !util.DurationSliceContains(periods, s.Script.period()) {
+		periods = append(periods, s.Script.period())
 	}
 	sort.Slice(periods, func(i, j int) bool {
 		return periods[i] < periods[j]
@@ -202,6 +396,83 @@ func (s Signal) GetPeriods() []time.Duration {
 	return periods
 }
 
+// LastBar returns the start of the last bar of the signal's time period on the runner.
+func (s Signal) LastBar(r *runner.Runner) (time.Time, bool) {
+	if r == nil {
+		return time.Time{}, false
+	}
+	return barAt(r, s.period(r), 0)
+}
+
+// period returns the time period of the signal, the smallest frame of the runner if it's unknown.
+func (s Signal) period(r *runner.Runner) time.Duration {
+	if s.TimePeriod == 0 {
+		return r.SmallestFrame()
+	}
+	return s.TimePeriod
+}
+
+// barAt returns the start of the bar of the period, the given number of bars before the last one.
+func barAt(r *runner.Runner, period time.Duration, shift int) (time.Time, bool) {
+	line, ok := r.GetLines(period)
+	if !ok || line == nil {
+		return time.Time{}, false
+	}
+	cd := line.CandleByIndex(len(line.Candles.Candles) - 1 - shift)
+	if cd == nil {
+		return time.Time{}, false
+	}
+	return cd.Period.Start, true
+}
+
+// conditions returns the conditions of the signal's rule, or of its stages.
+func (s Signal) conditions() Conditions {
+	out := s.Rule.conditions()
+	for _, n := range s.Stages.rules() {
+		out = append(out, n.conditions()...)
+	}
+	return out
+}
+
+// comparables returns all the comparables of the signal's conditions, exits and sizing,
+// including the operands of the arithmetic comparables.
+func (s Signal) comparables() []*Comparable {
+	var out []*Comparable
+	for _, c := range s.conditions() {
+		out = append(out, c.This.flatten()...)
+		out = append(out, c.That.flatten()...)
+	}
+	if s.Exit != nil {
+		out = append(out, s.Exit.comparables()...)
+	}
+	if l := s.Sizing.atr(); l != nil {
+		out = append(out, l.comparable())
+	}
+	return out
+}
+
+// NeedsDepth returns true if the signal has conditions on the order book, the runner
+// needs to be streamed with the depth data for the signal to be evaluated.
+func (s Signal) NeedsDepth() bool {
+	for _, c := range s.comparables() {
+		if c != nil && c.Depth != nil && DepthLevel(c.Depth.Name) != DepthFixed {
+			return true
+		}
+	}
+	return false
+}
+
+// NeedsTrades returns true if the signal has conditions on the recent trades, the runner
+// needs to be streamed with the trade data for the signal to be evaluated.
+func (s Signal) NeedsTrades() bool {
+	for _, c := range s.comparables() {
+		if c != nil && c.Trade != nil && TradeLevel(c.Trade.Name) != TradeFixed {
+			return true
+		}
+	}
+	return false
+}
+
 // encodeNotify returns float64 ranging from -1 to 1 depends on signal notification option.
 // the valid values ranges from 0 to 1,
 // -1 means given data is wrong and won't be accepted.
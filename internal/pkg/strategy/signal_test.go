@@ -1,6 +1,7 @@
 package strategy
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"testing"
 	"time"
@@ -37,7 +38,7 @@ func Test_Signal(t *testing.T) {
 	ok = r.SyncCandle(candle1)
 	assert.EqualValues(t, true, ok)
 
-	for _, g := range signal.Groups {
+	for _, g := range signal.Rule.Nodes {
 		err := g.validate()
 		assert.EqualValues(t, nil, err)
 
@@ -97,3 +98,27 @@ func Test_GetMaxWaitToFill(t *testing.T) {
 	assert.EqualValues(t, true, ok)
 	assert.EqualValues(t, time.Minute, wait)
 }
+
+func Test_Evaluation(t *testing.T) {
+	path := "./signals/signal.json"
+	raw, err := ioutil.ReadFile(path)
+	assert.EqualValues(t, nil, err)
+
+	signal, err := NewSignalFromBytes(raw)
+	assert.EqualValues(t, nil, err)
+	assert.EqualValues(t, false, signal.IsIntrabar())
+
+	var data map[string]interface{}
+	assert.EqualValues(t, nil, json.Unmarshal(raw, &data))
+	data["evaluation"] = map[string]interface{}{"mode": "intrabar", "min_interval": 10}
+	raw, _ = json.Marshal(data)
+	signal, err = NewSignalFromBytes(raw)
+	assert.EqualValues(t, nil, err)
+	assert.EqualValues(t, true, signal.IsIntrabar())
+	assert.EqualValues(t, 10*time.Second, signal.copy().GetMinInterval())
+
+	data["evaluation"] = map[string]interface{}{"mode": "tick"}
+	raw, _ = json.Marshal(data)
+	_, err = NewSignalFromBytes(raw)
+	assert.EqualValues(t, "evaluation.mode: invalid evaluation mode", err.Error())
+}
@@ -0,0 +1,260 @@
+package strategy
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	ta "github.com/heyphat/techan"
+	"github.com/sdcoffey/big"
+
+	"follow.markets/internal/pkg/runner"
+	tax "follow.markets/internal/pkg/techanex"
+	"follow.markets/pkg/util"
+)
+
+// the start of the candles of a signal test without start times, a Monday.
+var signalTestStart = time.Date(2022, 1, 3, 0, 0, 0, 0, time.UTC)
+
+// SignalTest is a test case of a signal. Its candles, of the smallest time period of the signal,
+// are replayed through a runner and the signal is expected to trigger on the candles of the
+// expected indices, from 0, and on none of the others. The candles are either given inline or
+// as a CSV file, relative to the signal file, with the columns start, open, high, low, close,
+// volume and optionally trades, and an optional header. The start is a unix time in second or
+// an RFC3339 time, the candles follow each other from a fixed Monday if it's missing.
+type SignalTest struct {
+	Name     string        `json:"name"`
+	Ticker   string        `json:"ticker,omitempty"` // BTCUSDT by default
+	Candles  []*TestCandle `json:"candles,omitempty"`
+	CSV      string        `json:"csv,omitempty"`
+	Expected []int         `json:"expected"`
+}
+
+// TestCandle is a candle of a signal test, the prices are strings to keep their precision.
+type TestCandle struct {
+	Start  int64  `json:"start,omitempty"` // unix time in second
+	Open   string `json:"open"`
+	High   string `json:"high"`
+	Low    string `json:"low"`
+	Close  string `json:"close"`
+	Volume string `json:"volume"`
+	Trades uint   `json:"trades,omitempty"`
+}
+
+// SignalTestResult is the outcome of a signal test, the expected indices the signal didn't
+// trigger on are missing, the others it triggered on are unexpected.
+type SignalTestResult struct {
+	Name       string `json:"name"`
+	Expected   []int  `json:"expected"`
+	Triggered  []int  `json:"triggered"`
+	Missing    []int  `json:"missing,omitempty"`
+	Unexpected []int  `json:"unexpected,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Passed returns true if the signal triggered on the expected candles only.
+func (res SignalTestResult) Passed() bool {
+	return len(res.Error) == 0 && len(res.Missing) == 0 && len(res.Unexpected) == 0
+}
+
+func (st *SignalTest) validate() error {
+	if (len(st.Candles) == 0) == (len(st.CSV) == 0) {
+		return errors.New("a signal test must have either candles or a csv")
+	}
+	for _, i := range st.Expected {
+		if i < 0 || (len(st.Candles) > 0 && i >= len(st.Candles)) {
+			return errors.New("signal test " + st.Name + " expects an out of range candle " + strconv.Itoa(i))
+		}
+	}
+	for j, tc := range st.Candles {
+		if tc == nil {
+			return atPath(errors.New("missing candle"), indexPath("candles", j))
+		}
+		if err := tc.validate(); err != nil {
+			return atPath(err, indexPath("candles", j))
+		}
+	}
+	return nil
+}
+
+// validate checks the prices and the volume of the candle are numbers, like the ones of a CSV.
+func (tc *TestCandle) validate() error {
+	for _, v := range []struct{ name, value string }{
+		{"open", tc.Open}, {"high", tc.High}, {"low", tc.Low}, {"close", tc.Close}, {"volume", tc.Volume},
+	} {
+		if _, err := strconv.ParseFloat(v.value, 64); err != nil {
+			return atPath(errors.New("must be a number"), v.name)
+		}
+	}
+	return nil
+}
+
+// RunTestFile loads the signal of the file and runs its tests.
+func RunTestFile(path string) (*Signal, []*SignalTestResult, error) {
+	bts, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	s, err := NewSignalFromBytes(bts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s, s.RunTests(filepath.Dir(path)), nil
+}
+
+// RunTests runs the tests of the signal, the CSV files are relative to the given directory. The
+// signals referring to other signals and the pairs signals can't be tested on their own.
+func (s Signal) RunTests(dir string) []*SignalTestResult {
+	out := make([]*SignalTestResult, 0, len(s.Tests))
+	for _, st := range s.Tests {
+		res := &SignalTestResult{Name: st.Name, Expected: st.Expected, Triggered: []int{}}
+		if err := s.runTest(st, dir, res); err != nil {
+			res.Error = err.Error()
+		}
+		out = append(out, res)
+	}
+	return out
+}
+
+func (s Signal) runTest(st *SignalTest, dir string, res *SignalTestResult) error {
+	if s.IsPair() || len(s.References()) > 0 {
+		return errors.New("a signal with a pair or references can't be tested on its own")
+	}
+	ticker := st.Ticker
+	if len(ticker) == 0 {
+		ticker = "BTCUSDT"
+	}
+	periods := s.GetPeriods()
+	if len(periods) == 0 {
+		periods = []time.Duration{time.Minute}
+	}
+	r := runner.NewRunner(ticker, &runner.RunnerConfigs{
+		LFrames:  periods,
+		IConfigs: tax.NewDefaultIndicatorConfigs(),
+	})
+	candles, err := st.candles(dir, r.SmallestFrame())
+	if err != nil {
+		return err
+	}
+	// a copy of the signal keeps the states of its runs apart from the other tests.
+	sc := Signals{&s}.Copy()[0]
+	var state *StageState
+	for i, c := range candles {
+		if !r.SyncCandle(c) {
+			return errors.New("failed to sync candle " + strconv.Itoa(i))
+		}
+		// the schedules without a timezone are in UTC.
+		if !sc.IsActive(r, c.Period.End, time.UTC) {
+			continue
+		}
+		fired := false
+		if sc.IsStaged() {
+			state, _, fired = sc.Advance(r, state)
+		} else {
+			fired = sc.Evaluate(r, nil)
+		}
+		if fired {
+			res.Triggered = append(res.Triggered, i)
+		}
+	}
+	for _, i := range st.Expected {
+		if !util.IntSliceContains(res.Triggered, i) {
+			res.Missing = append(res.Missing, i)
+		}
+	}
+	for _, i := range res.Triggered {
+		if !util.IntSliceContains(st.Expected, i) {
+			res.Unexpected = append(res.Unexpected, i)
+		}
+	}
+	return nil
+}
+
+// candles returns the candles of the test, of the given period.
+func (st *SignalTest) candles(dir string, period time.Duration) ([]*ta.Candle, error) {
+	tcs := st.Candles
+	if len(st.CSV) > 0 {
+		path := st.CSV
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		var err error
+		if tcs, err = readTestCandles(path); err != nil {
+			return nil, err
+		}
+	}
+	out := make([]*ta.Candle, 0, len(tcs))
+	for i, tc := range tcs {
+		start := signalTestStart.Add(time.Duration(i) * period)
+		if tc.Start > 0 {
+			start = time.Unix(tc.Start, 0)
+		}
+		c := ta.NewCandle(ta.NewTimePeriod(start, period))
+		c.OpenPrice = big.NewFromString(tc.Open)
+		c.MaxPrice = big.NewFromString(tc.High)
+		c.MinPrice = big.NewFromString(tc.Low)
+		c.ClosePrice = big.NewFromString(tc.Close)
+		c.Volume = big.NewFromString(tc.Volume)
+		c.TradeCount = tc.Trades
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// readTestCandles reads the candles of a CSV file, see SignalTest.
+func readTestCandles(path string) ([]*TestCandle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	var out []*TestCandle
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < 6 {
+			return nil, errors.New("invalid candle at line " + strconv.Itoa(line))
+		}
+		if _, err := strconv.ParseFloat(record[1], 64); err != nil && line == 1 {
+			continue // the header
+		}
+		for _, v := range record[1:6] {
+			if _, err := strconv.ParseFloat(v, 64); err != nil {
+				return nil, errors.New("invalid candle at line " + strconv.Itoa(line))
+			}
+		}
+		tc := &TestCandle{Open: record[1], High: record[2], Low: record[3], Close: record[4], Volume: record[5]}
+		if start := strings.TrimSpace(record[0]); len(start) > 0 {
+			if tc.Start, err = strconv.ParseInt(start, 10, 64); err != nil {
+				t, err := time.Parse(time.RFC3339, start)
+				if err != nil {
+					return nil, errors.New("invalid candle start at line " + strconv.Itoa(line))
+				}
+				tc.Start = t.Unix()
+			}
+		}
+		if len(record) > 6 && len(record[6]) > 0 {
+			trades, err := strconv.ParseUint(record[6], 10, 64)
+			if err != nil {
+				return nil, errors.New("invalid candle trades at line " + strconv.Itoa(line))
+			}
+			tc.Trades = uint(trades)
+		}
+		out = append(out, tc)
+	}
+	return out, nil
+}
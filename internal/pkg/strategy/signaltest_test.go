@@ -0,0 +1,50 @@
+package strategy
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SignalTests(t *testing.T) {
+	dir := t.TempDir()
+	csv := "start,open,high,low,close,volume\n,1,4,1,4,1\n,4,6,3,6,1\n,6,6,5,5,1\n"
+	assert.EqualValues(t, nil, ioutil.WriteFile(filepath.Join(dir, "candles.csv"), []byte(csv), 0644))
+	path := filepath.Join(dir, "signal.json")
+	assert.EqualValues(t, nil, ioutil.WriteFile(path, []byte(`{"name": "tested", "expression": "CLOSE > 4.5", "tests": [
+		{"name": "inline", "candles": [
+			{"open": "1", "high": "5", "low": "1", "close": "5", "volume": "1"},
+			{"open": "5", "high": "5", "low": "3", "close": "4", "volume": "1"}
+		], "expected": [0]},
+		{"name": "csv", "csv": "candles.csv", "expected": [0, 1]}
+	]}`), 0644))
+
+	s, results, err := RunTestFile(path)
+	assert.EqualValues(t, nil, err)
+	assert.EqualValues(t, "tested", s.Name)
+	assert.EqualValues(t, 2, len(results))
+	assert.EqualValues(t, true, results[0].Passed())
+	assert.EqualValues(t, []int{0}, results[0].Triggered)
+	assert.EqualValues(t, false, results[1].Passed())
+	assert.EqualValues(t, []int{1, 2}, results[1].Triggered)
+	assert.EqualValues(t, []int{0}, results[1].Missing)
+	assert.EqualValues(t, []int{2}, results[1].Unexpected)
+
+	// the tests aren't kept by the copies.
+	assert.EqualValues(t, 0, len(Signals{s}.Copy()[0].Tests))
+
+	_, err = NewSignalFromBytes([]byte(`{"name": "tested", "expression": "CLOSE > 4.5", "tests": [{"name": "empty", "expected": [0]}]}`))
+	assert.EqualValues(t, "tests[0]: a signal test must have either candles or a csv", err.Error())
+	_, err = NewSignalFromBytes([]byte(`{"name": "tested", "expression": "CLOSE > 4.5", "tests": [{"name": "invalid", "expected": [], "candles": [
+		{"open": "1", "high": "5", "low": "1", "close": "5", "volume": "1"},
+		{"open": "abc", "high": "5", "low": "1", "close": "5", "volume": "1"}]}]}`))
+	assert.EqualValues(t, "tests[0].candles[1].open: must be a number", err.Error())
+	_, err = NewSignalFromBytes([]byte(`{"name": "tested", "expression": "CLOSE > 4.5", "tests": [{"name": "invalid", "expected": [], "candles": [
+		{"open": "1", "high": "", "low": "1", "close": "5", "volume": "1"}]}]}`))
+	assert.EqualValues(t, "tests[0].candles[0].high: must be a number", err.Error())
+
+	s.Tests[1].CSV = "missing.csv"
+	assert.NotEqual(t, "", s.RunTests(dir)[1].Error)
+}
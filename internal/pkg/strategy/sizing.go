@@ -0,0 +1,156 @@
+package strategy
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/sdcoffey/big"
+
+	"follow.markets/internal/pkg/runner"
+)
+
+const (
+	// the position sizing models.
+	FixedQuoteSizing    = "FIXED_QUOTE"
+	PercentEquitySizing = "PERCENT_EQUITY"
+	FixedRiskSizing     = "FIXED_RISK"
+	VolatilitySizing    = "VOLATILITY"
+	KellySizing         = "KELLY"
+
+	// the default cap of the Kelly fraction, in percent of the equity.
+	defaultKellyCap = 25.0
+)
+
+// Sizing defines the quantity of the orders opening the positions of a signal.
+//
+//   - FIXED_QUOTE trades the amount of the quote currency.
+//   - PERCENT_EQUITY trades the percent of the equity.
+//   - FIXED_RISK risks the percent of the equity on the distance to the signal's stop loss.
+//   - VOLATILITY risks the percent of the equity on a multiple of the ATR, 1 by default.
+//   - KELLY trades the Kelly fraction of the equity given the win rate and the payoff ratio,
+//     capped to 25 percent by default.
+//
+// The value of a position is capped to the max percent of the equity if it's set, and always
+// to the equity times the leverage.
+type Sizing struct {
+	Model      string  `json:"model"`
+	Amount     float64 `json:"amount,omitempty"`
+	Percent    float64 `json:"percent,omitempty"`
+	ATR        float64 `json:"atr,omitempty"`
+	ATRWindow  int     `json:"atr_window,omitempty"`
+	ATRPeriod  int     `json:"atr_period,omitempty"` // in second
+	WinRate    float64 `json:"win_rate,omitempty"`   // from 0 to 1
+	Payoff     float64 `json:"payoff,omitempty"`     // the average win over the average loss
+	MaxPercent float64 `json:"max_percent,omitempty"`
+}
+
+func (sz *Sizing) copy() *Sizing {
+	if sz == nil {
+		return nil
+	}
+	nsz := *sz
+	return &nsz
+}
+
+// Validate returns an error if the sizing model is unknown or misses its parameters.
+func (sz *Sizing) Validate() error {
+	if sz.Amount < 0 || sz.Percent < 0 || sz.ATR < 0 || sz.ATRWindow < 0 || sz.ATRPeriod < 0 || sz.MaxPercent < 0 {
+		return errors.New("invalid sizing")
+	}
+	switch strings.ToUpper(sz.Model) {
+	case FixedQuoteSizing:
+		if sz.Amount == 0 {
+			return errors.New("missing sizing amount")
+		}
+	case PercentEquitySizing, FixedRiskSizing, VolatilitySizing:
+		if sz.Percent == 0 {
+			return errors.New("missing sizing percent")
+		}
+	case KellySizing:
+		if sz.WinRate <= 0 || sz.WinRate >= 1 || sz.Payoff <= 0 {
+			return errors.New("kelly sizing must have a win rate between 0 and 1 and a positive payoff")
+		}
+	default:
+		return errors.New("unknown sizing model")
+	}
+	return nil
+}
+
+// atr returns the ATR level of the volatility sizing, nil for the other models.
+func (sz *Sizing) atr() *ExitLevel {
+	if sz == nil || strings.ToUpper(sz.Model) != VolatilitySizing {
+		return nil
+	}
+	multiple := sz.ATR
+	if multiple == 0 {
+		multiple = 1
+	}
+	return &ExitLevel{ATR: multiple, ATRWindow: sz.ATRWindow, ATRPeriod: sz.ATRPeriod}
+}
+
+// GetSizing returns the sizing of the signal, the given one if it doesn't have any.
+func (s Signal) GetSizing(sz *Sizing) *Sizing {
+	if s.Sizing != nil {
+		return s.Sizing
+	}
+	return sz
+}
+
+// Quantity returns the quantity of an order opening a position of the signal on the runner
+// at the price, false if the position can't be sized.
+func (sz *Sizing) Quantity(r *runner.Runner, s Signal, equity, price, leverage big.Decimal) (big.Decimal, bool) {
+	if sz == nil || price.LTE(big.ZERO) || equity.LTE(big.ZERO) {
+		return big.ZERO, false
+	}
+	if leverage.LTE(big.ZERO) {
+		leverage = big.ONE
+	}
+	var qty big.Decimal
+	switch strings.ToUpper(sz.Model) {
+	case FixedQuoteSizing:
+		qty = big.NewDecimal(sz.Amount).Div(price)
+	case PercentEquitySizing:
+		qty = equity.Mul(percent(sz.Percent)).Div(price)
+	case FixedRiskSizing:
+		distance, ok := s.stopDistance(r, price)
+		if !ok {
+			return big.ZERO, false
+		}
+		qty = equity.Mul(percent(sz.Percent)).Div(distance)
+	case VolatilitySizing:
+		distance, ok := sz.atr().distance(r, price)
+		if !ok {
+			return big.ZERO, false
+		}
+		qty = equity.Mul(percent(sz.Percent)).Div(distance)
+	case KellySizing:
+		fraction := sz.WinRate - (1-sz.WinRate)/sz.Payoff
+		if fraction <= 0 {
+			return big.ZERO, false
+		}
+		limit := sz.MaxPercent
+		if limit == 0 {
+			limit = defaultKellyCap
+		}
+		if fraction > limit/100 {
+			fraction = limit / 100
+		}
+		qty = equity.Mul(big.NewDecimal(fraction)).Div(price)
+	default:
+		return big.ZERO, false
+	}
+	caps := []big.Decimal{equity.Mul(leverage)}
+	if sz.MaxPercent > 0 {
+		caps = append(caps, equity.Mul(percent(sz.MaxPercent)))
+	}
+	for _, c := range caps {
+		if qty.Mul(price).GT(c) {
+			qty = c.Div(price)
+		}
+	}
+	return qty, qty.GT(big.ZERO)
+}
+
+func percent(p float64) big.Decimal {
+	return big.NewDecimal(p / 100)
+}
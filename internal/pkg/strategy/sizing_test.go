@@ -0,0 +1,65 @@
+package strategy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sdcoffey/big"
+	"github.com/stretchr/testify/assert"
+
+	"follow.markets/internal/pkg/runner"
+)
+
+func Test_Sizing(t *testing.T) {
+	r := runner.NewRunner("BTCUSDT", nil)
+	equity, price := big.NewDecimal(10000), big.NewDecimal(100)
+	signal := Signal{Exit: &Exit{StopLoss: &ExitLevel{Percent: 2}}}
+
+	sizes := []struct {
+		sizing *Sizing
+		qty    string
+	}{
+		{&Sizing{Model: FixedQuoteSizing, Amount: 500}, "5"},
+		{&Sizing{Model: PercentEquitySizing, Percent: 10}, "10"},
+		// 1% of the equity on a stop 2 below the price.
+		{&Sizing{Model: FixedRiskSizing, Percent: 1}, "50"},
+		{&Sizing{Model: FixedRiskSizing, Percent: 1, MaxPercent: 20}, "20"},
+		// the kelly fraction of 0.6 - 0.4 / 2 is capped to 25%.
+		{&Sizing{Model: KellySizing, WinRate: 0.6, Payoff: 2}, "25"},
+		{&Sizing{Model: KellySizing, WinRate: 0.6, Payoff: 2, MaxPercent: 50}, "40"},
+		// the position can't be more than the equity without leverage.
+		{&Sizing{Model: FixedQuoteSizing, Amount: 50000}, "100"},
+	}
+	for _, s := range sizes {
+		assert.EqualValues(t, nil, s.sizing.Validate())
+		qty, ok := s.sizing.Quantity(r, signal, equity, price, big.ONE)
+		assert.EqualValues(t, true, ok)
+		assert.EqualValues(t, s.qty, qty.String())
+	}
+	qty, ok := (&Sizing{Model: FixedQuoteSizing, Amount: 50000}).Quantity(r, signal, equity, price, big.NewDecimal(10))
+	assert.EqualValues(t, true, ok)
+	assert.EqualValues(t, "500", qty.String())
+
+	// no position without a stop, the ATR or an edge.
+	_, ok = (&Sizing{Model: FixedRiskSizing, Percent: 1}).Quantity(r, Signal{}, equity, price, big.ONE)
+	assert.EqualValues(t, false, ok)
+	_, ok = (&Sizing{Model: VolatilitySizing, Percent: 1}).Quantity(r, signal, equity, price, big.ONE)
+	assert.EqualValues(t, false, ok)
+	_, ok = (&Sizing{Model: KellySizing, WinRate: 0.3, Payoff: 1}).Quantity(r, signal, equity, price, big.ONE)
+	assert.EqualValues(t, false, ok)
+
+	assert.NotEqual(t, nil, (&Sizing{Model: "ALL_IN"}).Validate())
+	assert.NotEqual(t, nil, (&Sizing{Model: PercentEquitySizing}).Validate())
+	assert.NotEqual(t, nil, (&Sizing{Model: KellySizing, WinRate: 1, Payoff: 1}).Validate())
+
+	_, err := NewSignalFromBytes([]byte(`{"name": "risk", "expression": "CLOSE > 1", "sizing": {"model": "FIXED_RISK", "percent": 1}}`))
+	assert.NotEqual(t, nil, err)
+	assert.EqualValues(t, &Sizing{Model: "A"}, Signal{Sizing: &Sizing{Model: "A"}}.GetSizing(&Sizing{Model: "B"}))
+	assert.EqualValues(t, &Sizing{Model: "B"}, Signal{}.GetSizing(&Sizing{Model: "B"}))
+
+	// the frame of the ATR of the volatility sizing is among the periods of the signal.
+	signal = Signal{Sizing: &Sizing{Model: VolatilitySizing, Percent: 1, ATRPeriod: 300}}
+	assert.EqualValues(t, []time.Duration{5 * time.Minute}, signal.GetPeriods())
+	signal = Signal{Sizing: &Sizing{Model: PercentEquitySizing, Percent: 1, ATRPeriod: 300}}
+	assert.EqualValues(t, 0, len(signal.GetPeriods()))
+}
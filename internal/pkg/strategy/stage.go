@@ -0,0 +1,253 @@
+package strategy
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"follow.markets/internal/pkg/runner"
+)
+
+// IdleStage is the initial stage of a staged signal if it's not given.
+const IdleStage = "IDLE"
+
+// Stages makes the signal a staged signal, defined as stages and the transitions between
+// them instead of a rule. The signal starts at the initial stage and fires when it reaches a
+// final stage, a stage without transitions from it, after which it's back to the initial stage.
+// It's also back to the initial stage when the reset rule is satisfied, or when none of the
+// transitions from its stage can be taken within their max bars. The reset rule is either given
+// as a rule or as an expression, see CompileExpression.
+type Stages struct {
+	Initial         string        `json:"initial,omitempty"`
+	Transitions     []*Transition `json:"transitions"`
+	Reset           *RuleNode     `json:"reset,omitempty"`
+	ResetExpression string        `json:"reset_expression,omitempty"`
+}
+
+// Transition moves a staged signal from a stage to another when its rule is satisfied, within
+// the max bars of the signal's time period since the stage was entered, without limit if it's
+// not given. A stage is left at the earliest on the bar after it was entered. The transition is
+// notified if it's set to, the transitions to a final stage are always notified as the signal
+// fires.
+type Transition struct {
+	From       string    `json:"from,omitempty"` // the initial stage by default
+	To         string    `json:"to"`
+	Rule       *RuleNode `json:"rule,omitempty"`
+	Expression string    `json:"expression,omitempty"`
+	MaxBars    int       `json:"max_bars,omitempty"`
+	Notify     bool      `json:"notify,omitempty"`
+}
+
+// MarshalJSON encodes the stages, without the reset rule compiled from the reset expression.
+func (st Stages) MarshalJSON() ([]byte, error) {
+	type stages Stages
+	out := stages(st)
+	if len(strings.TrimSpace(st.ResetExpression)) > 0 {
+		out.Reset = nil
+	}
+	return json.Marshal(out)
+}
+
+// MarshalJSON encodes the transition, without the rule compiled from its expression.
+func (tr Transition) MarshalJSON() ([]byte, error) {
+	type transition Transition
+	out := transition(tr)
+	if len(strings.TrimSpace(tr.Expression)) > 0 {
+		out.Rule = nil
+	}
+	return json.Marshal(out)
+}
+
+// StageState is the stage of a staged signal on a runner, and the start of the bar it was
+// entered on.
+type StageState struct {
+	Name    string    `json:"name"`
+	Entered time.Time `json:"entered"`
+}
+
+func (st *Stages) copy() *Stages {
+	if st == nil {
+		return nil
+	}
+	nst := *st
+	nst.Reset = st.Reset.copy()
+	nst.Transitions = make([]*Transition, 0, len(st.Transitions))
+	for _, tr := range st.Transitions {
+		ntr := *tr
+		ntr.Rule = tr.Rule.copy()
+		nst.Transitions = append(nst.Transitions, &ntr)
+	}
+	return &nst
+}
+
+// compile compiles the expressions of the transitions and the reset to their rules, the
+// transitions without a stage to go from go from the initial stage.
+func (st *Stages) compile() error {
+	for i, tr := range st.Transitions {
+		if tr == nil {
+			continue
+		}
+		tr.From = tr.from(st)
+		if len(strings.TrimSpace(tr.Expression)) == 0 {
+			continue
+		}
+		if !tr.Rule.isEmpty() {
+			return atPath(errors.New("a transition must have either a rule or an expression"), indexPath("transitions", i))
+		}
+		rule, err := CompileExpression(tr.Expression)
+		if err != nil {
+			return atExpression(err, indexPath("transitions", i)+".expression")
+		}
+		tr.Rule = rule
+	}
+	if len(strings.TrimSpace(st.ResetExpression)) == 0 {
+		return nil
+	}
+	if !st.Reset.isEmpty() {
+		return errors.New("a reset must have either a rule or an expression")
+	}
+	rule, err := CompileExpression(st.ResetExpression)
+	if err != nil {
+		return atExpression(err, "reset_expression")
+	}
+	st.Reset = rule
+	return nil
+}
+
+func (st *Stages) validate() error {
+	if len(st.Transitions) == 0 {
+		return atPath(errors.New("missing stage transitions"), "transitions")
+	}
+	reached := map[string]bool{st.initial(): true}
+	for i, tr := range st.Transitions {
+		if tr == nil {
+			return atPath(errors.New("missing stage transition"), indexPath("transitions", i))
+		}
+		reached[tr.To] = true
+	}
+	hasFinal := false
+	for i, tr := range st.Transitions {
+		path := indexPath("transitions", i)
+		if len(strings.TrimSpace(tr.To)) == 0 || tr.To == st.initial() {
+			return atPath(errors.New("a transition must go to a stage other than the initial one"), path+".to")
+		}
+		if !reached[tr.from(st)] {
+			return atPath(errors.New("unreachable stage "+tr.from(st)), path+".from")
+		}
+		if tr.MaxBars < 0 {
+			return atPath(errors.New("invalid transition max bars"), path+".max_bars")
+		}
+		if tr.Rule == nil {
+			return atPath(errors.New("missing transition rule"), path+".rule")
+		}
+		if err := tr.Rule.validate(); err != nil {
+			if len(strings.TrimSpace(tr.Expression)) > 0 {
+				return atExpression(err, path+".expression")
+			}
+			return atPath(err, path+".rule")
+		}
+		hasFinal = hasFinal || st.IsFinal(tr.To)
+	}
+	if !hasFinal {
+		return errors.New("missing final stage")
+	}
+	if st.Reset != nil {
+		if err := st.Reset.validate(); err != nil {
+			if len(strings.TrimSpace(st.ResetExpression)) > 0 {
+				return atExpression(err, "reset_expression")
+			}
+			return atPath(err, "reset")
+		}
+	}
+	return nil
+}
+
+func (st *Stages) initial() string {
+	if st.Initial == "" {
+		return IdleStage
+	}
+	return st.Initial
+}
+
+func (tr *Transition) from(st *Stages) string {
+	if tr.From == "" {
+		return st.initial()
+	}
+	return tr.From
+}
+
+// IsFinal returns true if there is no transition from the stage.
+func (st *Stages) IsFinal(name string) bool {
+	for _, tr := range st.Transitions {
+		if tr.from(st) == name {
+			return false
+		}
+	}
+	return true
+}
+
+// rules returns the rules of the transitions and the reset.
+func (st *Stages) rules() []*RuleNode {
+	if st == nil {
+		return nil
+	}
+	var out []*RuleNode
+	for _, tr := range st.Transitions {
+		out = append(out, tr.Rule)
+	}
+	if st.Reset != nil {
+		out = append(out, st.Reset)
+	}
+	return out
+}
+
+// IsStaged returns true if the signal is defined as stages.
+func (s Signal) IsStaged() bool {
+	return s.Stages != nil
+}
+
+// Advance evaluates the transitions of the staged signal from the given state on the runner,
+// the initial stage if it's nil, and takes the first one satisfied. It returns the new state,
+// the transition taken, nil if there is none, and true if the signal fires. A fired signal is
+// back to its initial stage.
+func (s Signal) Advance(r *runner.Runner, state *StageState) (*StageState, *Transition, bool) {
+	if s.Stages == nil || r == nil {
+		return state, nil, false
+	}
+	period := s.period(r)
+	bar, ok := barAt(r, period, 0)
+	if !ok {
+		return state, nil, false
+	}
+	initial := &StageState{Name: s.Stages.initial(), Entered: bar}
+	if state == nil {
+		state = initial
+	}
+	if state.Name != initial.Name {
+		if s.Stages.Reset != nil && s.Stages.Reset.evaluate(r, nil) {
+			return initial, nil, false
+		}
+		if !bar.After(state.Entered) {
+			return state, nil, false
+		}
+	}
+	bars, isWaiting := int(bar.Sub(state.Entered)/period), false
+	for _, tr := range s.Stages.Transitions {
+		if tr.from(s.Stages) != state.Name || (tr.MaxBars > 0 && state.Name != initial.Name && bars > tr.MaxBars) {
+			continue
+		}
+		isWaiting = true
+		if !tr.Rule.evaluate(r, nil) {
+			continue
+		}
+		if s.Stages.IsFinal(tr.To) {
+			return initial, tr, true
+		}
+		return &StageState{Name: tr.To, Entered: bar}, tr, false
+	}
+	if !isWaiting {
+		return initial, nil, false
+	}
+	return state, nil, false
+}
@@ -0,0 +1,72 @@
+package strategy
+
+import (
+	"testing"
+	"time"
+
+	bn "github.com/adshao/go-binance/v2"
+	"github.com/stretchr/testify/assert"
+
+	"follow.markets/internal/pkg/runner"
+	tax "follow.markets/internal/pkg/techanex"
+)
+
+func Test_Stages(t *testing.T) {
+	signal, err := NewSignalFromBytes([]byte(`{"name": "staged", "stages": {"transitions": [
+		{"to": "ARMED", "expression": "CLOSE > 5", "notify": true},
+		{"from": "ARMED", "to": "TRIGGER", "expression": "CLOSE < 5", "max_bars": 2}
+	], "reset_expression": "CLOSE > 10"}}`))
+	assert.EqualValues(t, nil, err)
+	assert.EqualValues(t, true, signal.IsStaged())
+	assert.EqualValues(t, time.Minute, signal.TimePeriod)
+
+	_, err = NewSignalFromBytes([]byte(`{"name": "staged", "stages": {"transitions": [
+		{"from": "ARMED", "to": "TRIGGER", "expression": "CLOSE < 5"}]}}`))
+	assert.EqualValues(t, "stages.transitions[0].from: unreachable stage ARMED", err.Error())
+
+	_, err = NewSignalFromBytes([]byte(`{"name": "staged", "stages": {"transitions": [
+		{"to": "ARMED", "expression": "CLOSE > 5"}, {"from": "ARMED", "to": "IDLE", "expression": "CLOSE < 5"}]}}`))
+	assert.EqualValues(t, "stages.transitions[1].to: a transition must go to a stage other than the initial one", err.Error())
+
+	r := runner.NewRunner("BTCUSDT", nil)
+	start := time.Unix(1499040000, 0)
+	sync := func(i int, close string) {
+		kline := &bn.Kline{OpenTime: start.Add(time.Duration(i)*time.Minute).Unix() * 1000, Open: "1", High: close, Low: "0", Close: close, Volume: "1", TradeNum: 1}
+		assert.EqualValues(t, true, r.SyncCandle(tax.ConvertBinanceKline(kline, nil)))
+	}
+
+	// armed, then triggered on a later bar.
+	sync(0, "6")
+	state, tr, fired := signal.Advance(r, nil)
+	assert.EqualValues(t, "ARMED", state.Name)
+	assert.EqualValues(t, true, tr.Notify)
+	assert.EqualValues(t, false, fired)
+	state, tr, fired = signal.Advance(r, state)
+	assert.EqualValues(t, "ARMED", state.Name)
+	assert.True(t, tr == nil)
+	sync(1, "4")
+	state, tr, fired = signal.Advance(r, state)
+	assert.EqualValues(t, IdleStage, state.Name)
+	assert.EqualValues(t, "TRIGGER", tr.To)
+	assert.EqualValues(t, true, fired)
+
+	// armed, then timed out.
+	sync(2, "6")
+	state, _, _ = signal.Advance(r, state)
+	assert.EqualValues(t, "ARMED", state.Name)
+	sync(3, "6")
+	sync(4, "6")
+	state, _, _ = signal.Advance(r, state)
+	assert.EqualValues(t, "ARMED", state.Name)
+	sync(5, "6")
+	state, _, _ = signal.Advance(r, state)
+	assert.EqualValues(t, IdleStage, state.Name)
+
+	// armed, then reset.
+	state, _, _ = signal.Advance(r, state)
+	assert.EqualValues(t, "ARMED", state.Name)
+	sync(6, "11")
+	state, _, fired = signal.Advance(r, state)
+	assert.EqualValues(t, IdleStage, state.Name)
+	assert.EqualValues(t, false, fired)
+}
@@ -7,7 +7,7 @@ import (
 
 type Strategy struct {
 	EntryRule      *GenericRule
-	ExitRule       *GenericRule
+	ExitRule       *ExitRule
 	RiskRewardRule *RiskRewardRule
 }
 
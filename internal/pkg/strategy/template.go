@@ -0,0 +1,300 @@
+package strategy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// the types of the template parameters.
+	IntParam    = "INT"
+	FloatParam  = "FLOAT"
+	PeriodParam = "PERIOD" // a time frame of the expressions, e.g. 5m, 1h or 1d
+	ChoiceParam = "CHOICE"
+
+	// the parameter every template has, the type of the signal.
+	sideParam = "side"
+)
+
+// Template is a parameterized signal, it expands to the signal's expression with the values of
+// its typed parameters. Every template has the side parameter, BULLISH or BEARISH, which is the
+// type of the signal.
+type Template struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Params      []*TemplateParam `json:"params"`
+
+	expression func(v TemplateValues) string
+	// check returns an error if the parameters are inconsistent with each other.
+	check func(v TemplateValues) error
+}
+
+// TemplateParam is a typed parameter of a template, with its default value and its bounds.
+type TemplateParam struct {
+	Name        string      `json:"name"`
+	Type        string      `json:"type"`
+	Description string      `json:"description"`
+	Default     interface{} `json:"default"`
+	Min         *float64    `json:"min,omitempty"`
+	Max         *float64    `json:"max,omitempty"`
+	Choices     []string    `json:"choices,omitempty"`
+}
+
+// TemplateValues are the values of the parameters of a template, numbers are float64.
+type TemplateValues map[string]interface{}
+
+// TemplateInstance is a request to instantiate a template to a signal of the given name, the
+// notify and track types are FIRST and CONTINUOUS by default.
+type TemplateInstance struct {
+	Name       string         `json:"name"`
+	NotifyType string         `json:"notify_type,omitempty"`
+	TrackType  string         `json:"track_type,omitempty"`
+	Params     TemplateValues `json:"params"`
+}
+
+func bound(v float64) *float64 { return &v }
+
+func sideTemplateParam() *TemplateParam {
+	return &TemplateParam{Name: sideParam, Type: ChoiceParam, Description: "the type of the signal", Default: BullishSignal, Choices: []string{BullishSignal, BearishSignal}}
+}
+
+func frameTemplateParam(frame string) *TemplateParam {
+	return &TemplateParam{Name: "frame", Type: PeriodParam, Description: "the time frame of the candles", Default: frame}
+}
+
+// templates are the built-in templates by name.
+var templates = map[string]*Template{
+	"BREAKOUT": {
+		Name:        "BREAKOUT",
+		Description: "the close breaks out of the highest high, or the lowest low, of the previous bars",
+		Params: []*TemplateParam{
+			sideTemplateParam(),
+			frameTemplateParam("1h"),
+			{Name: "window", Type: IntParam, Description: "the number of previous bars", Default: 20.0, Min: bound(2), Max: bound(50)},
+		},
+		expression: func(v TemplateValues) string {
+			levels := make([]string, 0, v.int("window"))
+			level, fn, opt := "HIGH", "max", ">"
+			if v.isBearish() {
+				level, fn, opt = "LOW", "min", "<"
+			}
+			for i := 1; i <= v.int("window"); i++ {
+				levels = append(levels, fmt.Sprintf("%s@%s[%d]", level, v.str("frame"), i))
+			}
+			return fmt.Sprintf("CLOSE@%s %s %s(%s)", v.str("frame"), opt, fn, strings.Join(levels, ", "))
+		},
+	},
+	"MEAN_REVERSION": {
+		Name:        "MEAN_REVERSION",
+		Description: "the RSI is oversold and the close is below the lower Bollinger band, or overbought and above the upper band",
+		Params: []*TemplateParam{
+			sideTemplateParam(),
+			frameTemplateParam("15m"),
+			{Name: "rsi_window", Type: IntParam, Description: "the window of the RSI", Default: 14.0, Min: bound(2)},
+			{Name: "band_window", Type: IntParam, Description: "the window of the Bollinger bands", Default: 20.0, Min: bound(2)},
+			{Name: "oversold", Type: FloatParam, Description: "the RSI level of a bullish signal", Default: 30.0, Min: bound(0), Max: bound(100)},
+			{Name: "overbought", Type: FloatParam, Description: "the RSI level of a bearish signal", Default: 70.0, Min: bound(0), Max: bound(100)},
+		},
+		expression: func(v TemplateValues) string {
+			if v.isBearish() {
+				return fmt.Sprintf("RSI(%d)@%s > %s and CLOSE@%s > BBU(%d)@%s", v.int("rsi_window"), v.str("frame"), v.str("overbought"), v.str("frame"), v.int("band_window"), v.str("frame"))
+			}
+			return fmt.Sprintf("RSI(%d)@%s < %s and CLOSE@%s < BBL(%d)@%s", v.int("rsi_window"), v.str("frame"), v.str("oversold"), v.str("frame"), v.int("band_window"), v.str("frame"))
+		},
+		check: func(v TemplateValues) error {
+			if v.float("oversold") >= v.float("overbought") {
+				return errors.New("template parameter oversold must be less than overbought")
+			}
+			return nil
+		},
+	},
+	"MA_CROSSOVER": {
+		Name:        "MA_CROSSOVER",
+		Description: "the fast EMA crosses above, or below, the slow EMA",
+		Params: []*TemplateParam{
+			sideTemplateParam(),
+			frameTemplateParam("5m"),
+			{Name: "fast", Type: IntParam, Description: "the window of the fast EMA", Default: 9.0, Min: bound(1)},
+			{Name: "slow", Type: IntParam, Description: "the window of the slow EMA", Default: 26.0, Min: bound(2)},
+		},
+		expression: func(v TemplateValues) string {
+			opt := "crosses_above"
+			if v.isBearish() {
+				opt = "crosses_below"
+			}
+			return fmt.Sprintf("EMA(%d)@%s %s EMA(%d)@%s", v.int("fast"), v.str("frame"), opt, v.int("slow"), v.str("frame"))
+		},
+		check: func(v TemplateValues) error {
+			if v.int("fast") >= v.int("slow") {
+				return errors.New("template parameter fast must be less than slow")
+			}
+			return nil
+		},
+	},
+	"VOLUME_SPIKE": {
+		Name:        "VOLUME_SPIKE",
+		Description: "the volume is a multiple of its moving average on an up, or a down, candle",
+		Params: []*TemplateParam{
+			sideTemplateParam(),
+			frameTemplateParam("5m"),
+			{Name: "window", Type: IntParam, Description: "the window of the volume moving average", Default: 20.0, Min: bound(2)},
+			{Name: "multiple", Type: FloatParam, Description: "the multiple of the volume moving average", Default: 3.0, Min: bound(1)},
+		},
+		expression: func(v TemplateValues) string {
+			opt := ">"
+			if v.isBearish() {
+				opt = "<"
+			}
+			return fmt.Sprintf("VOLUME@%s > %s * VMA(%d)@%s and CLOSE@%s %s OPEN@%s", v.str("frame"), v.str("multiple"), v.int("window"), v.str("frame"), v.str("frame"), opt, v.str("frame"))
+		},
+	},
+}
+
+// Templates returns the built-in templates sorted by name.
+func Templates() []*Template {
+	out := make([]*Template, 0, len(templates))
+	for _, t := range templates {
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// GetTemplate returns the built-in template of the given name.
+func GetTemplate(name string) (*Template, bool) {
+	t, ok := templates[strings.ToUpper(name)]
+	return t, ok
+}
+
+// Instantiate expands the template with the parameters of the instance to a signal, the missing
+// parameters take their default values.
+func (t *Template) Instantiate(in *TemplateInstance) (*Signal, error) {
+	if in == nil || len(strings.TrimSpace(in.Name)) == 0 {
+		return nil, errors.New("missing signal name")
+	}
+	values, err := t.values(in.Params)
+	if err != nil {
+		return nil, err
+	}
+	notifyType, trackType := in.NotifyType, in.TrackType
+	if notifyType == "" {
+		notifyType = FstNotify
+	}
+	if trackType == "" {
+		trackType = ContinuousTrack
+	}
+	bts, err := json.Marshal(map[string]interface{}{
+		"name":        in.Name,
+		"notify_type": notifyType,
+		"track_type":  trackType,
+		"signal_type": values.str(sideParam),
+		"expression":  t.expression(values),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return NewSignalFromBytes(bts)
+}
+
+// values returns the typed values of the parameters, it returns an error if a parameter is
+// unknown, of the wrong type, out of bounds or inconsistent with the others.
+func (t *Template) values(params TemplateValues) (TemplateValues, error) {
+	out := TemplateValues{}
+	for name := range params {
+		if t.param(name) == nil {
+			return nil, errors.New("unknown template parameter " + name)
+		}
+	}
+	for _, p := range t.Params {
+		val, ok := params[p.Name]
+		if !ok || val == nil {
+			val = p.Default
+		}
+		v, err := p.parse(val)
+		if err != nil {
+			return nil, err
+		}
+		out[p.Name] = v
+	}
+	if t.check != nil {
+		if err := t.check(out); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func (t *Template) param(name string) *TemplateParam {
+	for _, p := range t.Params {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// parse returns the value of the parameter, a float64 for numbers and an upper case string for
+// time frames and choices.
+func (p *TemplateParam) parse(val interface{}) (interface{}, error) {
+	switch p.Type {
+	case IntParam, FloatParam:
+		f, ok := val.(float64)
+		if !ok || math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, errors.New("template parameter " + p.Name + " must be a number")
+		}
+		if p.Type == IntParam && f != math.Trunc(f) {
+			return nil, errors.New("template parameter " + p.Name + " must be an integer")
+		}
+		if (p.Min != nil && f < *p.Min) || (p.Max != nil && f > *p.Max) {
+			return nil, errors.New("template parameter " + p.Name + " is out of bounds")
+		}
+		return f, nil
+	case PeriodParam:
+		s, ok := val.(string)
+		if !ok {
+			return nil, errors.New("template parameter " + p.Name + " must be a time frame")
+		}
+		// the time frame is checked when the expression is compiled.
+		return strings.ToLower(strings.TrimSpace(s)), nil
+	case ChoiceParam:
+		s, ok := val.(string)
+		for _, c := range p.Choices {
+			if ok && strings.ToUpper(s) == c {
+				return c, nil
+			}
+		}
+		return nil, errors.New("template parameter " + p.Name + " must be one of " + strings.Join(p.Choices, ", "))
+	default:
+		return nil, errors.New("unknown template parameter type")
+	}
+}
+
+func (v TemplateValues) int(name string) int {
+	return int(v.float(name))
+}
+
+func (v TemplateValues) float(name string) float64 {
+	f, _ := v[name].(float64)
+	return f
+}
+
+// str returns the value as written in an expression.
+func (v TemplateValues) str(name string) string {
+	switch val := v[name].(type) {
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case string:
+		return val
+	default:
+		return ""
+	}
+}
+
+func (v TemplateValues) isBearish() bool {
+	return v.str(sideParam) == BearishSignal
+}
@@ -0,0 +1,51 @@
+package strategy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Templates(t *testing.T) {
+	names := []string{}
+	for _, tmpl := range Templates() {
+		names = append(names, tmpl.Name)
+		for _, side := range []string{BullishSignal, BearishSignal} {
+			signal, err := tmpl.Instantiate(&TemplateInstance{Name: "test", Params: TemplateValues{"side": side}})
+			assert.EqualValues(t, nil, err, tmpl.Name)
+			assert.EqualValues(t, side, signal.SignalType)
+			assert.EqualValues(t, FstNotify, signal.NotifyType)
+			assert.EqualValues(t, ContinuousTrack, signal.TrackType)
+		}
+	}
+	assert.EqualValues(t, []string{"BREAKOUT", "MA_CROSSOVER", "MEAN_REVERSION", "VOLUME_SPIKE"}, names)
+
+	tmpl, ok := GetTemplate("ma_crossover")
+	assert.EqualValues(t, true, ok)
+	assert.EqualValues(t, "EMA(5)@1h crosses_below EMA(26)@1h", tmpl.expression(TemplateValues{"side": BearishSignal, "frame": "1h", "fast": 5.0, "slow": 26.0}))
+	signal, err := tmpl.Instantiate(&TemplateInstance{Name: "cross", TrackType: OnetimeTrack, Params: TemplateValues{"fast": 5.0, "frame": "1h"}})
+	assert.EqualValues(t, nil, err)
+	assert.EqualValues(t, OnetimeTrack, signal.TrackType)
+
+	_, err = tmpl.Instantiate(&TemplateInstance{Name: "cross", Params: TemplateValues{"fast": 5.5}})
+	assert.EqualValues(t, "template parameter fast must be an integer", err.Error())
+	_, err = tmpl.Instantiate(&TemplateInstance{Name: "cross", Params: TemplateValues{"fast": 0.0}})
+	assert.EqualValues(t, "template parameter fast is out of bounds", err.Error())
+	_, err = tmpl.Instantiate(&TemplateInstance{Name: "cross", Params: TemplateValues{"window": 5.0}})
+	assert.EqualValues(t, "unknown template parameter window", err.Error())
+	_, err = tmpl.Instantiate(&TemplateInstance{Name: "cross", Params: TemplateValues{"side": "UP"}})
+	assert.EqualValues(t, "template parameter side must be one of BULLISH, BEARISH", err.Error())
+	_, err = tmpl.Instantiate(&TemplateInstance{Name: "cross", Params: TemplateValues{"fast": 26.0}})
+	assert.EqualValues(t, "template parameter fast must be less than slow", err.Error())
+	_, err = tmpl.Instantiate(&TemplateInstance{Params: TemplateValues{}})
+	assert.EqualValues(t, "missing signal name", err.Error())
+	_, err = tmpl.Instantiate(&TemplateInstance{Name: "cross", Params: TemplateValues{"frame": "7m"}})
+	assert.NotNil(t, err)
+
+	tmpl, _ = GetTemplate("MEAN_REVERSION")
+	_, err = tmpl.Instantiate(&TemplateInstance{Name: "reversion", Params: TemplateValues{"oversold": 70.0, "overbought": 60.0}})
+	assert.EqualValues(t, "template parameter oversold must be less than overbought", err.Error())
+
+	_, ok = GetTemplate("unknown")
+	assert.EqualValues(t, false, ok)
+}
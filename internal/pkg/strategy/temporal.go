@@ -0,0 +1,143 @@
+package strategy
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/sdcoffey/big"
+
+	"follow.markets/internal/pkg/runner"
+	tax "follow.markets/internal/pkg/techanex"
+)
+
+const (
+	// the number of bars BARS_SINCE looks back by default.
+	defaultLookback = 100
+)
+
+// BarsSince is the number of bars since its rule was last satisfied, 0 if it's satisfied on
+// the last bar. The rule is looked for in the given number of bars, the comparable fails to
+// map if it's not satisfied on any of them.
+type BarsSince struct {
+	Rule *RuleNode `json:"rule"`
+	Bars int       `json:"bars,omitempty"`
+}
+
+func (b *BarsSince) copy() *BarsSince {
+	if b == nil {
+		return nil
+	}
+	return &BarsSince{Rule: b.Rule.copy(), Bars: b.Bars}
+}
+
+func (b *BarsSince) validate() error {
+	if b.Rule == nil {
+		return atPath(errors.New("missing bars since rule"), "rule")
+	}
+	if b.Bars < 0 {
+		return atPath(errors.New("invalid number of bars"), "bars")
+	}
+	// the bars past the ones kept on the runners can't be looked back on.
+	if b.Bars > runner.MaxSize() {
+		return atPath(errors.New("the number of bars must be at most "+strconv.Itoa(runner.MaxSize())), "bars")
+	}
+	return atPath(b.Rule.validate(), "rule")
+}
+
+func (b *BarsSince) getBars() int {
+	if b.Bars < 1 {
+		return defaultLookback
+	}
+	return b.Bars
+}
+
+// mapBarsSince evaluates the rule from the bar shifted by the comparable's time frame back
+// to the lookback and returns the number of bars to the first bar it's satisfied on.
+func (c *Comparable) mapBarsSince(r *runner.Runner, t *tax.Trade) (string, big.Decimal, bool) {
+	for i := 0; i < c.BarsSince.getBars(); i++ {
+		if c.BarsSince.Rule.evaluateAt(r, t, c.TimeFrame+i) {
+			return "BarsSince: " + strconv.Itoa(i), big.NewFromInt(i), true
+		}
+	}
+	return "", big.ZERO, false
+}
+
+// isTemporal returns true if the operator evaluates its children on the past bars.
+func (o *Operator) isTemporal() bool {
+	return *o == Persist || *o == AtLeast || *o == Sequence
+}
+
+func (n *RuleNode) validateTemporal() error {
+	if n.Bars < 1 {
+		return atPath(errors.New("the number of bars of a temporal rule must be positive"), n.fieldPath("bars"))
+	}
+	if n.Bars > runner.MaxSize() {
+		return atPath(errors.New("the number of bars of a temporal rule must be at most "+strconv.Itoa(runner.MaxSize())), n.fieldPath("bars"))
+	}
+	switch n.Opt {
+	case Persist, AtLeast:
+		if len(n.Nodes) != 1 {
+			return errors.New(string(n.Opt) + " takes one rule node")
+		}
+		if n.Opt == AtLeast && (n.K < 1 || n.K > n.Bars) {
+			return atPath(errors.New("K of AT_LEAST must be between 1 and the number of bars"), n.fieldPath("k"))
+		}
+	case Sequence:
+		if len(n.Nodes) < 2 {
+			return errors.New("SEQUENCE takes two rule nodes or more")
+		}
+		if n.Bars < len(n.Nodes)-1 {
+			return atPath(errors.New("SEQUENCE has fewer bars than steps"), n.fieldPath("bars"))
+		}
+	}
+	return nil
+}
+
+// evaluateTemporal evaluates a temporal node on the bar given by the shift. PERSIST is satisfied
+// if its child is on each of the last bars, AT_LEAST if its child is on K of the last bars and
+// SEQUENCE if its children are satisfied one after another on later bars, the last one on the
+// shifted bar and the first one at most the given number of bars before.
+func (n *RuleNode) evaluateTemporal(r *runner.Runner, t *tax.Trade, shift int) bool {
+	switch n.Opt {
+	case Persist:
+		for i := 0; i < n.Bars; i++ {
+			if !n.Nodes[0].evaluateAt(r, t, shift+i) {
+				return false
+			}
+		}
+		return n.Bars > 0
+	case AtLeast:
+		count := 0
+		for i := 0; i < n.Bars; i++ {
+			if n.Nodes[0].evaluateAt(r, t, shift+i) {
+				count++
+			}
+			if count >= n.K || count+n.Bars-1-i < n.K {
+				break
+			}
+		}
+		return n.K > 0 && count >= n.K
+	case Sequence:
+		last := len(n.Nodes) - 1
+		if last < 1 || !n.Nodes[last].evaluateAt(r, t, shift) {
+			return false
+		}
+		// the nearest bar of each step leaves the most bars to the previous steps.
+		at := shift
+		for i := last - 1; i >= 0; i-- {
+			found := false
+			for s := at + 1; s <= shift+n.Bars; s++ {
+				if n.Nodes[i].evaluateAt(r, t, s) {
+					at, found = s, true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,92 @@
+package strategy
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_TemporalRule(t *testing.T) {
+	rule, err := CompileExpression("persist(3, CLOSE > 5)")
+	assert.EqualValues(t, nil, err)
+	assert.EqualValues(t, Persist, rule.Opt)
+	assert.EqualValues(t, 3, rule.Bars)
+	assert.EqualValues(t, nil, rule.validate())
+	assert.EqualValues(t, true, rule.evaluate(newTestRunner(t, "1", "6", "7", "8"), nil))
+	assert.EqualValues(t, false, rule.evaluate(newTestRunner(t, "1", "4", "7", "8"), nil))
+	assert.EqualValues(t, false, rule.evaluate(newTestRunner(t, "7", "8"), nil))
+
+	rule, err = CompileExpression("at_least(2, 4, CLOSE > 5)")
+	assert.EqualValues(t, nil, err)
+	assert.EqualValues(t, AtLeast, rule.Opt)
+	assert.EqualValues(t, 2, rule.K)
+	assert.EqualValues(t, true, rule.evaluate(newTestRunner(t, "6", "1", "7", "1"), nil))
+	assert.EqualValues(t, false, rule.evaluate(newTestRunner(t, "6", "1", "1", "7", "1"), nil))
+
+	rule, err = CompileExpression("sequence(3, CLOSE < 2, CLOSE > 5)")
+	assert.EqualValues(t, nil, err)
+	assert.EqualValues(t, Sequence, rule.Opt)
+	assert.EqualValues(t, 2, len(rule.Nodes))
+	assert.EqualValues(t, true, rule.evaluate(newTestRunner(t, "1", "3", "3", "6"), nil))
+	assert.EqualValues(t, false, rule.evaluate(newTestRunner(t, "1", "3", "3", "3", "6"), nil))
+	// the last step must be satisfied on the last bar.
+	assert.EqualValues(t, false, rule.evaluate(newTestRunner(t, "1", "6", "3"), nil))
+	// the steps must be satisfied in order.
+	assert.EqualValues(t, false, rule.evaluate(newTestRunner(t, "6", "1", "1"), nil))
+	assert.EqualValues(t, false, rule.evaluate(newTestRunner(t, "6", "6", "1"), nil))
+
+	// temporal nodes can't be negated, they are kept under a NOT node.
+	rule, err = CompileExpression("not persist(2, CLOSE > 5)")
+	assert.EqualValues(t, nil, err)
+	assert.EqualValues(t, Not, rule.Opt)
+	assert.EqualValues(t, true, rule.evaluate(newTestRunner(t, "1", "6"), nil))
+
+	var node RuleNode
+	assert.EqualValues(t, nil, json.Unmarshal([]byte(`{"opt":"at_least","k":3,"bars":2,"nodes":[{"condition":{"this":{"time_period":60,"candle":{"name":"CLOSE"}},"that":{"constant":5},"opt":"MORE"}}]}`), &node))
+	assert.EqualValues(t, "k: K of AT_LEAST must be between 1 and the number of bars", node.validate().Error())
+	node.K = 2
+	assert.EqualValues(t, nil, node.validate())
+	assert.EqualValues(t, 2, node.copy().Bars)
+
+	node.Bars = 1000000000
+	assert.EqualValues(t, "bars: the number of bars of a temporal rule must be at most 1500", node.validate().Error())
+	_, err = NewSignalFromBytes([]byte(`{"name":"persist","expression":"persist(1000000000, CLOSE > 5)"}`))
+	assert.EqualValues(t, "expression: the number of bars of a temporal rule must be at most 1500", err.Error())
+
+	for _, expr := range []string{"persist(0, CLOSE > 5)", "persist(2, CLOSE > 5, CLOSE < 6)", "sequence(3, CLOSE > 5)", "at_least(3, 2, CLOSE > 5)"} {
+		_, err := CompileExpression(expr)
+		assert.NotEqual(t, nil, err, expr)
+	}
+}
+
+func Test_BarsSince(t *testing.T) {
+	rule, err := CompileExpression("bars_since(CLOSE crosses_above 5) <= 2")
+	assert.EqualValues(t, nil, err)
+	c := rule.Condition
+	assert.NotEqual(t, nil, c.This.BarsSince)
+	assert.NotEqual(t, nil, c.That.Constant)
+	assert.EqualValues(t, defaultLookback, c.This.BarsSince.getBars())
+
+	r := newTestRunner(t, "1", "6", "7", "8")
+	_, val, ok := c.This.mapDecimal(r, nil)
+	assert.EqualValues(t, true, ok)
+	assert.EqualValues(t, 2, val.Float())
+	assert.EqualValues(t, true, c.evaluate(r, nil))
+	assert.EqualValues(t, false, c.evaluate(newTestRunner(t, "1", "6", "7", "8", "9"), nil))
+
+	// the rule isn't satisfied within the lookback.
+	rule, err = CompileExpression("bars_since(CLOSE crosses_above 5, 2) >= 0")
+	assert.EqualValues(t, nil, err)
+	assert.EqualValues(t, 2, rule.Condition.This.BarsSince.Bars)
+	assert.EqualValues(t, false, rule.evaluate(r, nil))
+
+	// the periods of the inner rule are the ones of the signal.
+	signal, err := NewSignalFromBytes([]byte(`{"name":"since","expression":"bars_since(CLOSE@5m > 10) < 3"}`))
+	assert.EqualValues(t, nil, err)
+	assert.EqualValues(t, 1, len(signal.GetPeriods()))
+	assert.EqualValues(t, 300, signal.TimePeriod.Seconds())
+
+	_, err = NewSignalFromBytes([]byte(`{"name":"since","expression":"bars_since(CLOSE > 10, 1000000000) < 3"}`))
+	assert.EqualValues(t, "expression: line 1, column 1: the number of bars must be at most 1500", err.Error())
+}
@@ -0,0 +1,143 @@
+package strategy
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sdcoffey/big"
+
+	"follow.markets/internal/pkg/runner"
+	"follow.markets/pkg/util"
+)
+
+// Universe defines the tickers a signal is evaluated on by filters on the watched runners.
+// The volume rank is the rank of the 24h quote volume among the runners of the same market
+// which pass the other filters, the market cap band and the listing age need the runners'
+// fundamentals. The excluded base suffixes apply to the base asset left by one of the quote
+// assets, they need the quote assets. The included tickers are always part of the universe if
+// they are watched on one of the markets, the excluded ones never are.
+type Universe struct {
+	QuoteAssets         []string `json:"quote_assets,omitempty"`
+	Markets             []string `json:"markets,omitempty"`
+	MaxVolumeRank       int      `json:"max_volume_rank,omitempty"`
+	MinQuoteVolume      float64  `json:"min_quote_volume,omitempty"`
+	MinMarketCap        float64  `json:"min_market_cap,omitempty"`
+	MaxMarketCap        float64  `json:"max_market_cap,omitempty"`
+	MinListingDays      int      `json:"min_listing_days,omitempty"`
+	ExcludeBaseSuffixes []string `json:"exclude_base_suffixes,omitempty"`
+	Include             []string `json:"include,omitempty"`
+	Exclude             []string `json:"exclude,omitempty"`
+}
+
+func (u *Universe) copy() *Universe {
+	if u == nil {
+		return nil
+	}
+	nu := *u
+	return &nu
+}
+
+func (u *Universe) validate() error {
+	for _, m := range u.Markets {
+		if _, ok := runner.ValidateMarket(m); !ok {
+			return errors.New("invalid universe market")
+		}
+	}
+	if u.MaxVolumeRank < 0 || u.MinQuoteVolume < 0 || u.MinMarketCap < 0 || u.MaxMarketCap < 0 || u.MinListingDays < 0 {
+		return errors.New("invalid universe filters")
+	}
+	if u.MaxMarketCap > 0 && u.MaxMarketCap < u.MinMarketCap {
+		return errors.New("invalid universe market cap band")
+	}
+	if len(u.ExcludeBaseSuffixes) > 0 && len(u.QuoteAssets) == 0 {
+		return errors.New("the excluded base suffixes need quote assets")
+	}
+	return nil
+}
+
+// Resolve returns the unique names of the runners in the universe.
+func (u *Universe) Resolve(runners []*runner.Runner) []string {
+	var out []string
+	ranked := make(map[runner.MarketType][]*runner.Runner)
+	volumes := make(map[*runner.Runner]big.Decimal, len(runners))
+	for _, r := range runners {
+		if !u.isOnMarkets(r) || util.StringSliceContains(u.Exclude, r.GetName()) {
+			continue
+		}
+		if util.StringSliceContains(u.Include, r.GetName()) {
+			out = append(out, r.GetUniqueName())
+			continue
+		}
+		if !u.isMatched(r) {
+			continue
+		}
+		volumes[r] = r.GetQuoteVolume(24 * time.Hour)
+		if u.MinQuoteVolume > 0 && volumes[r].LT(big.NewDecimal(u.MinQuoteVolume)) {
+			continue
+		}
+		ranked[r.GetMarketType()] = append(ranked[r.GetMarketType()], r)
+	}
+	for _, rs := range ranked {
+		sort.SliceStable(rs, func(i, j int) bool { return volumes[rs[i]].GT(volumes[rs[j]]) })
+		if u.MaxVolumeRank > 0 && len(rs) > u.MaxVolumeRank {
+			rs = rs[:u.MaxVolumeRank]
+		}
+		for _, r := range rs {
+			out = append(out, r.GetUniqueName())
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (u *Universe) isOnMarkets(r *runner.Runner) bool {
+	if len(u.Markets) == 0 {
+		return true
+	}
+	for _, m := range u.Markets {
+		if mk, _ := runner.ValidateMarket(m); mk == r.GetMarketType() {
+			return true
+		}
+	}
+	return false
+}
+
+// isMatched returns true if the runner passes the filters on its quote asset and fundamentals.
+func (u *Universe) isMatched(r *runner.Runner) bool {
+	name := r.GetName()
+	if len(u.QuoteAssets) > 0 {
+		base := ""
+		for _, q := range u.QuoteAssets {
+			if strings.HasSuffix(name, q) && len(name) > len(q) {
+				base = strings.TrimSuffix(name, q)
+				break
+			}
+		}
+		if base == "" {
+			return false
+		}
+		for _, s := range u.ExcludeBaseSuffixes {
+			if strings.HasSuffix(base, s) {
+				return false
+			}
+		}
+	}
+	if u.MinMarketCap > 0 || u.MaxMarketCap > 0 {
+		mcap := r.GetCap()
+		if mcap.LTE(big.ZERO) || mcap.LT(big.NewDecimal(u.MinMarketCap)) {
+			return false
+		}
+		if u.MaxMarketCap > 0 && mcap.GT(big.NewDecimal(u.MaxMarketCap)) {
+			return false
+		}
+	}
+	if u.MinListingDays > 0 {
+		listed, ok := r.GetListingTime()
+		if !ok || time.Now().Sub(listed) < time.Duration(u.MinListingDays)*24*time.Hour {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,63 @@
+package strategy
+
+import (
+	"testing"
+	"time"
+
+	bn "github.com/adshao/go-binance/v2"
+	"github.com/stretchr/testify/assert"
+
+	"follow.markets/internal/pkg/runner"
+	tax "follow.markets/internal/pkg/techanex"
+)
+
+func newUniverseRunner(t *testing.T, name string, market runner.MarketType, volume string) *runner.Runner {
+	rc := runner.NewRunnerDefaultConfigs()
+	rc.Market = market
+	r := runner.NewRunner(name, rc)
+	kline := &bn.Kline{OpenTime: 1499040000000, Open: "1", High: "1", Low: "1", Close: "1", Volume: volume, TradeNum: 1}
+	assert.EqualValues(t, true, r.SyncCandle(tax.ConvertBinanceKline(kline, nil)))
+	return r
+}
+
+func Test_Universe(t *testing.T) {
+	runners := []*runner.Runner{
+		newUniverseRunner(t, "BTCUSDT", runner.Cash, "300"),
+		newUniverseRunner(t, "ETHUSDT", runner.Cash, "200"),
+		newUniverseRunner(t, "XRPUSDT", runner.Cash, "100"),
+		newUniverseRunner(t, "BTCUPUSDT", runner.Cash, "1000"),
+		newUniverseRunner(t, "ETHBTC", runner.Cash, "1000"),
+		newUniverseRunner(t, "BTCUSDT", runner.Futures, "50"),
+		newUniverseRunner(t, "ETHUSDT", runner.Futures, "10"),
+	}
+	u := &Universe{QuoteAssets: []string{"USDT"}, ExcludeBaseSuffixes: []string{"UP", "DOWN"}}
+	assert.EqualValues(t, nil, u.validate())
+	assert.EqualValues(t, []string{"BTCUSDT", "BTCUSDTPERP", "ETHUSDT", "ETHUSDTPERP", "XRPUSDT"}, u.Resolve(runners))
+
+	// the volume rank is per market.
+	u.MaxVolumeRank = 1
+	assert.EqualValues(t, []string{"BTCUSDT", "BTCUSDTPERP"}, u.Resolve(runners))
+
+	u.MaxVolumeRank = 0
+	u.Markets = []string{"cash"}
+	u.MinQuoteVolume = 150
+	u.Include = []string{"ETHBTC"}
+	u.Exclude = []string{"BTCUSDT"}
+	assert.EqualValues(t, []string{"ETHBTC", "ETHUSDT"}, u.Resolve(runners))
+
+	// the fundamental filters need the fundamentals of the runners.
+	u = &Universe{MinListingDays: 30, MinMarketCap: 100}
+	assert.EqualValues(t, 0, len(u.Resolve(runners)))
+	runners[0].SetFundamental(&runner.Fundamental{TotalSupply: 1000, DateAdded: time.Now().Add(-90 * 24 * time.Hour)})
+	runners[1].SetFundamental(&runner.Fundamental{TotalSupply: 1000, DateAdded: time.Now()})
+	runners[2].SetFundamental(&runner.Fundamental{TotalSupply: 10, DateAdded: time.Now().Add(-90 * 24 * time.Hour)})
+	assert.EqualValues(t, []string{"BTCUSDT"}, u.Resolve(runners))
+
+	assert.NotEqual(t, nil, (&Universe{Markets: []string{"OPTIONS"}}).validate())
+	assert.NotEqual(t, nil, (&Universe{MinMarketCap: 10, MaxMarketCap: 5}).validate())
+	assert.EqualValues(t, "the excluded base suffixes need quote assets", (&Universe{ExcludeBaseSuffixes: []string{"UP"}}).validate().Error())
+
+	signal, err := NewSignalFromBytes([]byte(`{"name":"universe","expression":"CLOSE > 1","universe":{"quote_assets":["USDT"],"max_volume_rank":10}}`))
+	assert.EqualValues(t, nil, err)
+	assert.EqualValues(t, 10, signal.copy().Universe.MaxVolumeRank)
+}
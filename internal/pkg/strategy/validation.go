@@ -0,0 +1,71 @@
+package strategy
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ValidationError is an error of a signal with the JSON path of the value it's about, e.g.
+// rule.groups[0].condition_groups[1].conditions[2].this.indicator.name. The path is empty for
+// the errors about the signal as a whole, and stops at the expression for the rules compiled
+// from an expression.
+type ValidationError struct {
+	Path string
+	Err  error
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Path) == 0 {
+		return e.Err.Error()
+	}
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error { return e.Err }
+
+// atPath returns the error at the given path, the path of a validation error is relative to it.
+func atPath(err error, path string) error {
+	if err == nil || len(path) == 0 {
+		return err
+	}
+	if ve, ok := err.(*ValidationError); ok {
+		return &ValidationError{Path: joinPath(path, ve.Path), Err: ve.Err}
+	}
+	return &ValidationError{Path: path, Err: err}
+}
+
+// atExpression returns the error of a rule compiled from an expression at the path of the
+// expression, the paths within the rule aren't the ones of the signal.
+func atExpression(err error, path string) error {
+	if ve, ok := err.(*ValidationError); ok {
+		err = ve.Err
+	}
+	return atPath(err, path)
+}
+
+// errorMessage returns the message of the error without its path.
+func errorMessage(err error) string {
+	if ve, ok := err.(*ValidationError); ok {
+		return ve.Err.Error()
+	}
+	return err.Error()
+}
+
+// joinPath joins a path and a path relative to it.
+func joinPath(parent, child string) string {
+	switch {
+	case len(parent) == 0:
+		return child
+	case len(child) == 0:
+		return parent
+	case strings.HasPrefix(child, "["):
+		return parent + child
+	default:
+		return parent + "." + child
+	}
+}
+
+// indexPath returns the path of the element of the given index of an array.
+func indexPath(name string, i int) string {
+	return name + "[" + strconv.Itoa(i) + "]"
+}
@@ -0,0 +1,202 @@
+package techanex
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	ta "github.com/heyphat/techan"
+	"github.com/sdcoffey/big"
+)
+
+const (
+	// the default number of periods of the premium index moving average.
+	DefaultPremiumWindow = 30
+
+	year = 365 * 24 * time.Hour
+	// the interval between the fundings of the perpetuals.
+	fundingInterval = 8 * time.Hour
+)
+
+// Basis relates the close prices of a spot and a futures candle over the same period, the
+// futures is either a perpetual or a dated contract. The basis percentage is relative to the
+// spot price. The annualized basis of a dated contract is the basis percentage scaled to a year
+// over the time left to expiry at the close of the candle. A perpetual doesn't expire, its
+// annualized basis is its carry, the funding rate of the candle scaled to a year of funding
+// intervals, it's only defined with the derivative of the candle. The premium index is the
+// percentage of the mark price over the index price.
+type Basis struct {
+	Period              ta.TimePeriod
+	SpotPrice           big.Decimal
+	PerpPrice           big.Decimal
+	Basis               big.Decimal
+	BasisPercentage     big.Decimal
+	Expiry              time.Time // zero for a perpetual
+	AnnualizedBasis     big.Decimal
+	PremiumIndex        big.Decimal
+	PremiumIndexAverage big.Decimal
+
+	annualized bool
+}
+
+// IsAnnualized returns true if the annualized basis is defined, a dated futures has yet to
+// expire and the funding rate of a perpetual is known.
+func (b *Basis) IsAnnualized() bool {
+	return b.annualized
+}
+
+// BasisSeries is a series of basis aligned to the candles of the same time frame.
+type BasisSeries struct {
+	sync.RWMutex
+
+	window int
+	expiry time.Time
+	Bases  []*Basis
+}
+
+// NewBasisSeries returns the basis series of a perpetual.
+func NewBasisSeries(window int) *BasisSeries {
+	if window <= 0 {
+		window = DefaultPremiumWindow
+	}
+	return &BasisSeries{
+		window: window,
+		Bases:  make([]*Basis, 0),
+	}
+}
+
+// NewDatedBasisSeries returns the basis series of a futures contract expiring at the given time.
+func NewDatedBasisSeries(window int, expiry time.Time) *BasisSeries {
+	bs := NewBasisSeries(window)
+	bs.expiry = expiry
+	return bs
+}
+
+// Sync computes the basis of the given spot and futures candles and syncs it to the series.
+// Both candles must have the same period, the last basis is replaced if it has the same period.
+// The derivative of the futures is optional, the premium index is zero without it.
+func (bs *BasisSeries) Sync(spot, perp *ta.Candle, d *Derivative) bool {
+	if spot == nil || perp == nil || !spot.Period.Start.Equal(perp.Period.Start) || spot.ClosePrice.EQ(big.ZERO) {
+		return false
+	}
+	bs.Lock()
+	defer bs.Unlock()
+	b := &Basis{
+		Period:       spot.Period,
+		SpotPrice:    spot.ClosePrice,
+		PerpPrice:    perp.ClosePrice,
+		Basis:        perp.ClosePrice.Sub(spot.ClosePrice),
+		Expiry:       bs.expiry,
+		PremiumIndex: big.ZERO,
+	}
+	b.BasisPercentage = b.Basis.Div(spot.ClosePrice).Mul(big.NewFromInt(100))
+	b.AnnualizedBasis = big.ZERO
+	switch {
+	case !b.Expiry.IsZero():
+		if b.annualized = b.Expiry.After(b.Period.End); b.annualized {
+			b.AnnualizedBasis = b.BasisPercentage.Mul(big.NewDecimal(float64(year) / float64(b.Expiry.Sub(b.Period.End))))
+		}
+	case d != nil:
+		b.annualized = true
+		b.AnnualizedBasis = d.FundingRate.Mul(big.NewFromInt(100)).Mul(big.NewDecimal(float64(year) / float64(fundingInterval)))
+	}
+	if d != nil && d.IndexPrice.GT(big.ZERO) {
+		b.PremiumIndex = d.MarkPrice.Sub(d.IndexPrice).Div(d.IndexPrice).Mul(big.NewFromInt(100))
+	}
+	size := len(bs.Bases)
+	if size > 0 {
+		last := bs.Bases[size-1]
+		if b.Period.Start.Before(last.Period.Start) {
+			return false
+		}
+		if b.Period.Start.Equal(last.Period.Start) {
+			bs.Bases = bs.Bases[:size-1]
+		}
+	}
+	bs.Bases = append(bs.Bases, b)
+	b.PremiumIndexAverage = bs.premiumAverage()
+	return true
+}
+
+// premiumAverage returns the simple moving average of the premium index over the window.
+func (bs *BasisSeries) premiumAverage() big.Decimal {
+	start := len(bs.Bases) - bs.window
+	if start < 0 {
+		start = 0
+	}
+	sum := big.ZERO
+	for _, b := range bs.Bases[start:] {
+		sum = sum.Add(b.PremiumIndex)
+	}
+	return sum.Div(big.NewFromInt(len(bs.Bases) - start))
+}
+
+// LastBasis returns the last basis of the series.
+func (bs *BasisSeries) LastBasis() *Basis {
+	bs.RLock()
+	defer bs.RUnlock()
+	if len(bs.Bases) == 0 {
+		return nil
+	}
+	return bs.Bases[len(bs.Bases)-1]
+}
+
+// BasisAt returns the basis of the period starting at the given time.
+func (bs *BasisSeries) BasisAt(start time.Time) (*Basis, bool) {
+	bs.RLock()
+	defer bs.RUnlock()
+	for i := len(bs.Bases) - 1; i >= 0; i-- {
+		if bs.Bases[i].Period.Start.Equal(start) {
+			return bs.Bases[i], true
+		}
+		if bs.Bases[i].Period.Start.Before(start) {
+			break
+		}
+	}
+	return nil, false
+}
+
+// Shrink shrinks the length of the series to the given size.
+func (bs *BasisSeries) Shrink(size int) {
+	bs.Lock()
+	defer bs.Unlock()
+	if len(bs.Bases) < size+100 {
+		return
+	}
+	bs.Bases = bs.Bases[len(bs.Bases)-size:]
+}
+
+type BasisJSON struct {
+	StartTime           string `json:"st"`
+	EndTime             string `json:"et"`
+	SpotPrice           string `json:"spot"`
+	PerpPrice           string `json:"perp"`
+	Basis               string `json:"basis"`
+	BasisPercentage     string `json:"basis_pct"`
+	AnnualizedBasis     string `json:"annualized_basis_pct,omitempty"`
+	PremiumIndex        string `json:"premium_index_pct"`
+	PremiumIndexAverage string `json:"premium_index_ma_pct"`
+}
+
+type BasesJSON []BasisJSON
+
+func Basis2JSON(b *Basis) *BasisJSON {
+	if b == nil {
+		return nil
+	}
+	layout := fmt.Sprint(SimpleDateFormatV2, "T", SimpleTimeFormat)
+	out := &BasisJSON{
+		StartTime:           b.Period.Start.Format(layout),
+		EndTime:             b.Period.End.Format(layout),
+		SpotPrice:           b.SpotPrice.FormattedString(4),
+		PerpPrice:           b.PerpPrice.FormattedString(4),
+		Basis:               b.Basis.FormattedString(4),
+		BasisPercentage:     b.BasisPercentage.FormattedString(4),
+		PremiumIndex:        b.PremiumIndex.FormattedString(4),
+		PremiumIndexAverage: b.PremiumIndexAverage.FormattedString(4),
+	}
+	if b.IsAnnualized() {
+		out.AnnualizedBasis = b.AnnualizedBasis.FormattedString(2)
+	}
+	return out
+}
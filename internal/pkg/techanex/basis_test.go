@@ -0,0 +1,56 @@
+package techanex
+
+import (
+	"testing"
+	"time"
+
+	bn "github.com/adshao/go-binance/v2"
+	ta "github.com/heyphat/techan"
+	"github.com/sdcoffey/big"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_BasisSeries(t *testing.T) {
+	bs := NewBasisSeries(2)
+	spot := ConvertBinanceKline(&bn.Kline{OpenTime: 1499040000000, Open: "100", High: "100", Low: "100", Close: "100", Volume: "1"}, nil)
+	perp := ConvertBinanceKline(&bn.Kline{OpenTime: 1499040000000, Open: "101", High: "101", Low: "101", Close: "101", Volume: "1"}, nil)
+	d := NewDerivative(ta.NewTimePeriod(spot.Period.Start, time.Minute))
+	d.MarkPrice, d.IndexPrice = big.NewFromString("100.2"), big.NewFromInt(100)
+	d.FundingRate = big.NewFromString("0.0001")
+
+	assert.EqualValues(t, true, bs.Sync(spot, perp, d))
+	b := bs.LastBasis()
+	assert.EqualValues(t, "1", b.Basis.FormattedString(0))
+	assert.EqualValues(t, "1", b.BasisPercentage.FormattedString(0))
+	// a perpetual doesn't expire, its basis is annualized from its funding, 3 times a day.
+	assert.EqualValues(t, true, b.IsAnnualized())
+	assert.EqualValues(t, "10.95", b.AnnualizedBasis.FormattedString(2))
+	assert.EqualValues(t, "10.95", Basis2JSON(b).AnnualizedBasis)
+	assert.EqualValues(t, "0.2", b.PremiumIndex.FormattedString(1))
+
+	// candles of different periods are not related
+	next := ConvertBinanceKline(&bn.Kline{OpenTime: 1499040060000, Open: "100", High: "100", Low: "100", Close: "100", Volume: "1"}, nil)
+	assert.EqualValues(t, false, bs.Sync(next, perp, nil))
+
+	nextPerp := ConvertBinanceKline(&bn.Kline{OpenTime: 1499040060000, Open: "99", High: "99", Low: "99", Close: "99", Volume: "1"}, nil)
+	assert.EqualValues(t, true, bs.Sync(next, nextPerp, nil))
+	assert.EqualValues(t, "-1", bs.LastBasis().Basis.FormattedString(0))
+	// the funding isn't known without the derivative.
+	assert.EqualValues(t, false, bs.LastBasis().IsAnnualized())
+	assert.EqualValues(t, "", Basis2JSON(bs.LastBasis()).AnnualizedBasis)
+	assert.EqualValues(t, "0.1", bs.LastBasis().PremiumIndexAverage.FormattedString(1))
+
+	_, ok := bs.BasisAt(spot.Period.Start)
+	assert.EqualValues(t, true, ok)
+
+	// the basis of a dated futures is annualized over the time left to expiry, 73 days.
+	dated := NewDatedBasisSeries(2, spot.Period.End.Add(73*24*time.Hour))
+	assert.EqualValues(t, true, dated.Sync(spot, perp, nil))
+	assert.EqualValues(t, true, dated.LastBasis().IsAnnualized())
+	assert.EqualValues(t, "5", dated.LastBasis().AnnualizedBasis.FormattedString(0))
+	assert.EqualValues(t, "5.00", Basis2JSON(dated.LastBasis()).AnnualizedBasis)
+
+	expired := NewDatedBasisSeries(2, spot.Period.Start)
+	assert.EqualValues(t, true, expired.Sync(spot, perp, nil))
+	assert.EqualValues(t, false, expired.LastBasis().IsAnnualized())
+}
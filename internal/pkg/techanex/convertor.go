@@ -107,7 +107,7 @@ func ConvertBinanceFuturesStreamingAggTrade(t *bnf.WsAggTradeEvent) *Trade {
 	trade.Price = big.NewFromString(t.Price)
 	trade.Quantity = big.NewFromString(t.Quantity)
 	trade.TradeTime = t.TradeTime
-	//trade.IsBuyerMaker = t.IsBuyerMaker
+	trade.IsBuyerMaker = t.Maker
 	return trade
 }
 
@@ -27,7 +27,7 @@ func Test_L1(t *testing.T) {
 		},
 	}
 
-	l1 := BinanceSpotBestBidAskFromDepth(d)
+	l1 := BinanceSpotBestBidAskFromDepth(&d)
 	assert.EqualValues(t, "20", l1.BestBid.Price.FormattedString(0))
 	assert.EqualValues(t, "100", l1.BestAsk.Price.FormattedString(0))
 
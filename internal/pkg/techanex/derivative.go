@@ -0,0 +1,260 @@
+package techanex
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	bnf "github.com/adshao/go-binance/v2/futures"
+	ta "github.com/heyphat/techan"
+	"github.com/sdcoffey/big"
+)
+
+// Derivative holds the futures market data of a ticker over a time period. The funding rate,
+// open interest, mark and index price are the last known values within the period, the
+// liquidations are the value, in the quote currency, of all liquidation orders within the period.
+type Derivative struct {
+	Period          ta.TimePeriod
+	FundingRate     big.Decimal
+	NextFundingTime int64
+	OpenInterest    big.Decimal
+	MarkPrice       big.Decimal
+	IndexPrice      big.Decimal
+	LiquidationBuy  big.Decimal
+	LiquidationSell big.Decimal
+}
+
+func NewDerivative(period ta.TimePeriod) *Derivative {
+	return &Derivative{
+		Period:          period,
+		FundingRate:     big.ZERO,
+		OpenInterest:    big.ZERO,
+		MarkPrice:       big.ZERO,
+		IndexPrice:      big.ZERO,
+		LiquidationBuy:  big.ZERO,
+		LiquidationSell: big.ZERO,
+	}
+}
+
+// next returns the derivative of the given period carrying over the last known values.
+func (d *Derivative) next(period ta.TimePeriod) *Derivative {
+	nd := NewDerivative(period)
+	nd.FundingRate = d.FundingRate
+	nd.NextFundingTime = d.NextFundingTime
+	nd.OpenInterest = d.OpenInterest
+	nd.MarkPrice = d.MarkPrice
+	nd.IndexPrice = d.IndexPrice
+	return nd
+}
+
+// MarkPrice is a mark price update from the exchange, it also carries the current funding rate.
+type MarkPrice struct {
+	Time            time.Time
+	MarkPrice       big.Decimal
+	IndexPrice      big.Decimal
+	FundingRate     big.Decimal
+	NextFundingTime int64
+}
+
+// OpenInterest is an open interest update from the exchange.
+type OpenInterest struct {
+	Time         time.Time
+	OpenInterest big.Decimal
+}
+
+// FundingRate is a realized funding rate from the exchange.
+type FundingRate struct {
+	Time        time.Time
+	FundingRate big.Decimal
+}
+
+// Liquidation is a liquidation order from the exchange. A BUY liquidation closes a short
+// position, a SELL liquidation closes a long position.
+type Liquidation struct {
+	Time     time.Time
+	Side     string
+	Price    big.Decimal
+	Quantity big.Decimal
+}
+
+func ConvertBinanceFuturesMarkPrice(e *bnf.WsMarkPriceEvent) *MarkPrice {
+	return &MarkPrice{
+		Time:            time.Unix(e.Time/1000, 0),
+		MarkPrice:       big.NewFromString(e.MarkPrice),
+		IndexPrice:      big.NewFromString(e.IndexPrice),
+		FundingRate:     big.NewFromString(e.FundingRate),
+		NextFundingTime: e.NextFundingTime,
+	}
+}
+
+func ConvertBinanceFuturesLiquidation(e *bnf.WsLiquidationOrderEvent) *Liquidation {
+	price := big.NewFromString(e.LiquidationOrder.AvgPrice)
+	if price.EQ(big.ZERO) {
+		price = big.NewFromString(e.LiquidationOrder.Price)
+	}
+	return &Liquidation{
+		Time:     time.Unix(e.LiquidationOrder.TradeTime/1000, 0),
+		Side:     string(e.LiquidationOrder.Side),
+		Price:    price,
+		Quantity: big.NewFromString(e.LiquidationOrder.OrigQuantity),
+	}
+}
+
+func ConvertBinanceFuturesOpenInterest(o *bnf.OpenInterest) *OpenInterest {
+	return &OpenInterest{
+		Time:         time.Unix(o.Time/1000, 0),
+		OpenInterest: big.NewFromString(o.OpenInterest),
+	}
+}
+
+func ConvertBinanceFuturesOpenInterestStatistic(o *bnf.OpenInterestStatistic) *OpenInterest {
+	return &OpenInterest{
+		Time:         time.Unix(o.Timestamp/1000, 0),
+		OpenInterest: big.NewFromString(o.SumOpenInterest),
+	}
+}
+
+func ConvertBinanceFuturesFundingRate(f *bnf.FundingRate) *FundingRate {
+	return &FundingRate{
+		Time:        time.Unix(f.FundingTime/1000, 0),
+		FundingRate: big.NewFromString(f.FundingRate),
+	}
+}
+
+// DerivativeSeries is a series of derivatives aligned to the candles of the same time frame.
+// The derivatives are never changed once they're on the series, an update replaces the last
+// derivative with a new one, so the derivatives returned can be read without the lock.
+type DerivativeSeries struct {
+	sync.RWMutex
+
+	frame       time.Duration
+	Derivatives []*Derivative
+}
+
+func NewDerivativeSeries(frame time.Duration) *DerivativeSeries {
+	return &DerivativeSeries{
+		frame:       frame,
+		Derivatives: make([]*Derivative, 0),
+	}
+}
+
+// update applies the given function to the derivative of the period the given time falls in.
+// Updates older than the last period are ignored since the series only goes forward.
+func (ds *DerivativeSeries) update(t time.Time, fn func(d *Derivative)) bool {
+	ds.Lock()
+	defer ds.Unlock()
+	period := ta.NewTimePeriod(t.Truncate(ds.frame), ds.frame)
+	if len(ds.Derivatives) == 0 {
+		ds.Derivatives = append(ds.Derivatives, NewDerivative(period))
+	}
+	last := ds.Derivatives[len(ds.Derivatives)-1]
+	if period.Start.Before(last.Period.Start) {
+		return false
+	}
+	if period.Start.After(last.Period.Start) {
+		nd := last.next(period)
+		fn(nd)
+		ds.Derivatives = append(ds.Derivatives, nd)
+		return true
+	}
+	nd := *last
+	fn(&nd)
+	ds.Derivatives[len(ds.Derivatives)-1] = &nd
+	return true
+}
+
+// SyncMarkPrice updates the series with the given mark price.
+func (ds *DerivativeSeries) SyncMarkPrice(m *MarkPrice) bool {
+	if m == nil {
+		return false
+	}
+	return ds.update(m.Time, func(d *Derivative) {
+		d.MarkPrice = m.MarkPrice
+		d.IndexPrice = m.IndexPrice
+		d.FundingRate = m.FundingRate
+		d.NextFundingTime = m.NextFundingTime
+	})
+}
+
+// SyncOpenInterest updates the series with the given open interest.
+func (ds *DerivativeSeries) SyncOpenInterest(o *OpenInterest) bool {
+	if o == nil {
+		return false
+	}
+	return ds.update(o.Time, func(d *Derivative) { d.OpenInterest = o.OpenInterest })
+}
+
+// SyncFundingRate updates the series with the given realized funding rate.
+func (ds *DerivativeSeries) SyncFundingRate(f *FundingRate) bool {
+	if f == nil {
+		return false
+	}
+	return ds.update(f.Time, func(d *Derivative) { d.FundingRate = f.FundingRate })
+}
+
+// SyncLiquidation adds the value of the given liquidation to the series.
+func (ds *DerivativeSeries) SyncLiquidation(l *Liquidation) bool {
+	if l == nil {
+		return false
+	}
+	return ds.update(l.Time, func(d *Derivative) {
+		if strings.ToUpper(l.Side) == "BUY" {
+			d.LiquidationBuy = d.LiquidationBuy.Add(l.Price.Mul(l.Quantity))
+		} else {
+			d.LiquidationSell = d.LiquidationSell.Add(l.Price.Mul(l.Quantity))
+		}
+	})
+}
+
+// LastDerivative returns the last derivative of the series.
+func (ds *DerivativeSeries) LastDerivative() *Derivative {
+	ds.RLock()
+	defer ds.RUnlock()
+	if len(ds.Derivatives) == 0 {
+		return nil
+	}
+	return ds.Derivatives[len(ds.Derivatives)-1]
+}
+
+// DerivativeByIndex returns the derivative by the given index.
+func (ds *DerivativeSeries) DerivativeByIndex(index int) *Derivative {
+	ds.RLock()
+	defer ds.RUnlock()
+	if index < 0 || index >= len(ds.Derivatives) {
+		return nil
+	}
+	return ds.Derivatives[index]
+}
+
+// DerivativeAt returns the derivative of the period starting at the given time. If the series
+// doesn't have such a period, it returns the last derivative before the given time since it holds
+// the last known values, false is returned in that case.
+func (ds *DerivativeSeries) DerivativeAt(start time.Time) (*Derivative, bool) {
+	ds.RLock()
+	defer ds.RUnlock()
+	for i := len(ds.Derivatives) - 1; i >= 0; i-- {
+		d := ds.Derivatives[i]
+		if d.Period.Start.After(start) {
+			continue
+		}
+		return d, d.Period.Start.Equal(start)
+	}
+	return nil, false
+}
+
+// Len returns the length of the series.
+func (ds *DerivativeSeries) Len() int {
+	ds.RLock()
+	defer ds.RUnlock()
+	return len(ds.Derivatives)
+}
+
+// Shrink shrinks the length of the series to the given size.
+func (ds *DerivativeSeries) Shrink(size int) {
+	ds.Lock()
+	defer ds.Unlock()
+	if len(ds.Derivatives) < size+100 {
+		return
+	}
+	ds.Derivatives = ds.Derivatives[len(ds.Derivatives)-size:]
+}
@@ -0,0 +1,49 @@
+package techanex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sdcoffey/big"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DerivativeSeries(t *testing.T) {
+	ds := NewDerivativeSeries(time.Minute)
+	start := time.Unix(1499040000, 0)
+
+	ok := ds.SyncMarkPrice(&MarkPrice{Time: start.Add(time.Second), MarkPrice: big.NewFromInt(100), IndexPrice: big.NewFromInt(99), FundingRate: big.NewDecimal(0.0001)})
+	assert.EqualValues(t, true, ok)
+	ok = ds.SyncOpenInterest(&OpenInterest{Time: start.Add(2 * time.Second), OpenInterest: big.NewFromInt(1000)})
+	assert.EqualValues(t, true, ok)
+	ok = ds.SyncLiquidation(&Liquidation{Time: start.Add(3 * time.Second), Side: "SELL", Price: big.NewFromInt(100), Quantity: big.NewFromInt(2)})
+	assert.EqualValues(t, true, ok)
+	assert.EqualValues(t, 1, ds.Len())
+	assert.EqualValues(t, "200", ds.LastDerivative().LiquidationSell.FormattedString(0))
+
+	// the derivatives read before an update aren't changed by it.
+	last := ds.LastDerivative()
+	ok = ds.SyncLiquidation(&Liquidation{Time: start.Add(4 * time.Second), Side: "SELL", Price: big.NewFromInt(100), Quantity: big.NewFromInt(1)})
+	assert.EqualValues(t, true, ok)
+	assert.EqualValues(t, "200", last.LiquidationSell.FormattedString(0))
+	assert.EqualValues(t, "300", ds.LastDerivative().LiquidationSell.FormattedString(0))
+	assert.EqualValues(t, 1, ds.Len())
+
+	// a new period carries over the last known values but not the liquidations
+	ok = ds.SyncOpenInterest(&OpenInterest{Time: start.Add(2 * time.Minute), OpenInterest: big.NewFromInt(1100)})
+	assert.EqualValues(t, true, ok)
+	assert.EqualValues(t, 2, ds.Len())
+	assert.EqualValues(t, "100", ds.LastDerivative().MarkPrice.FormattedString(0))
+	assert.EqualValues(t, "0", ds.LastDerivative().LiquidationSell.FormattedString(0))
+
+	// updates older than the last period are ignored
+	ok = ds.SyncOpenInterest(&OpenInterest{Time: start, OpenInterest: big.NewFromInt(900)})
+	assert.EqualValues(t, false, ok)
+
+	d, ok := ds.DerivativeAt(start.Add(time.Minute))
+	assert.EqualValues(t, false, ok)
+	assert.EqualValues(t, "1000", d.OpenInterest.FormattedString(0))
+	d, ok = ds.DerivativeAt(start.Add(2 * time.Minute))
+	assert.EqualValues(t, true, ok)
+	assert.EqualValues(t, "1100", d.OpenInterest.FormattedString(0))
+}
@@ -0,0 +1,317 @@
+package techanex
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	bn "github.com/adshao/go-binance/v2"
+	bnc "github.com/adshao/go-binance/v2/common"
+	bnf "github.com/adshao/go-binance/v2/futures"
+	"github.com/sdcoffey/big"
+)
+
+const maxBufferedUpdates = 1000
+
+var (
+	ErrOrderBookNotSynced = errors.New("order book is waiting for a snapshot")
+	ErrOrderBookGap       = errors.New("order book sequence gap, resync required")
+	ErrOrderBookStale     = errors.New("order book snapshot is older than the buffered updates")
+)
+
+// DepthUpdate is a diff-depth event from the exchange. Binance spot only sends the first
+// and the final update ids, the futures stream also sends the final update id of the
+// previous event which is used to check the sequence.
+type DepthUpdate struct {
+	FirstUpdateID    int64
+	LastUpdateID     int64
+	PrevLastUpdateID int64
+	Bids             []bnc.PriceLevel
+	Asks             []bnc.PriceLevel
+}
+
+func NewDepthUpdateFromBinanceSpot(e *bn.WsDepthEvent) *DepthUpdate {
+	return &DepthUpdate{
+		FirstUpdateID: e.FirstUpdateID,
+		LastUpdateID:  e.LastUpdateID,
+		Bids:          e.Bids,
+		Asks:          e.Asks,
+	}
+}
+
+func NewDepthUpdateFromBinanceFutures(e *bnf.WsDepthEvent) *DepthUpdate {
+	return &DepthUpdate{
+		FirstUpdateID:    e.FirstUpdateID,
+		LastUpdateID:     e.LastUpdateID,
+		PrevLastUpdateID: e.PrevLastUpdateID,
+		Bids:             e.Bids,
+		Asks:             e.Asks,
+	}
+}
+
+// OrderBook is a local copy of an exchange order book. It is initialized from a REST
+// snapshot and kept up to date with the diff-depth stream following the Binance rules
+// on how to manage a local order book correctly.
+type OrderBook struct {
+	sync.RWMutex
+
+	symbol       string
+	isFutures    bool
+	isSynced     bool
+	lastUpdateID int64
+	buffer       []*DepthUpdate
+	bids         map[string]*PriceLevel
+	asks         map[string]*PriceLevel
+}
+
+func NewOrderBook(symbol string, isFutures bool) *OrderBook {
+	return &OrderBook{
+		symbol:    symbol,
+		isFutures: isFutures,
+		bids:      make(map[string]*PriceLevel),
+		asks:      make(map[string]*PriceLevel),
+	}
+}
+
+// Symbol returns the symbol of the book.
+func (ob *OrderBook) Symbol() string { return ob.symbol }
+
+// IsSynced returns true if the book has been initialized from a snapshot and no gap has
+// been detected since.
+func (ob *OrderBook) IsSynced() bool {
+	ob.RLock()
+	defer ob.RUnlock()
+	return ob.isSynced
+}
+
+// LastUpdateID returns the id of the last applied update.
+func (ob *OrderBook) LastUpdateID() int64 {
+	ob.RLock()
+	defer ob.RUnlock()
+	return ob.lastUpdateID
+}
+
+// Snapshot resets the book with the given snapshot and replays all the buffered updates
+// received while waiting for it. It returns ErrOrderBookStale if the snapshot is older than
+// the first buffered update, a new snapshot should be fetched in that case.
+func (ob *OrderBook) Snapshot(lastUpdateID int64, bids, asks []bnc.PriceLevel) error {
+	ob.Lock()
+	defer ob.Unlock()
+	ob.bids = make(map[string]*PriceLevel, len(bids))
+	ob.asks = make(map[string]*PriceLevel, len(asks))
+	setLevels(ob.bids, bids)
+	setLevels(ob.asks, asks)
+	ob.lastUpdateID = lastUpdateID
+	ob.isSynced = true
+	buffer := ob.buffer
+	ob.buffer = nil
+	first := true
+	for i, u := range buffer {
+		if ob.isOutdated(u) {
+			continue
+		}
+		if first && !ob.isFirst(u) {
+			ob.isSynced = false
+			ob.buffer = buffer[i:]
+			return ErrOrderBookStale
+		}
+		if !first && !ob.isNext(u) {
+			ob.isSynced = false
+			ob.buffer = buffer[i:]
+			return ErrOrderBookGap
+		}
+		ob.apply(u)
+		first = false
+	}
+	return nil
+}
+
+// Update applies a diff-depth event to the book. Updates are buffered until the book is
+// synced with a snapshot. ErrOrderBookGap is returned when an event is missing in the
+// sequence, the book then starts buffering again and waits for a new snapshot.
+func (ob *OrderBook) Update(u *DepthUpdate) error {
+	if u == nil {
+		return nil
+	}
+	ob.Lock()
+	defer ob.Unlock()
+	if !ob.isSynced {
+		ob.buffer = append(ob.buffer, u)
+		if len(ob.buffer) > maxBufferedUpdates {
+			ob.buffer = ob.buffer[len(ob.buffer)-maxBufferedUpdates:]
+		}
+		return ErrOrderBookNotSynced
+	}
+	if ob.isOutdated(u) {
+		return nil
+	}
+	if !ob.isNext(u) {
+		ob.isSynced = false
+		ob.buffer = []*DepthUpdate{u}
+		return ErrOrderBookGap
+	}
+	ob.apply(u)
+	return nil
+}
+
+// isOutdated returns true if the event is already included in the book.
+func (ob *OrderBook) isOutdated(u *DepthUpdate) bool {
+	if ob.isFutures {
+		return u.LastUpdateID < ob.lastUpdateID
+	}
+	return u.LastUpdateID <= ob.lastUpdateID
+}
+
+// isFirst checks if the event is a valid first event to be applied after a snapshot.
+func (ob *OrderBook) isFirst(u *DepthUpdate) bool {
+	if ob.isFutures {
+		return u.FirstUpdateID <= ob.lastUpdateID && u.LastUpdateID >= ob.lastUpdateID
+	}
+	return u.FirstUpdateID <= ob.lastUpdateID+1 && u.LastUpdateID >= ob.lastUpdateID+1
+}
+
+// isNext checks if the event follows the last applied one.
+func (ob *OrderBook) isNext(u *DepthUpdate) bool {
+	if ob.isFutures {
+		return u.PrevLastUpdateID == ob.lastUpdateID || ob.isFirst(u)
+	}
+	return u.FirstUpdateID == ob.lastUpdateID+1 || ob.isFirst(u)
+}
+
+func (ob *OrderBook) apply(u *DepthUpdate) {
+	setLevels(ob.bids, u.Bids)
+	setLevels(ob.asks, u.Asks)
+	ob.lastUpdateID = u.LastUpdateID
+}
+
+// setLevels sets the absolute quantity of the given price levels, a zero quantity removes the level.
+func setLevels(side map[string]*PriceLevel, levels []bnc.PriceLevel) {
+	for _, l := range levels {
+		qty := big.NewFromString(l.Quantity)
+		if qty.EQ(big.ZERO) {
+			delete(side, l.Price)
+			continue
+		}
+		side[l.Price] = &PriceLevel{Price: big.NewFromString(l.Price), Quantity: qty}
+	}
+}
+
+// sortedLevels returns the levels of a side from the best to the worst price.
+func sortedLevels(side map[string]*PriceLevel, descending bool) []*PriceLevel {
+	out := make([]*PriceLevel, 0, len(side))
+	for _, l := range side {
+		out = append(out, &PriceLevel{Price: l.Price, Quantity: l.Quantity})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if descending {
+			return out[i].Price.GT(out[j].Price)
+		}
+		return out[i].Price.LT(out[j].Price)
+	})
+	return out
+}
+
+// Bids returns the best n bid levels, all levels if n <= 0.
+func (ob *OrderBook) Bids(n int) []*PriceLevel {
+	ob.RLock()
+	defer ob.RUnlock()
+	levels := sortedLevels(ob.bids, true)
+	if n > 0 && len(levels) > n {
+		return levels[:n]
+	}
+	return levels
+}
+
+// Asks returns the best n ask levels, all levels if n <= 0.
+func (ob *OrderBook) Asks(n int) []*PriceLevel {
+	ob.RLock()
+	defer ob.RUnlock()
+	levels := sortedLevels(ob.asks, false)
+	if n > 0 && len(levels) > n {
+		return levels[:n]
+	}
+	return levels
+}
+
+// L1 returns the best bid and the best ask of the book.
+func (ob *OrderBook) L1() *L1 {
+	l1 := NewL1()
+	if bids := ob.Bids(1); len(bids) > 0 {
+		l1.BestBid = bids[0]
+	}
+	if asks := ob.Asks(1); len(asks) > 0 {
+		l1.BestAsk = asks[0]
+	}
+	return l1
+}
+
+// MidPrice returns the mid point between the best bid and the best ask.
+func (ob *OrderBook) MidPrice() big.Decimal {
+	l1 := ob.L1()
+	if l1.BestBid.Price.EQ(big.ZERO) || l1.BestAsk.Price.EQ(big.ZERO) {
+		return big.ZERO
+	}
+	return MidPoint(l1.BestBid.Price, l1.BestAsk.Price)
+}
+
+// DepthWithin returns the cumulative bid and ask quantities of all levels within
+// the given percentage, ex: 0.5 for 0.5%, of the mid price.
+func (ob *OrderBook) DepthWithin(percent float64) (big.Decimal, big.Decimal) {
+	mid := ob.MidPrice()
+	if mid.EQ(big.ZERO) {
+		return big.ZERO, big.ZERO
+	}
+	distance := mid.Mul(big.NewDecimal(percent)).Div(big.NewDecimal(100.0))
+	lower, upper := mid.Sub(distance), mid.Add(distance)
+	ob.RLock()
+	defer ob.RUnlock()
+	bidDepth, askDepth := big.ZERO, big.ZERO
+	for _, l := range ob.bids {
+		if l.Price.GTE(lower) {
+			bidDepth = bidDepth.Add(l.Quantity)
+		}
+	}
+	for _, l := range ob.asks {
+		if l.Price.LTE(upper) {
+			askDepth = askDepth.Add(l.Quantity)
+		}
+	}
+	return bidDepth, askDepth
+}
+
+// Imbalance returns (bid - ask) / (bid + ask) of the depth within the given percentage
+// of the mid price. It ranges from -1, only asks, to 1, only bids.
+func (ob *OrderBook) Imbalance(percent float64) big.Decimal {
+	bidDepth, askDepth := ob.DepthWithin(percent)
+	total := bidDepth.Add(askDepth)
+	if total.EQ(big.ZERO) {
+		return big.ZERO
+	}
+	return bidDepth.Sub(askDepth).Div(total)
+}
+
+// VWAPToFill returns the volume weighted average price to fill the given size
+// at market. A BUY walks the asks, a SELL walks the bids. It returns false if the
+// book doesn't have enough liquidity to fill the size.
+func (ob *OrderBook) VWAPToFill(side string, size big.Decimal) (big.Decimal, bool) {
+	if size.LTE(big.ZERO) {
+		return big.ZERO, false
+	}
+	var levels []*PriceLevel
+	if strings.ToUpper(side) == "BUY" {
+		levels = ob.Asks(0)
+	} else {
+		levels = ob.Bids(0)
+	}
+	remaining, cost := size, big.ZERO
+	for _, l := range levels {
+		if remaining.LTE(l.Quantity) {
+			cost = cost.Add(remaining.Mul(l.Price))
+			return cost.Div(size), true
+		}
+		cost = cost.Add(l.Quantity.Mul(l.Price))
+		remaining = remaining.Sub(l.Quantity)
+	}
+	return big.ZERO, false
+}
@@ -0,0 +1,69 @@
+package techanex
+
+import (
+	"testing"
+
+	bnc "github.com/adshao/go-binance/v2/common"
+	"github.com/sdcoffey/big"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_OrderBook(t *testing.T) {
+	ob := NewOrderBook("BTCUSDT", false)
+
+	// updates are buffered until the snapshot arrives
+	err := ob.Update(&DepthUpdate{
+		FirstUpdateID: 99,
+		LastUpdateID:  101,
+		Bids:          []bnc.PriceLevel{{Price: "99", Quantity: "3"}},
+	})
+	assert.EqualValues(t, ErrOrderBookNotSynced, err)
+	assert.EqualValues(t, false, ob.IsSynced())
+
+	err = ob.Snapshot(100,
+		[]bnc.PriceLevel{{Price: "99", Quantity: "1"}, {Price: "98", Quantity: "2"}},
+		[]bnc.PriceLevel{{Price: "101", Quantity: "1"}, {Price: "102", Quantity: "4"}},
+	)
+	assert.EqualValues(t, nil, err)
+	assert.EqualValues(t, true, ob.IsSynced())
+	assert.EqualValues(t, 101, ob.LastUpdateID())
+	assert.EqualValues(t, "3", ob.Bids(1)[0].Quantity.FormattedString(0))
+
+	err = ob.Update(&DepthUpdate{
+		FirstUpdateID: 102,
+		LastUpdateID:  103,
+		Asks:          []bnc.PriceLevel{{Price: "101", Quantity: "0"}},
+	})
+	assert.EqualValues(t, nil, err)
+	assert.EqualValues(t, "102", ob.L1().BestAsk.Price.FormattedString(0))
+	assert.EqualValues(t, "100.5", ob.MidPrice().FormattedString(1))
+
+	bidDepth, askDepth := ob.DepthWithin(5)
+	assert.EqualValues(t, "5", bidDepth.FormattedString(0))
+	assert.EqualValues(t, "4", askDepth.FormattedString(0))
+	assert.EqualValues(t, "0.111", ob.Imbalance(5).FormattedString(3))
+
+	price, ok := ob.VWAPToFill("SELL", big.NewFromInt(4))
+	assert.EqualValues(t, true, ok)
+	assert.EqualValues(t, "98.75", price.FormattedString(2))
+	_, ok = ob.VWAPToFill("BUY", big.NewFromInt(5))
+	assert.EqualValues(t, false, ok)
+
+	// a missing event requires a resync
+	err = ob.Update(&DepthUpdate{FirstUpdateID: 105, LastUpdateID: 106})
+	assert.EqualValues(t, ErrOrderBookGap, err)
+	assert.EqualValues(t, false, ob.IsSynced())
+}
+
+func Test_FuturesOrderBook(t *testing.T) {
+	ob := NewOrderBook("BTCUSDT", true)
+	err := ob.Snapshot(100, []bnc.PriceLevel{{Price: "99", Quantity: "1"}}, []bnc.PriceLevel{{Price: "101", Quantity: "1"}})
+	assert.EqualValues(t, nil, err)
+
+	err = ob.Update(&DepthUpdate{FirstUpdateID: 95, LastUpdateID: 102, PrevLastUpdateID: 94})
+	assert.EqualValues(t, nil, err)
+	err = ob.Update(&DepthUpdate{FirstUpdateID: 103, LastUpdateID: 104, PrevLastUpdateID: 102})
+	assert.EqualValues(t, nil, err)
+	err = ob.Update(&DepthUpdate{FirstUpdateID: 106, LastUpdateID: 107, PrevLastUpdateID: 105})
+	assert.EqualValues(t, ErrOrderBookGap, err)
+}
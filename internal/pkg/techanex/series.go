@@ -2,12 +2,17 @@ package techanex
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	ta "github.com/heyphat/techan"
 )
 
+// Series is the candles of a time frame and their indicators. The lock is taken by the methods
+// changing the series and by the readers going through all of it.
 type Series struct {
+	sync.RWMutex
+
 	Candles    *ta.TimeSeries
 	Indicators *IndicatorSeries
 }
@@ -25,6 +30,41 @@ func (s *Series) SyncCandle(candle *ta.Candle, d *time.Duration) bool {
 	if candle == nil {
 		panic(fmt.Errorf("error syncing candle: cannle cannot be nil"))
 	}
+	s.Lock()
+	defer s.Unlock()
+	return s.syncCandle(candle, d)
+}
+
+// WithPartialCandle returns a copy of the series with the given non-final candle synced to it,
+// the series itself is left as it is. The copy shares the candles and the indicators of the
+// series but the last ones, which the partial candle updates or adds.
+func (s *Series) WithPartialCandle(candle *ta.Candle, d *time.Duration) (*Series, bool) {
+	if candle == nil {
+		panic(fmt.Errorf("error syncing candle: cannle cannot be nil"))
+	}
+	s.RLock()
+	defer s.RUnlock()
+	size := len(s.Candles.Candles)
+	if size != len(s.Indicators.Indicators) {
+		return nil, false
+	}
+	ps := &Series{
+		Candles:    &ta.TimeSeries{Candles: append(make([]*ta.Candle, 0, size+1), s.Candles.Candles...)},
+		Indicators: &IndicatorSeries{Indicators: append(make([]*Indicator, 0, size+1), s.Indicators.Indicators...), Configs: s.Indicators.Configs},
+	}
+	if last := ps.Candles.LastCandle(); last != nil && candle.Period.Since(last.Period) < 0 {
+		own := NewCandleFromCandle(last, nil)
+		own.Period = last.Period
+		ps.Candles.Candles[size-1] = own
+		ps.Indicators.Indicators[size-1] = NewIndicator(own.Period, ps.Indicators.Configs)
+	}
+	if !ps.syncCandle(candle, d) {
+		return nil, false
+	}
+	return ps, true
+}
+
+func (s *Series) syncCandle(candle *ta.Candle, d *time.Duration) bool {
 	indicator := NewIndicator(syncPeriod(candle.Period, d), s.Indicators.Configs)
 	if s.Candles.LastCandle() == nil || candle.Period.Since(s.Candles.LastCandle().Period) >= 0 {
 		if !s.Candles.AddCandle(NewCandleFromCandle(candle, d)) {
@@ -51,6 +91,8 @@ func (s *Series) SyncCandles(candles *ta.TimeSeries, d *time.Duration) bool {
 	if len(candles.Candles) == 0 {
 		return true
 	}
+	s.Lock()
+	defer s.Unlock()
 	for _, c := range candles.Candles {
 		if s.Candles.LastCandle() == nil || c.Period.Since(s.Candles.LastCandle().Period) >= 0 {
 			if !s.Candles.AddCandle(NewCandleFromCandle(c, d)) {
@@ -69,6 +111,8 @@ func (s *Series) AddCandle(candle *ta.Candle) bool {
 	if candle == nil {
 		panic(fmt.Errorf("error adding candle: cannle cannot be nil"))
 	}
+	s.Lock()
+	defer s.Unlock()
 	if !s.Candles.AddCandle(candle) {
 		return false
 	}
@@ -85,6 +129,8 @@ func (s *Series) UpdateCandle(candle *ta.Candle) bool {
 	if candle == nil {
 		panic(fmt.Errorf("error aggregating Candle: cannle cannot be nil"))
 	}
+	s.Lock()
+	defer s.Unlock()
 	if s.Candles.LastCandle() == nil {
 		return false
 	}
@@ -120,6 +166,8 @@ func (ts *Series) IndicatorByIndex(index int) *Indicator {
 
 // Shink the lenght of candles and indicator to the given size.
 func (ts *Series) Shrink(size int) {
+	ts.Lock()
+	defer ts.Unlock()
 	if len(ts.Candles.Candles) != len(ts.Indicators.Indicators) {
 		return
 	}
@@ -0,0 +1,47 @@
+package techanex
+
+import (
+	"testing"
+	"time"
+
+	bn "github.com/adshao/go-binance/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WithPartialCandle(t *testing.T) {
+	d := 5 * time.Minute
+	s := NewSeries(nil)
+	kline := &bn.Kline{OpenTime: 1499040000000, Open: "1.0", High: "1.2", Low: "0.9", Close: "1.1", Volume: "10", TradeNum: 10}
+	assert.EqualValues(t, true, s.SyncCandle(ConvertBinanceKline(kline, nil), &d))
+
+	// a partial candle updates the last candle of a copy, the series is left as it is
+	partial := &bn.Kline{OpenTime: 1499040060000, Open: "1.1", High: "2.0", Low: "1.0", Close: "1.9", Volume: "5", TradeNum: 5}
+	ps, ok := s.WithPartialCandle(ConvertBinanceKline(partial, nil), &d)
+	assert.EqualValues(t, true, ok)
+	assert.EqualValues(t, "2.0", ps.Candles.LastCandle().MaxPrice.FormattedString(1))
+	assert.EqualValues(t, "15", ps.Candles.LastCandle().Volume.FormattedString(0))
+	assert.EqualValues(t, "1.2", s.Candles.LastCandle().MaxPrice.FormattedString(1))
+	assert.EqualValues(t, "10", s.Candles.LastCandle().Volume.FormattedString(0))
+
+	// the final candle is synced to the series only
+	final := &bn.Kline{OpenTime: 1499040060000, Open: "1.1", High: "1.5", Low: "1.0", Close: "1.4", Volume: "8", TradeNum: 8}
+	assert.EqualValues(t, true, s.SyncCandle(ConvertBinanceKline(final, nil), &d))
+	assert.EqualValues(t, 1, len(s.Candles.Candles))
+	assert.EqualValues(t, "1.5", s.Candles.LastCandle().MaxPrice.FormattedString(1))
+	assert.EqualValues(t, "18", s.Candles.LastCandle().Volume.FormattedString(0))
+	assert.EqualValues(t, "15", ps.Candles.LastCandle().Volume.FormattedString(0))
+
+	// a partial candle of a new period is added to the copy only
+	partial = &bn.Kline{OpenTime: 1499040300000, Open: "1.4", High: "1.6", Low: "1.3", Close: "1.5", Volume: "3", TradeNum: 3}
+	ps, ok = s.WithPartialCandle(ConvertBinanceKline(partial, nil), &d)
+	assert.EqualValues(t, true, ok)
+	assert.EqualValues(t, 2, len(ps.Candles.Candles))
+	assert.EqualValues(t, 2, len(ps.Indicators.Indicators))
+	assert.EqualValues(t, 1, len(s.Candles.Candles))
+	assert.EqualValues(t, 1, len(s.Indicators.Indicators))
+	final = &bn.Kline{OpenTime: 1499040300000, Open: "1.4", High: "1.7", Low: "1.3", Close: "1.6", Volume: "4", TradeNum: 4}
+	assert.EqualValues(t, true, s.SyncCandle(ConvertBinanceKline(final, nil), &d))
+	assert.EqualValues(t, 2, len(s.Candles.Candles))
+	assert.EqualValues(t, "4", s.Candles.LastCandle().Volume.FormattedString(0))
+	assert.EqualValues(t, "3", ps.Candles.LastCandle().Volume.FormattedString(0))
+}
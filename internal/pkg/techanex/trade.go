@@ -1,6 +1,11 @@
 package techanex
 
-import "github.com/sdcoffey/big"
+import (
+	"sync"
+	"time"
+
+	"github.com/sdcoffey/big"
+)
 
 type Trade struct {
 	Price        big.Decimal
@@ -15,3 +20,97 @@ func NewTrade() *Trade {
 		Quantity: big.ZERO,
 	}
 }
+
+// USDVolume returns the value of the trade in the quote currency.
+func (t *Trade) USDVolume() big.Decimal {
+	return t.Price.Mul(t.Quantity)
+}
+
+// Trades holds the recent trades of a ticker within a given retention window,
+// older trades are removed when new trades arrive.
+type Trades struct {
+	sync.RWMutex
+
+	retention time.Duration
+	trades    []*Trade
+}
+
+func NewTrades(retention time.Duration) *Trades {
+	return &Trades{
+		retention: retention,
+		trades:    make([]*Trade, 0),
+	}
+}
+
+// Add appends a new trade and removes trades older than the retention window.
+func (ts *Trades) Add(t *Trade) {
+	if t == nil {
+		return
+	}
+	ts.Lock()
+	defer ts.Unlock()
+	ts.trades = append(ts.trades, t)
+	cutoff := t.TradeTime - ts.retention.Milliseconds()
+	i := 0
+	for i < len(ts.trades) && ts.trades[i].TradeTime < cutoff {
+		i++
+	}
+	ts.trades = ts.trades[i:]
+}
+
+// Last returns the last trade, nil if there is no trade.
+func (ts *Trades) Last() *Trade {
+	ts.RLock()
+	defer ts.RUnlock()
+	if len(ts.trades) == 0 {
+		return nil
+	}
+	return ts.trades[len(ts.trades)-1]
+}
+
+// Within returns all the trades within the given window from the last trade.
+func (ts *Trades) Within(window time.Duration) []*Trade {
+	ts.RLock()
+	defer ts.RUnlock()
+	if len(ts.trades) == 0 {
+		return nil
+	}
+	cutoff := ts.trades[len(ts.trades)-1].TradeTime - window.Milliseconds()
+	out := make([]*Trade, 0)
+	for _, t := range ts.trades {
+		if t.TradeTime >= cutoff {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// Rate returns the number of trades per second within the given window.
+func (ts *Trades) Rate(window time.Duration) big.Decimal {
+	if window <= 0 {
+		return big.ZERO
+	}
+	return big.NewFromInt(len(ts.Within(window))).Div(big.NewDecimal(window.Seconds()))
+}
+
+// Volume returns the base and quote volume traded within the given window.
+func (ts *Trades) Volume(window time.Duration) (big.Decimal, big.Decimal) {
+	volume, usdVolume := big.ZERO, big.ZERO
+	for _, t := range ts.Within(window) {
+		volume = volume.Add(t.Quantity)
+		usdVolume = usdVolume.Add(t.USDVolume())
+	}
+	return volume, usdVolume
+}
+
+// LargePrints returns the number of trades within the given window where the traded
+// value in the quote currency is at least the given value.
+func (ts *Trades) LargePrints(window time.Duration, minUSDVolume big.Decimal) int {
+	count := 0
+	for _, t := range ts.Within(window) {
+		if t.USDVolume().GTE(minUSDVolume) {
+			count++
+		}
+	}
+	return count
+}
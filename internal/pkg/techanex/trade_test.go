@@ -0,0 +1,31 @@
+package techanex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sdcoffey/big"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Trades(t *testing.T) {
+	ts := NewTrades(time.Minute)
+	assert.EqualValues(t, true, ts.Last() == nil)
+
+	for i, qty := range []int{1, 5, 2, 10} {
+		td := NewTrade()
+		td.Price = big.NewFromInt(100)
+		td.Quantity = big.NewFromInt(qty)
+		td.TradeTime = int64(i) * 30 * 1000
+		ts.Add(td)
+	}
+	// the first trade is out of the retention window
+	assert.EqualValues(t, 3, len(ts.Within(time.Hour)))
+	assert.EqualValues(t, "10", ts.Last().Quantity.FormattedString(0))
+
+	volume, usdVolume := ts.Volume(30 * time.Second)
+	assert.EqualValues(t, "12", volume.FormattedString(0))
+	assert.EqualValues(t, "1200", usdVolume.FormattedString(0))
+	assert.EqualValues(t, "0.07", ts.Rate(30*time.Second).FormattedString(2))
+	assert.EqualValues(t, 2, ts.LargePrints(time.Minute, big.NewFromInt(500)))
+}
@@ -65,13 +65,15 @@ type Configs struct {
 			} `json:"runner"`
 		} `json:"watcher"`
 		Evaluator struct {
-			SourcePath string `json:"source_path"`
+			SourcePath string    `json:"source_path"`
+			Conflict   *Conflict `json:"conflict"`
 		} `json:evaluator`
 		Tester struct {
 			SavePath      string  `json:"save_path"`
 			InitBalance   float64 `json:"init_balance"`
 			ProfitMargin  float64 `json:"profit_margin"`
 			LossTolerance float64 `json:"loss_tolerance"`
+			Sizing        *Sizing `json:"sizing"`
 		} `json:"tester"`
 		Trader struct {
 			Allowed           bool     `json:"allowed"`
@@ -85,6 +87,7 @@ type Configs struct {
 			ProfitMargin      float64  `json:"profit_margin"`
 			MaxLossPerTrade   float64  `json:"max_loss_per_trade"`
 			MinProfitPerTrade float64  `json:"min_profit_per_trade"`
+			Sizing            *Sizing  `json:"sizing"`
 		} `json:"trader"`
 	} `json:"market"`
 	Database struct {
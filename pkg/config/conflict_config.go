@@ -0,0 +1,14 @@
+package config
+
+// Conflict is how the evaluator resolves the signals firing on a ticker at once, the policy of
+// the first ticker pattern matching the ticker is used, the default policy otherwise.
+type Conflict struct {
+	Policy    string            `json:"policy"`
+	Tickers   []*TickerConflict `json:"tickers"`
+	Aggregate bool              `json:"aggregate"`
+}
+
+type TickerConflict struct {
+	Pattern string `json:"pattern"`
+	Policy  string `json:"policy"`
+}
@@ -13,4 +13,5 @@ type Notion struct {
 	NotiDBID           string `json:"notification_db_id"`
 	BacktestDBID       string `json:"backtest_db_id"`
 	BacktestResultDBID string `json:"backtest_result_db_id"`
+	SignalDBID         string `json:"signal_db_id"`
 }
@@ -0,0 +1,13 @@
+package config
+
+type Sizing struct {
+	Model      string  `json:"model"`
+	Amount     float64 `json:"amount"`
+	Percent    float64 `json:"percent"`
+	ATR        float64 `json:"atr"`
+	ATRWindow  int     `json:"atr_window"`
+	ATRPeriod  int     `json:"atr_period"`
+	WinRate    float64 `json:"win_rate"`
+	Payoff     float64 `json:"payoff"`
+	MaxPercent float64 `json:"max_percent"`
+}
@@ -8,3 +8,12 @@ func Int64SliceContains(slice []int64, i int64) bool {
 	}
 	return false
 }
+
+func IntSliceContains(slice []int, i int) bool {
+	for _, j := range slice {
+		if i == j {
+			return true
+		}
+	}
+	return false
+}